@@ -0,0 +1,198 @@
+package ragkit
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultBatchSize is the number of documents embedded and written at once by the vector stores
+const DefaultBatchSize = 100
+
+// BatchLimits is a type that bounds the inputs of a single embedding call
+type BatchLimits struct {
+	MaxTexts  int // Max number of texts, 0 for unlimited
+	MaxTokens int // Max number of estimated tokens (see EstimateTokens), 0 for unlimited
+}
+
+// BatchLimiter is an Embedder that reports the input limits of its provider
+type BatchLimiter interface {
+	BatchLimits() BatchLimits
+}
+
+// DocumentError is a type that reports why a document failed to be indexed
+type DocumentError struct {
+	Index int    // Position of the document in the Index call
+	ID    string // ID of the document
+	Err   error
+}
+
+func (e DocumentError) Error() string {
+	return fmt.Sprintf("document %s: %v", e.ID, e.Err)
+}
+
+func (e DocumentError) Unwrap() error {
+	return e.Err
+}
+
+// IndexError is a type that reports the documents an Index call failed to index.
+// The other documents were indexed and their IDs returned.
+// errors.Is and errors.As look into the errors of every failed document.
+type IndexError struct {
+	Failed []DocumentError
+}
+
+// Add records the failure of the document at position index
+func (e *IndexError) Add(index int, id string, err error) {
+	e.Failed = append(e.Failed, DocumentError{Index: index, ID: id, Err: err})
+}
+
+// Has reports whether the document at position index failed
+func (e *IndexError) Has(index int) bool {
+	return slices.ContainsFunc(e.Failed, func(de DocumentError) bool { return de.Index == index })
+}
+
+// Err returns e ordered by document position, or nil if no document failed
+func (e *IndexError) Err() error {
+	if e == nil || len(e.Failed) == 0 {
+		return nil
+	}
+	slices.SortStableFunc(e.Failed, func(a, b DocumentError) int { return a.Index - b.Index })
+	return e
+}
+
+func (e *IndexError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "failed to index %d document(s)", len(e.Failed))
+	for i, de := range e.Failed {
+		if i == 3 {
+			fmt.Fprintf(&sb, "; and %d more", len(e.Failed)-i)
+			break
+		}
+		sb.WriteString("; ")
+		sb.WriteString(de.Error())
+	}
+	return sb.String()
+}
+
+func (e *IndexError) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i, de := range e.Failed {
+		errs[i] = de
+	}
+	return errs
+}
+
+// EstimateTokens roughly estimates the number of tokens of text without a tokenizer.
+// It assumes 4 bytes per token for ASCII and a token per rune otherwise (e.g. Korean),
+// which errs on the high side for most embedding models.
+func EstimateTokens(text string) int {
+	tokens, ascii := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			tokens++
+		}
+	}
+	return tokens + (ascii+3)/4
+}
+
+// EmbedDocs returns a copy of docs with missing IDs generated by GenerateID
+// and missing vectors embedded by embedder, in batches bounded by limits and,
+// if embedder is a BatchLimiter, by its own limits.
+// Documents of a failed batch keep a nil Vector and are reported by the returned IndexError,
+// which is never nil; use its Err method to test for failures.
+// embedder may be nil if every document already has a Vector.
+func EmbedDocs(ctx context.Context, embedder Embedder, limits BatchLimits, docs ...Document) ([]Document, *IndexError) {
+	docs = slices.Clone(docs)
+	failed := &IndexError{}
+
+	var idxs []int
+	for i := range docs {
+		if docs[i].ID == "" {
+			docs[i].ID = GenerateID(docs[i].Text, docs[i].Metadata)
+		}
+		if docs[i].Vector == nil {
+			if embedder == nil {
+				failed.Add(i, docs[i].ID, fmt.Errorf("no embedder to embed the document"))
+				continue
+			}
+			idxs = append(idxs, i)
+		}
+	}
+	if len(idxs) == 0 {
+		return docs, failed
+	}
+
+	if bl, ok := embedder.(BatchLimiter); ok {
+		limits = limits.merge(bl.BatchLimits())
+	}
+
+	for len(idxs) > 0 {
+		n := limits.fit(docs, idxs)
+		batch := idxs[:n]
+		idxs = idxs[n:]
+
+		texts := make([]string, len(batch))
+		for j, i := range batch {
+			texts[j] = docs[i].Text
+		}
+
+		vectors, err := embedder.EmbedTexts(ctx, texts...)
+		if err == nil && len(vectors) != len(texts) {
+			err = fmt.Errorf("embedder returned %d vectors for %d texts", len(vectors), len(texts))
+		}
+		if err != nil {
+			for _, i := range batch {
+				failed.Add(i, docs[i].ID, err)
+			}
+			continue
+		}
+		for j, i := range batch {
+			docs[i].Vector = vectors[j]
+		}
+	}
+	return docs, failed
+}
+
+// PrepareDocs is EmbedDocs for all-or-nothing operations.
+// It fails if any document fails to embed.
+func PrepareDocs(ctx context.Context, embedder Embedder, limits BatchLimits, docs ...Document) ([]Document, error) {
+	docs, failed := EmbedDocs(ctx, embedder, limits, docs...)
+	if err := failed.Err(); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// merge returns the tighter of both limits
+func (l BatchLimits) merge(o BatchLimits) BatchLimits {
+	tighter := func(a, b int) int {
+		if a == 0 || (b != 0 && b < a) {
+			return b
+		}
+		return a
+	}
+	return BatchLimits{
+		MaxTexts:  tighter(l.MaxTexts, o.MaxTexts),
+		MaxTokens: tighter(l.MaxTokens, o.MaxTokens),
+	}
+}
+
+// fit returns how many of the documents at idxs fit in a batch, at least one
+func (l BatchLimits) fit(docs []Document, idxs []int) int {
+	tokens := 0
+	for n, i := range idxs {
+		if l.MaxTexts > 0 && n == l.MaxTexts {
+			return n
+		}
+		tokens += EstimateTokens(docs[i].Text)
+		if l.MaxTokens > 0 && tokens > l.MaxTokens && n > 0 {
+			return n
+		}
+	}
+	return len(idxs)
+}
@@ -0,0 +1,154 @@
+package ragkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var _ Retriever = &ConversationalRetriever{}
+
+// DefaultHistoryLimit is the number of previous messages a ConversationalRetriever condenses by default
+const DefaultHistoryLimit = 6
+
+// DefaultCondensePrompt asks for a follow-up question rewritten as a standalone one.
+// It is formatted with the conversation and the follow-up question.
+const DefaultCondensePrompt = `Rewrite the follow-up question of the conversation below as a standalone question, understandable without the conversation: replace pronouns and references by what they refer to. Keep the language of the question. If it is already standalone, repeat it unchanged.
+
+Conversation:
+%s
+
+Follow-up question: %s
+
+Reply with the standalone question only.`
+
+// ConversationalRetriever is a Retriever for the last question of a conversation.
+// A follow-up question like "what about his daughter?" retrieves little on its own, so the
+// Generator first rewrites it into a standalone question from the previous messages, which is
+// then retrieved by the wrapped Retriever.
+type ConversationalRetriever struct {
+	retriever Retriever
+	generator Generator
+	history   HistoryStore
+	prompt    string
+	limit     int
+}
+
+// ConversationalOption configures a ConversationalRetriever
+type ConversationalOption func(*ConversationalRetriever)
+
+// WithCondensePrompt sets the prompt, a format string taking the conversation and the question (default: DefaultCondensePrompt)
+func WithCondensePrompt(prompt string) ConversationalOption {
+	return func(c *ConversationalRetriever) {
+		c.prompt = prompt
+	}
+}
+
+// WithHistoryLimit sets the number of previous messages given to the Generator (default: DefaultHistoryLimit)
+func WithHistoryLimit(n int) ConversationalOption {
+	return func(c *ConversationalRetriever) {
+		c.limit = n
+	}
+}
+
+// WithHistoryStore sets the store of the conversations retrieved by RetrieveConversation
+func WithHistoryStore(store HistoryStore) ConversationalOption {
+	return func(c *ConversationalRetriever) {
+		c.history = store
+	}
+}
+
+// NewConversationalRetriever creates a ConversationalRetriever condensing questions with generator before retrieving them with retriever
+func NewConversationalRetriever(retriever Retriever, generator Generator, opts ...ConversationalOption) *ConversationalRetriever {
+	c := &ConversationalRetriever{
+		retriever: retriever,
+		generator: generator,
+		prompt:    DefaultCondensePrompt,
+		limit:     DefaultHistoryLimit,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *ConversationalRetriever) String() string {
+	return fmt.Sprintf("ConversationalRetriever(%s)", c.generator)
+}
+
+// Retrieve returns the topK documents of the wrapped retriever for the query vector
+func (c *ConversationalRetriever) Retrieve(ctx context.Context, query []float32, topK int, metadataFieldNames ...string) ([]RetrievedDoc, error) {
+	return c.retriever.Retrieve(ctx, query, topK, metadataFieldNames...)
+}
+
+// RetrieveText returns the topK documents of the wrapped retriever for a standalone text query
+func (c *ConversationalRetriever) RetrieveText(ctx context.Context, text string, topK int, metadataFieldNames ...string) ([]RetrievedDoc, error) {
+	return c.retriever.RetrieveText(ctx, text, topK, metadataFieldNames...)
+}
+
+// RetrieveHistory returns the topK documents for the last message of history, a question of the user,
+// condensed with the previous messages
+func (c *ConversationalRetriever) RetrieveHistory(ctx context.Context, history []Message, topK int, metadataFieldNames ...string) ([]RetrievedDoc, error) {
+	query, err := c.Condense(ctx, history)
+	if err != nil {
+		return nil, err
+	}
+	return c.retriever.RetrieveText(ctx, query, topK, metadataFieldNames...)
+}
+
+// RetrieveConversation returns the topK documents for question, following the conversation kept by the history store.
+// It doesn't record question: append it along with the answer once known.
+func (c *ConversationalRetriever) RetrieveConversation(ctx context.Context, conversationID, question string, topK int, metadataFieldNames ...string) ([]RetrievedDoc, error) {
+	if c.history == nil {
+		return nil, errors.New("no history store")
+	}
+	history, err := c.history.Messages(ctx, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("history: %w", err)
+	}
+	history = append(history, Message{Role: RoleUser, Content: question})
+	return c.RetrieveHistory(ctx, history, topK, metadataFieldNames...)
+}
+
+// Condense returns the last message of history, a question of the user, rewritten as a standalone question.
+// A question without previous messages is returned as is.
+func (c *ConversationalRetriever) Condense(ctx context.Context, history []Message) (string, error) {
+	if len(history) == 0 || history[len(history)-1].Role != RoleUser {
+		return "", errors.New("history doesn't end with a message of the user")
+	}
+	question := history[len(history)-1].Content
+
+	var previous []Message
+	for _, m := range history[:len(history)-1] {
+		if m.Role != RoleSystem {
+			previous = append(previous, m)
+		}
+	}
+	if c.limit > 0 && len(previous) > c.limit {
+		previous = previous[len(previous)-c.limit:]
+	}
+	if len(previous) == 0 {
+		return question, nil
+	}
+
+	var sb strings.Builder
+	for _, m := range previous {
+		name := "User"
+		if m.Role == RoleAssistant {
+			name = "Assistant"
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", name, strings.TrimSpace(m.Content))
+	}
+	prompt := fmt.Sprintf(c.prompt, strings.TrimSpace(sb.String()), question)
+
+	reply, err := c.generator.Generate(ctx, Message{Role: RoleUser, Content: prompt})
+	if err != nil {
+		return "", fmt.Errorf("condense: %w", err)
+	}
+	standalone := strings.Trim(strings.TrimSpace(reply), `"'“”`)
+	if standalone == "" {
+		return question, nil
+	}
+	return standalone, nil
+}
@@ -0,0 +1,176 @@
+package ragkit
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// stubGenerator replies to the last message of a chat with reply, recording the prompts it got
+type stubGenerator struct {
+	reply func(prompt string) (string, error)
+
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (g *stubGenerator) Generate(ctx context.Context, messages ...Message) (string, error) {
+	prompt := messages[len(messages)-1].Content
+	g.mu.Lock()
+	g.prompts = append(g.prompts, prompt)
+	g.mu.Unlock()
+	return g.reply(prompt)
+}
+
+func (g *stubGenerator) String() string { return "stub" }
+
+// replying returns a stubGenerator always replying with reply
+func replying(reply string) *stubGenerator {
+	return &stubGenerator{reply: func(string) (string, error) { return reply, nil }}
+}
+
+// stubHistory is a HistoryStore of fixed conversations
+type stubHistory map[string][]Message
+
+func (h stubHistory) Messages(ctx context.Context, conversationID string) ([]Message, error) {
+	return slices.Clone(h[conversationID]), nil
+}
+
+func (h stubHistory) Append(ctx context.Context, conversationID string, messages ...Message) error {
+	h[conversationID] = append(h[conversationID], messages...)
+	return nil
+}
+
+func (h stubHistory) Clear(ctx context.Context, conversationID string) error {
+	delete(h, conversationID)
+	return nil
+}
+
+var conversation = []Message{
+	{Role: RoleSystem, Content: "You are helpful."},
+	{Role: RoleUser, Content: "Who designed Go?"},
+	{Role: RoleAssistant, Content: " Griesemer, Pike and Thompson. "},
+	{Role: RoleUser, Content: "When was it released?"},
+}
+
+func TestCondense(t *testing.T) {
+	g := replying(` "When was Go released?" `)
+	c := NewConversationalRetriever(&stubRetriever{}, g, WithCondensePrompt("%s\n--\n%s"))
+
+	got, err := c.Condense(context.Background(), conversation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "When was Go released?" {
+		t.Errorf("got %q", got)
+	}
+	// system messages are left out and messages trimmed
+	want := "User: Who designed Go?\nAssistant: Griesemer, Pike and Thompson.\n--\nWhen was it released?"
+	if len(g.prompts) != 1 || g.prompts[0] != want {
+		t.Errorf("prompts = %q, want %q", g.prompts, want)
+	}
+
+	// the default prompt holds the conversation and the question
+	g = replying("standalone")
+	NewConversationalRetriever(&stubRetriever{}, g).Condense(context.Background(), conversation)
+	if !strings.Contains(g.prompts[0], "Conversation:\nUser: Who designed Go?\nAssistant:") || !strings.Contains(g.prompts[0], "Follow-up question: When was it released?") {
+		t.Errorf("default prompt = %q", g.prompts[0])
+	}
+}
+
+func TestCondenseHistoryLimit(t *testing.T) {
+	var history []Message
+	for i := range 10 {
+		role := RoleUser
+		if i%2 == 1 {
+			role = RoleAssistant
+		}
+		history = append(history, Message{Role: role, Content: string(rune('a' + i))})
+	}
+	history = append(history, Message{Role: RoleUser, Content: "question"})
+
+	for _, tt := range []struct {
+		limit int
+		want  string
+	}{
+		{2, "User: i\nAssistant: j"},
+		{0, "User: a\nAssistant: b\nUser: c\nAssistant: d\nUser: e\nAssistant: f\nUser: g\nAssistant: h\nUser: i\nAssistant: j"},
+	} {
+		g := replying("x")
+		NewConversationalRetriever(&stubRetriever{}, g, WithHistoryLimit(tt.limit), WithCondensePrompt("%s|%s")).Condense(context.Background(), history)
+		if want := tt.want + "|question"; g.prompts[0] != want {
+			t.Errorf("limit %d: prompt %q, want %q", tt.limit, g.prompts[0], want)
+		}
+	}
+
+	// the default limit keeps the last DefaultHistoryLimit messages
+	g := replying("x")
+	NewConversationalRetriever(&stubRetriever{}, g).Condense(context.Background(), history)
+	if strings.Contains(g.prompts[0], "User: c\n") || !strings.Contains(g.prompts[0], "User: e\n") {
+		t.Errorf("default limit: prompt %q", g.prompts[0])
+	}
+}
+
+func TestCondenseWithoutGenerating(t *testing.T) {
+	g := replying("x")
+	c := NewConversationalRetriever(&stubRetriever{}, g)
+
+	// a first question, even after a system message, is returned as is
+	for _, history := range [][]Message{conversation[1:2], conversation[:2]} {
+		if got, err := c.Condense(context.Background(), history); err != nil || got != "Who designed Go?" {
+			t.Errorf("got %q, %v", got, err)
+		}
+	}
+	if len(g.prompts) != 0 {
+		t.Errorf("generated for a first question: %q", g.prompts)
+	}
+
+	// a blank reply keeps the question
+	if got, _ := NewConversationalRetriever(&stubRetriever{}, replying(` "" `)).Condense(context.Background(), conversation); got != "When was it released?" {
+		t.Errorf("blank reply: got %q", got)
+	}
+}
+
+func TestCondenseErrors(t *testing.T) {
+	errDown := errors.New("down")
+	c := NewConversationalRetriever(&stubRetriever{}, &stubGenerator{reply: func(string) (string, error) { return "", errDown }})
+
+	for _, history := range [][]Message{nil, conversation[:3]} {
+		if _, err := c.Condense(context.Background(), history); err == nil {
+			t.Errorf("history %q not ending with a question: no error", history)
+		}
+	}
+	if _, err := c.Condense(context.Background(), conversation); !errors.Is(err, errDown) {
+		t.Errorf("got error %v, want %v", err, errDown)
+	}
+}
+
+func TestRetrieveConversation(t *testing.T) {
+	ctx := context.Background()
+	stub := &stubRetriever{results: map[string][]RetrievedDoc{"When was Go released?": makeDocs("a", "b", "c")}}
+	history := stubHistory{"c1": conversation[:3]}
+	c := NewConversationalRetriever(stub, replying("When was Go released?"), WithHistoryStore(history))
+
+	got, err := c.RetrieveConversation(ctx, "c1", "When was it released?", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if docIDs(got) != "a,b" {
+		t.Errorf("got %s, want a,b", docIDs(got))
+	}
+	if len(history["c1"]) != 3 {
+		t.Error("RetrieveConversation recorded the question")
+	}
+
+	got, err = c.RetrieveHistory(ctx, conversation, 1)
+	if err != nil || docIDs(got) != "a" {
+		t.Errorf("RetrieveHistory: got %v, %v", got, err)
+	}
+
+	if _, err := NewConversationalRetriever(stub, replying("x")).RetrieveConversation(ctx, "c1", "q", 2); err == nil {
+		t.Error("no history store: no error")
+	}
+}
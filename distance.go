@@ -0,0 +1,76 @@
+package ragkit
+
+import "math"
+
+// DistanceMetric is a type that represents how vector databases measure the distance between vectors
+type DistanceMetric string
+
+const (
+	// DistanceCosine is the cosine distance (1 - cosine similarity), in [0, 2]
+	DistanceCosine DistanceMetric = "cosine"
+	// DistanceDot is the negative inner product
+	DistanceDot DistanceMetric = "dot"
+	// DistanceL2 is the euclidean distance
+	DistanceL2 DistanceMetric = "l2"
+)
+
+// ScoreFromDistance converts a raw distance of the given metric to a similarity score in [0, 1].
+// Higher scores mean more similar vectors, so scores of different metrics can be thresholded the same way.
+func ScoreFromDistance(metric DistanceMetric, distance float32) float32 {
+	switch metric {
+	case DistanceCosine:
+		return clamp01(1 - distance/2)
+	case DistanceDot:
+		// distance is the negative inner product; squash it with a logistic function
+		return float32(1 / (1 + math.Exp(float64(distance))))
+	case DistanceL2:
+		return 1 / (1 + max(distance, 0))
+	default:
+		return 0
+	}
+}
+
+// Distance computes the distance between two vectors of the same dimension with the given metric
+func Distance(metric DistanceMetric, a, b []float32) float32 {
+	switch metric {
+	case DistanceCosine:
+		return 1 - CosineSimilarity(a, b)
+	case DistanceDot:
+		return -dot(a, b)
+	case DistanceL2:
+		var sum float64
+		for i := range a {
+			d := float64(a[i]) - float64(b[i])
+			sum += d * d
+		}
+		return float32(math.Sqrt(sum))
+	default:
+		return float32(math.Inf(1))
+	}
+}
+
+// CosineSimilarity returns the cosine of the angle between two vectors, in [-1, 1]
+func CosineSimilarity(a, b []float32) float32 {
+	var ab, aa, bb float64
+	for i := range a {
+		ab += float64(a[i]) * float64(b[i])
+		aa += float64(a[i]) * float64(a[i])
+		bb += float64(b[i]) * float64(b[i])
+	}
+	if aa == 0 || bb == 0 {
+		return 0
+	}
+	return float32(ab / math.Sqrt(aa*bb))
+}
+
+func dot(a, b []float32) float32 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return float32(sum)
+}
+
+func clamp01(v float32) float32 {
+	return min(max(v, 0), 1)
+}
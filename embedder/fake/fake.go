@@ -0,0 +1,171 @@
+// Package fake provides a deterministic ragkit.Embedder for offline tests.
+//
+// Vectors are built by hashing the words and character trigrams of a text into
+// a fixed number of dimensions, so equal texts get equal vectors and texts sharing
+// words are closer than unrelated ones. No model or network is involved.
+package fake
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+var _ ragkit.Embedder = &Fake{}
+
+type Fake struct {
+	dimension int
+	latency   time.Duration
+	errFunc   func(call int, texts []string) error
+
+	calls atomic.Int64
+}
+
+// Option configures a Fake embedder
+type Option func(*Fake)
+
+// WithLatency delays every EmbedTexts call by d, or until the context is done
+func WithLatency(d time.Duration) Option {
+	return func(f *Fake) {
+		f.latency = d
+	}
+}
+
+// WithError makes every call fail with err
+func WithError(err error) Option {
+	return WithErrorFunc(func(int, []string) error { return err })
+}
+
+// WithErrorEvery makes every n-th call (n, 2n, ...) fail with err
+func WithErrorEvery(n int, err error) Option {
+	return WithErrorFunc(func(call int, _ []string) error {
+		if n > 0 && call%n == 0 {
+			return err
+		}
+		return nil
+	})
+}
+
+// WithErrorFunc fails a call with the error returned by fn, if any.
+// call counts EmbedTexts calls from 1, and texts are the inputs of the call.
+func WithErrorFunc(fn func(call int, texts []string) error) Option {
+	return func(f *Fake) {
+		f.errFunc = fn
+	}
+}
+
+// New creates a fake embedder producing unit vectors of the given dimension
+func New(dimension int, opts ...Option) *Fake {
+	f := &Fake{
+		dimension: max(dimension, 1),
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+func (f *Fake) EmbedText(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := f.EmbedTexts(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+func (f *Fake) EmbedTexts(ctx context.Context, texts ...string) ([][]float32, error) {
+	call := int(f.calls.Add(1))
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if f.latency > 0 {
+		timer := time.NewTimer(f.latency)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if f.errFunc != nil {
+		if err := f.errFunc(call, texts); err != nil {
+			return nil, err
+		}
+	}
+
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		embeddings[i] = f.embed(text)
+	}
+	return embeddings, nil
+}
+
+// Dimension returns the dimension of the embedding vectors
+func (f *Fake) Dimension() int {
+	return f.dimension
+}
+
+// Calls returns the number of EmbedTexts calls made so far
+func (f *Fake) Calls() int {
+	return int(f.calls.Load())
+}
+
+func (f *Fake) String() string {
+	return fmt.Sprintf("Fake(dimension: %d)", f.dimension)
+}
+
+// embed hashes the features of text into a normalized vector
+func (f *Fake) embed(text string) []float32 {
+	vec := make([]float64, f.dimension)
+	add := func(feature string, weight float64) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		sign := 1.0
+		if sum>>63 == 1 {
+			sign = -1
+		}
+		vec[sum%uint64(f.dimension)] += sign * weight
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		add("w:"+w, 1)
+		// trigrams make inflected forms (and Korean words with particles) similar
+		runes := []rune(w)
+		for i := 0; i+3 <= len(runes); i++ {
+			add("t:"+string(runes[i:i+3]), 0.5)
+		}
+	}
+	if len(words) == 0 {
+		add("x:"+text, 1)
+	}
+
+	var norm float64
+	for _, v := range vec {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+
+	ret := make([]float32, f.dimension)
+	for i, v := range vec {
+		if norm > 0 {
+			ret[i] = float32(v / norm)
+		}
+	}
+	// hash collisions can cancel every feature out; keep the vector usable for cosine distance
+	if norm == 0 {
+		ret[0] = 1
+	}
+	return ret
+}
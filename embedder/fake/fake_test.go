@@ -0,0 +1,136 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+func TestDeterministic(t *testing.T) {
+	ctx := context.Background()
+	text := "The quick brown fox jumps over the lazy dog."
+
+	a, err := New(64).EmbedText(ctx, text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := New(64).EmbedText(ctx, text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(a, b) {
+		t.Errorf("embeddings of the same text differ:\n%v\n%v", a, b)
+	}
+}
+
+func TestDimensionAndNorm(t *testing.T) {
+	ctx := context.Background()
+	for _, dim := range []int{1, 8, 384} {
+		f := New(dim)
+		if f.Dimension() != dim {
+			t.Errorf("Dimension() = %d, want %d", f.Dimension(), dim)
+		}
+		vecs, err := f.EmbedTexts(ctx, "hello world", "안녕하세요", "", "!!!")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, v := range vecs {
+			if len(v) != dim {
+				t.Errorf("dim %d: vector %d has %d dimensions", dim, i, len(v))
+			}
+			var norm float64
+			for _, x := range v {
+				norm += float64(x) * float64(x)
+			}
+			if math.Abs(math.Sqrt(norm)-1) > 1e-5 {
+				t.Errorf("dim %d: vector %d has norm %v, want 1", dim, i, math.Sqrt(norm))
+			}
+		}
+	}
+	if d := New(0).Dimension(); d != 1 {
+		t.Errorf("New(0).Dimension() = %d, want 1", d)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	ctx := context.Background()
+	vecs, err := New(256).EmbedTexts(ctx,
+		"the quick brown fox jumps over the lazy dog",
+		"a quick brown fox jumped over a lazy dog",
+		"photosynthesis converts light energy into chemical energy",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	similar := ragkit.CosineSimilarity(vecs[0], vecs[1])
+	unrelated := ragkit.CosineSimilarity(vecs[0], vecs[2])
+	if similar <= unrelated {
+		t.Errorf("similar texts score %v, unrelated %v", similar, unrelated)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	errFake := errors.New("fake error")
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name string
+		opt  Option
+		fail []bool // per call
+	}{
+		{"error", WithError(errFake), []bool{true, true, true}},
+		{"every 2", WithErrorEvery(2, errFake), []bool{false, true, false, true}},
+		{"every 0", WithErrorEvery(0, errFake), []bool{false, false}},
+		{"func", WithErrorFunc(func(call int, texts []string) error {
+			if call == 1 || texts[0] == "bad" {
+				return errFake
+			}
+			return nil
+		}), []bool{true, false, true}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := New(8, tc.opt)
+			for i, fail := range tc.fail {
+				text := "good"
+				if i == 2 {
+					text = "bad"
+				}
+				_, err := f.EmbedTexts(ctx, text)
+				if fail != errors.Is(err, errFake) {
+					t.Errorf("call %d: err = %v, want failure %v", i+1, err, fail)
+				}
+			}
+			if f.Calls() != len(tc.fail) {
+				t.Errorf("Calls() = %d, want %d", f.Calls(), len(tc.fail))
+			}
+		})
+	}
+}
+
+func TestLatency(t *testing.T) {
+	f := New(8, WithLatency(time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := f.EmbedText(ctx, "hello"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("cancelled call took %v", d)
+	}
+
+	f = New(8, WithLatency(20*time.Millisecond))
+	start = time.Now()
+	if _, err := f.EmbedText(context.Background(), "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Errorf("call took %v, want at least 20ms", d)
+	}
+}
@@ -31,26 +31,19 @@ func (o *Ollama) EmbedText(ctx context.Context, text string) ([]float32, error)
 }
 
 func (o *Ollama) EmbedTexts(ctx context.Context, texts ...string) ([][]float32, error) {
-	embeddings := make([][]float32, len(texts))
-
-	for i, text := range texts {
-		req := &ollama_api.EmbedRequest{
-			Model: o.model,
-			Input: text,
-		}
-
-		resp, err := o.client.Embed(ctx, req)
-		if err != nil {
-			return nil, err
-		}
-
-		// Ollama can return multiple embeddings, but we only need one embedding per text
-		if len(resp.Embeddings) > 0 {
-			embeddings[i] = resp.Embeddings[0]
-		}
+	// embed all texts in a single request
+	resp, err := o.client.Embed(ctx, &ollama_api.EmbedRequest{
+		Model: o.model,
+		Input: texts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(resp.Embeddings))
 	}
 
-	return embeddings, nil
+	return resp.Embeddings, nil
 }
 
 func (o *Ollama) String() string {
@@ -8,7 +8,10 @@ import (
 	ragkit "github.com/suapapa/go_ragkit"
 )
 
-var _ ragkit.Embedder = &OpenAI{}
+var (
+	_ ragkit.Embedder     = &OpenAI{}
+	_ ragkit.BatchLimiter = &OpenAI{}
+)
 
 type OpenAI struct {
 	client *oai.Client
@@ -26,6 +29,11 @@ func (o *OpenAI) String() string {
 	return fmt.Sprintf("OpenAI(%s)", o.model)
 }
 
+// BatchLimits returns the input limits of the embeddings endpoint
+func (o *OpenAI) BatchLimits() ragkit.BatchLimits {
+	return ragkit.BatchLimits{MaxTexts: 2048, MaxTokens: 300000}
+}
+
 func (o *OpenAI) EmbedText(ctx context.Context, text string) ([]float32, error) {
 	embeddings, err := o.EmbedTexts(ctx, text)
 	if err != nil {
@@ -46,13 +54,22 @@ func (o *OpenAI) EmbedTexts(ctx context.Context, texts ...string) ([][]float32,
 		return nil, err
 	}
 
-	for i, embedding := range resp.Data {
+	for _, embedding := range resp.Data {
+		i := int(embedding.Index)
+		if i < 0 || i >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d out of range", i)
+		}
 		// Convert []float64 to []float32
 		embeddings[i] = make([]float32, len(embedding.Embedding))
 		for j, v := range embedding.Embedding {
 			embeddings[i][j] = float32(v)
 		}
 	}
+	for i, embedding := range embeddings {
+		if len(embedding) == 0 {
+			return nil, fmt.Errorf("no embedding for input %d of %d", i, len(texts))
+		}
+	}
 
 	return embeddings, nil
 }
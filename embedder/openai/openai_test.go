@@ -0,0 +1,66 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	oai "github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+// newTestEmbedder returns an embedder whose requests are answered with data, a list of embeddings
+func newTestEmbedder(t *testing.T, data []map[string]any) *OpenAI {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"object": "list", "model": "test", "data": data})
+	}))
+	t.Cleanup(srv.Close)
+
+	client := oai.NewClient(option.WithBaseURL(srv.URL), option.WithAPIKey("test"), option.WithMaxRetries(0))
+	return New(&client, "test")
+}
+
+func embedding(index int, vec ...float64) map[string]any {
+	return map[string]any{"object": "embedding", "index": index, "embedding": vec}
+}
+
+func TestEmbedTexts(t *testing.T) {
+	// out of order, as the API doesn't promise any
+	o := newTestEmbedder(t, []map[string]any{embedding(1, 0, 1), embedding(0, 1, 0)})
+	got, err := o.EmbedTexts(context.Background(), "a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || !slices.Equal(got[0], []float32{1, 0}) || !slices.Equal(got[1], []float32{0, 1}) {
+		t.Errorf("got %v, want [[1 0] [0 1]]", got)
+	}
+}
+
+func TestEmbedTextsBadResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		data []map[string]any
+	}{
+		{"short", []map[string]any{embedding(0, 1, 0)}},
+		{"duplicate index", []map[string]any{embedding(0, 1, 0), embedding(0, 0, 1)}},
+		{"index out of range", []map[string]any{embedding(0, 1, 0), embedding(2, 0, 1)}},
+		{"empty embedding", []map[string]any{embedding(0, 1, 0), embedding(1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newTestEmbedder(t, tt.data)
+			if got, err := o.EmbedTexts(context.Background(), "a", "b"); err == nil {
+				t.Errorf("got %v, want an error", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,6 @@
+package ragkit
+
+import "errors"
+
+// ErrDocumentExists is returned by Indexer.Index when a document with the same ID is already indexed
+var ErrDocumentExists = errors.New("document already exists")
@@ -24,17 +24,25 @@ func IndexAndRetriveExample(vstore ragkit.VectorStore) {
 		nil,
 	)
 	ctx := context.Background()
-	for _, doc := range docs {
-		if exist, err := vstore.Exists(ctx, doc.ID); err != nil {
+	if upserter, ok := vstore.(ragkit.Upserter); ok {
+		result, err := upserter.Upsert(ctx, docs...)
+		if err != nil {
 			panic(err)
-		} else if exist {
-			// log.Printf("document %s already exists", doc.ID)
-			continue
 		}
+		log.Printf("inserted %d, updated %d documents", len(result.Inserted), len(result.Updated))
+	} else {
+		for _, doc := range docs {
+			if exist, err := vstore.Exists(ctx, doc.ID); err != nil {
+				panic(err)
+			} else if exist {
+				// log.Printf("document %s already exists", doc.ID)
+				continue
+			}
 
-		_, err := vstore.Index(ctx, doc)
-		if err != nil {
-			panic(err)
+			_, err := vstore.Index(ctx, doc)
+			if err != nil {
+				panic(err)
+			}
 		}
 	}
 
@@ -0,0 +1,296 @@
+package ragkit
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// FilterOp is a type that represents the operator of a Filter
+type FilterOp string
+
+const (
+	FilterEq     FilterOp = "eq"     // Key equals Value (or contains it, for list values)
+	FilterNeq    FilterOp = "neq"    // Negation of FilterEq; documents without Key match
+	FilterIn     FilterOp = "in"     // Key equals one of Values
+	FilterRange  FilterOp = "range"  // Min <= Key <= Max
+	FilterExists FilterOp = "exists" // Key is present
+	FilterAnd    FilterOp = "and"    // All of Filters match
+	FilterOr     FilterOp = "or"     // Any of Filters match
+	FilterNot    FilterOp = "not"    // The only element of Filters doesn't match
+)
+
+// Filter is a backend-neutral boolean expression over Document.Metadata keys.
+// Operands may be strings, booleans, numbers or time.Time.
+// Numbers are compared numerically and strings lexicographically;
+// time.Time operands are compared as RFC 3339 strings, so store dates that way in metadata.
+type Filter struct {
+	Op      FilterOp
+	Key     string    // Metadata key for comparison operators
+	Value   any       // Operand of FilterEq and FilterNeq
+	Values  []any     // Operands of FilterIn
+	Min     any       // Inclusive lower bound of FilterRange, nil for unbounded
+	Max     any       // Inclusive upper bound of FilterRange, nil for unbounded
+	Filters []*Filter // Sub-expressions of FilterAnd, FilterOr and FilterNot
+}
+
+// Eq matches documents whose metadata key equals value
+func Eq(key string, value any) *Filter {
+	return &Filter{Op: FilterEq, Key: key, Value: value}
+}
+
+// Neq matches documents whose metadata key doesn't equal value
+func Neq(key string, value any) *Filter {
+	return &Filter{Op: FilterNeq, Key: key, Value: value}
+}
+
+// In matches documents whose metadata key equals one of values
+func In(key string, values ...any) *Filter {
+	return &Filter{Op: FilterIn, Key: key, Values: values}
+}
+
+// Range matches documents whose metadata key is between min and max (inclusive).
+// Pass nil for an open bound.
+func Range(key string, min, max any) *Filter {
+	return &Filter{Op: FilterRange, Key: key, Min: min, Max: max}
+}
+
+// Exists matches documents having the metadata key
+func Exists(key string) *Filter {
+	return &Filter{Op: FilterExists, Key: key}
+}
+
+// And matches documents matching all of filters
+func And(filters ...*Filter) *Filter {
+	return &Filter{Op: FilterAnd, Filters: filters}
+}
+
+// Or matches documents matching any of filters
+func Or(filters ...*Filter) *Filter {
+	return &Filter{Op: FilterOr, Filters: filters}
+}
+
+// Not matches documents not matching filter
+func Not(filter *Filter) *Filter {
+	return &Filter{Op: FilterNot, Filters: []*Filter{filter}}
+}
+
+// Validate checks the filter is well-formed so backends can compile it
+func (f *Filter) Validate() error {
+	if f == nil {
+		return nil
+	}
+
+	switch f.Op {
+	case FilterEq, FilterNeq, FilterIn, FilterRange, FilterExists:
+		if f.Key == "" {
+			return fmt.Errorf("filter %s: empty key", f.Op)
+		}
+	}
+
+	switch f.Op {
+	case FilterEq, FilterNeq:
+		if _, err := NormalizeFilterValue(f.Value); err != nil {
+			return fmt.Errorf("filter %s %q: %w", f.Op, f.Key, err)
+		}
+	case FilterIn:
+		if len(f.Values) == 0 {
+			return fmt.Errorf("filter in %q: no values", f.Key)
+		}
+		for _, v := range f.Values {
+			if _, err := NormalizeFilterValue(v); err != nil {
+				return fmt.Errorf("filter in %q: %w", f.Key, err)
+			}
+		}
+	case FilterRange:
+		if f.Min == nil && f.Max == nil {
+			return fmt.Errorf("filter range %q: no bounds", f.Key)
+		}
+		min, err := NormalizeFilterValue(f.Min)
+		if err != nil && f.Min != nil {
+			return fmt.Errorf("filter range %q: %w", f.Key, err)
+		}
+		max, err := NormalizeFilterValue(f.Max)
+		if err != nil && f.Max != nil {
+			return fmt.Errorf("filter range %q: %w", f.Key, err)
+		}
+		if f.Min != nil && f.Max != nil && reflect.TypeOf(min) != reflect.TypeOf(max) {
+			return fmt.Errorf("filter range %q: bounds of different types", f.Key)
+		}
+		if _, ok := min.(bool); ok {
+			return fmt.Errorf("filter range %q: boolean bounds", f.Key)
+		}
+		if _, ok := max.(bool); ok {
+			return fmt.Errorf("filter range %q: boolean bounds", f.Key)
+		}
+	case FilterExists:
+	case FilterAnd, FilterOr:
+		if len(f.Filters) == 0 {
+			return fmt.Errorf("filter %s: no sub-filters", f.Op)
+		}
+		for _, sub := range f.Filters {
+			if sub == nil {
+				return fmt.Errorf("filter %s: nil sub-filter", f.Op)
+			}
+			if err := sub.Validate(); err != nil {
+				return err
+			}
+		}
+	case FilterNot:
+		if len(f.Filters) != 1 || f.Filters[0] == nil {
+			return fmt.Errorf("filter not: needs exactly one sub-filter")
+		}
+		return f.Filters[0].Validate()
+	default:
+		return fmt.Errorf("unknown filter op: %q", f.Op)
+	}
+	return nil
+}
+
+// Match reports whether metadata satisfies the filter.
+// A nil filter matches everything.
+func (f *Filter) Match(metadata map[string]any) bool {
+	if f == nil {
+		return true
+	}
+
+	switch f.Op {
+	case FilterEq:
+		v, ok := metadata[f.Key]
+		return ok && containsValue(v, f.Value)
+	case FilterNeq:
+		v, ok := metadata[f.Key]
+		return !ok || !containsValue(v, f.Value)
+	case FilterIn:
+		v, ok := metadata[f.Key]
+		if !ok {
+			return false
+		}
+		for _, want := range f.Values {
+			if containsValue(v, want) {
+				return true
+			}
+		}
+		return false
+	case FilterRange:
+		v, ok := metadata[f.Key]
+		if !ok {
+			return false
+		}
+		if f.Min != nil {
+			if c, ok := compareValues(v, f.Min); !ok || c < 0 {
+				return false
+			}
+		}
+		if f.Max != nil {
+			if c, ok := compareValues(v, f.Max); !ok || c > 0 {
+				return false
+			}
+		}
+		return true
+	case FilterExists:
+		_, ok := metadata[f.Key]
+		return ok
+	case FilterAnd:
+		for _, sub := range f.Filters {
+			if !sub.Match(metadata) {
+				return false
+			}
+		}
+		return true
+	case FilterOr:
+		for _, sub := range f.Filters {
+			if sub.Match(metadata) {
+				return true
+			}
+		}
+		return false
+	case FilterNot:
+		return len(f.Filters) == 1 && !f.Filters[0].Match(metadata)
+	default:
+		return false
+	}
+}
+
+// NormalizeFilterValue converts a filter operand to one of string, bool or float64
+func NormalizeFilterValue(v any) (any, error) {
+	switch v := v.(type) {
+	case string, bool, float64:
+		return v, nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case fmt.Stringer:
+		return v.String(), nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), nil
+	case reflect.Float32:
+		return rv.Float(), nil
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return rv.Bool(), nil
+	}
+	return nil, fmt.Errorf("unsupported filter value type %T", v)
+}
+
+// containsValue reports whether v equals want, or contains it when v is a list
+func containsValue(v, want any) bool {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		for i := range rv.Len() {
+			if c, ok := compareValues(rv.Index(i).Interface(), want); ok && c == 0 {
+				return true
+			}
+		}
+		return false
+	}
+	c, ok := compareValues(v, want)
+	return ok && c == 0
+}
+
+// compareValues compares two operands of the same normalized type
+func compareValues(a, b any) (int, bool) {
+	na, err := NormalizeFilterValue(a)
+	if err != nil {
+		return 0, false
+	}
+	nb, err := NormalizeFilterValue(b)
+	if err != nil {
+		return 0, false
+	}
+
+	switch x := na.(type) {
+	case string:
+		y, ok := nb.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(x, y), true
+	case float64:
+		y, ok := nb.(float64)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	case bool:
+		y, ok := nb.(bool)
+		if !ok || x != y {
+			return 1, ok
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,117 @@
+package ragkit
+
+import (
+	"testing"
+	"time"
+)
+
+type stringer struct{}
+
+func (stringer) String() string { return "s" }
+
+func TestFilterValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  *Filter
+		wantErr bool
+	}{
+		{"nil", nil, false},
+		{"eq", Eq("k", "v"), false},
+		{"eq int", Eq("k", 3), false},
+		{"eq time", Eq("k", time.Now()), false},
+		{"eq stringer", Eq("k", stringer{}), false},
+		{"eq named string", Eq("k", Role("user")), false},
+		{"neq bool", Neq("k", true), false},
+		{"empty key", Eq("", "v"), true},
+		{"unsupported value", Eq("k", []string{"v"}), true},
+		{"nil value", Eq("k", nil), true},
+		{"in", In("k", "a", "b"), false},
+		{"in without values", In("k"), true},
+		{"in with a bad value", In("k", "a", map[string]any{}), true},
+		{"range", Range("k", 1, 2.5), false},
+		{"open range", Range("k", nil, "z"), false},
+		{"range without bounds", Range("k", nil, nil), true},
+		{"range of mixed types", Range("k", 1, "2"), true},
+		{"range of booleans", Range("k", false, nil), true},
+		{"exists", Exists("k"), false},
+		{"exists without key", Exists(""), true},
+		{"and", And(Eq("a", 1), Or(Exists("b"), Not(Eq("c", "d")))), false},
+		{"empty and", And(), true},
+		{"nil in or", Or(Eq("a", 1), nil), true},
+		{"invalid nested", And(Eq("a", 1), Range("b", nil, nil)), true},
+		{"not with two filters", &Filter{Op: FilterNot, Filters: []*Filter{Exists("a"), Exists("b")}}, true},
+		{"not nil", Not(nil), true},
+		{"unknown op", &Filter{Op: "like", Key: "k"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.filter.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, want error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	metadata := map[string]any{
+		"source": "a.txt",
+		"page":   3,
+		"score":  0.5,
+		"draft":  false,
+		"tags":   []any{"go", "rag"},
+		"date":   "2024-05-01T00:00:00Z",
+	}
+	tests := []struct {
+		name   string
+		filter *Filter
+		want   bool
+	}{
+		{"nil", nil, true},
+		{"eq string", Eq("source", "a.txt"), true},
+		{"eq other string", Eq("source", "b.txt"), false},
+		{"eq int against int", Eq("page", 3), true},
+		{"eq float against int", Eq("page", 3.0), true},
+		{"eq int64 against int", Eq("page", int64(3)), true},
+		{"eq string against number", Eq("page", "3"), false},
+		{"eq bool", Eq("draft", false), true},
+		{"eq list contains", Eq("tags", "rag"), true},
+		{"eq list doesn't contain", Eq("tags", "ai"), false},
+		{"eq missing", Eq("author", "x"), false},
+		{"neq", Neq("source", "b.txt"), true},
+		{"neq equal", Neq("source", "a.txt"), false},
+		{"neq missing", Neq("author", "x"), true},
+		{"neq list", Neq("tags", "go"), false},
+		{"in", In("page", 1, 2, 3), true},
+		{"in none", In("page", 1, 2), false},
+		{"in list", In("tags", "ai", "go"), true},
+		{"in missing", In("author", "x"), false},
+		{"range inside", Range("page", 1, 5), true},
+		{"range inclusive", Range("page", 3, 3), true},
+		{"range below", Range("page", 4, nil), false},
+		{"range above", Range("score", nil, 0.4), false},
+		{"range of strings", Range("source", "a", "b"), true},
+		{"range of times", Range("date", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), nil), true},
+		{"range of another type", Range("source", 1, nil), false},
+		{"range missing", Range("author", nil, "z"), false},
+		{"exists", Exists("draft"), true},
+		{"exists missing", Exists("author"), false},
+		{"and", And(Eq("source", "a.txt"), Range("page", 1, nil)), true},
+		{"and one false", And(Eq("source", "a.txt"), Eq("draft", true)), false},
+		{"or", Or(Eq("draft", true), Eq("page", 3)), true},
+		{"or none", Or(Eq("draft", true), Eq("page", 4)), false},
+		{"not", Not(Exists("author")), true},
+		{"not true", Not(Exists("page")), false},
+		{"unknown op", &Filter{Op: "like", Key: "source"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(metadata); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if !Neq("k", "v").Match(nil) || Eq("k", "v").Match(nil) {
+		t.Error("nil metadata: want Neq to match and Eq not to")
+	}
+}
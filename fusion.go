@@ -0,0 +1,223 @@
+package ragkit
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+var _ Retriever = &FusionRetriever{}
+
+// FusionMethod is a type that selects how FusionRetriever merges ranked lists
+type FusionMethod string
+
+const (
+	// FusionRRF scores a document by reciprocal rank fusion, sum(weight / (k + rank)),
+	// which needs no comparable scores across retrievers (default)
+	FusionRRF FusionMethod = "rrf"
+	// FusionWeighted scores a document by the weighted mean of its Scores, counting 0 where missing
+	FusionWeighted FusionMethod = "weighted"
+)
+
+// DefaultRRFConstant is the k of reciprocal rank fusion, from the original paper
+const DefaultRRFConstant = 60
+
+// FusionRetriever is a Retriever that queries several retrievers concurrently and merges their results.
+// Documents are deduplicated by ID, keeping the fields of the first retriever returning them,
+// and their Score is replaced by the fused score, normalized to [0, 1].
+type FusionRetriever struct {
+	retrievers []Retriever
+	weights    []float32
+	method     FusionMethod
+	k          int
+	candidates int
+}
+
+// FusionOption configures a FusionRetriever
+type FusionOption func(*FusionRetriever)
+
+// WithFusionMethod sets the method merging the results (default: FusionRRF)
+func WithFusionMethod(method FusionMethod) FusionOption {
+	return func(f *FusionRetriever) {
+		f.method = method
+	}
+}
+
+// WithFusionWeights sets the weight of each retriever, in order (default: 1 for all)
+func WithFusionWeights(weights ...float32) FusionOption {
+	return func(f *FusionRetriever) {
+		f.weights = weights
+	}
+}
+
+// WithRRFConstant sets the k of FusionRRF; lower values favor top ranks (default: DefaultRRFConstant)
+func WithRRFConstant(k int) FusionOption {
+	return func(f *FusionRetriever) {
+		f.k = k
+	}
+}
+
+// WithFusionCandidates sets the number of documents fetched from each retriever (default: topK)
+func WithFusionCandidates(n int) FusionOption {
+	return func(f *FusionRetriever) {
+		f.candidates = n
+	}
+}
+
+// NewFusionRetriever creates a FusionRetriever over retrievers
+func NewFusionRetriever(retrievers []Retriever, opts ...FusionOption) *FusionRetriever {
+	f := &FusionRetriever{
+		retrievers: retrievers,
+		method:     FusionRRF,
+		k:          DefaultRRFConstant,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// Retrieve passes query to every retriever, so they must share the same embedding space
+func (f *FusionRetriever) Retrieve(ctx context.Context, query []float32, topK int, metadataFieldNames ...string) ([]RetrievedDoc, error) {
+	return f.fanOut(ctx, topK, func(r Retriever, n int) ([]RetrievedDoc, error) {
+		return r.Retrieve(ctx, query, n, metadataFieldNames...)
+	})
+}
+
+// RetrieveText passes text to every retriever, each embedding it with its own embedder
+func (f *FusionRetriever) RetrieveText(ctx context.Context, text string, topK int, metadataFieldNames ...string) ([]RetrievedDoc, error) {
+	return f.fanOut(ctx, topK, func(r Retriever, n int) ([]RetrievedDoc, error) {
+		return r.RetrieveText(ctx, text, n, metadataFieldNames...)
+	})
+}
+
+func (f *FusionRetriever) String() string {
+	return fmt.Sprintf("FusionRetriever(method: %s, retrievers: %d)", f.method, len(f.retrievers))
+}
+
+// fanOut runs retrieve on every retriever concurrently and fuses the results.
+// It fails if any retriever fails.
+func (f *FusionRetriever) fanOut(ctx context.Context, topK int, retrieve func(r Retriever, n int) ([]RetrievedDoc, error)) ([]RetrievedDoc, error) {
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	if topK <= 0 {
+		return nil, ctx.Err()
+	}
+
+	n := topK
+	if f.candidates > 0 {
+		n = f.candidates
+	}
+
+	lists := make([][]RetrievedDoc, len(f.retrievers))
+	errs := make([]error, len(f.retrievers))
+	var wg sync.WaitGroup
+	for i, r := range f.retrievers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lists[i], errs[i] = retrieve(r, n)
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("retriever %d: %w", i, errs[i])
+			}
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return f.fuse(lists, topK), nil
+}
+
+func (f *FusionRetriever) validate() error {
+	if f.weights != nil && len(f.weights) != len(f.retrievers) {
+		return fmt.Errorf("%d weights for %d retrievers", len(f.weights), len(f.retrievers))
+	}
+	for _, w := range f.weights {
+		if w < 0 {
+			return fmt.Errorf("negative weight %v", w)
+		}
+	}
+	switch f.method {
+	case FusionRRF:
+		if f.k < 0 {
+			return fmt.Errorf("negative RRF constant %d", f.k)
+		}
+	case FusionWeighted:
+	default:
+		return fmt.Errorf("unknown fusion method: %q", f.method)
+	}
+	return nil
+}
+
+func (f *FusionRetriever) weight(i int) float32 {
+	if f.weights == nil {
+		return 1
+	}
+	return f.weights[i]
+}
+
+// fuse merges the ranked lists of every retriever into the topK best documents
+func (f *FusionRetriever) fuse(lists [][]RetrievedDoc, topK int) []RetrievedDoc {
+	// the score of a document ranked first by every retriever, to normalize to [0, 1]
+	var best float32
+	for i := range lists {
+		best += f.weight(i)
+	}
+	if f.method == FusionRRF {
+		best /= float32(f.k + 1)
+	}
+
+	fused := make(map[string]*RetrievedDoc)
+	var order []string
+	for i, list := range lists {
+		seen := make(map[string]bool) // a retriever counts once per document
+		for rank, doc := range list {
+			if seen[doc.ID] {
+				continue
+			}
+			seen[doc.ID] = true
+
+			var score float32
+			switch f.method {
+			case FusionRRF:
+				score = f.weight(i) / float32(f.k+rank+1)
+			case FusionWeighted:
+				score = f.weight(i) * doc.Score
+			}
+
+			d, ok := fused[doc.ID]
+			if !ok {
+				doc.Score = 0
+				d = &doc
+				fused[doc.ID] = d
+				order = append(order, doc.ID)
+			}
+			d.Score += score
+		}
+	}
+
+	results := make([]RetrievedDoc, 0, len(order))
+	for _, id := range order {
+		doc := *fused[id]
+		if best > 0 {
+			doc.Score = clamp01(doc.Score / best)
+		}
+		results = append(results, doc)
+	}
+	slices.SortFunc(results, func(a, b RetrievedDoc) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	return results
+}
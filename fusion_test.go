@@ -0,0 +1,194 @@
+package ragkit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+// scored returns documents with the given IDs and scores, alternating
+func scored(idScores ...any) []RetrievedDoc {
+	var docs []RetrievedDoc
+	for i := 0; i+1 < len(idScores); i += 2 {
+		docs = append(docs, RetrievedDoc{ID: idScores[i].(string), Score: float32(idScores[i+1].(float64))})
+	}
+	return docs
+}
+
+func TestFusionScores(t *testing.T) {
+	first := scored("a", 1.0, "b", 0.5, "c", 0.25)
+	second := scored("b", 0.8, "d", 0.4)
+
+	tests := []struct {
+		name  string
+		lists [][]RetrievedDoc
+		opts  []FusionOption
+		want  []RetrievedDoc
+	}{
+		{
+			name:  "rrf",
+			lists: [][]RetrievedDoc{first, second},
+			// sum(1 / (60 + rank)), normalized by 2 / 61
+			want: scored(
+				"b", (1.0/62+1.0/61)*61/2,
+				"a", 0.5,
+				"d", 61.0/124,
+				"c", 61.0/126,
+			),
+		},
+		{
+			name:  "rrf weighted and k",
+			lists: [][]RetrievedDoc{first, second},
+			opts:  []FusionOption{WithFusionWeights(1, 3), WithRRFConstant(0)},
+			// weight / (0 + rank), normalized by 4 / 1
+			want: scored(
+				"b", (1.0/2+3.0/1)/4,
+				"d", (3.0/2)/4,
+				"a", 0.25,
+				"c", (1.0/3)/4,
+			),
+		},
+		{
+			name:  "weighted",
+			lists: [][]RetrievedDoc{first, second},
+			opts:  []FusionOption{WithFusionMethod(FusionWeighted), WithFusionWeights(2, 1)},
+			// sum(weight * score), normalized by 3
+			want: scored(
+				"a", 2.0/3,
+				"b", (2*0.5+0.8)/3,
+				"c", 0.5/3,
+				"d", 0.4/3,
+			),
+		},
+		{
+			name:  "weighted clamped",
+			lists: [][]RetrievedDoc{scored("a", 3.0, "b", -1.0), scored("a", 2.0)},
+			opts:  []FusionOption{WithFusionMethod(FusionWeighted)},
+			want:  scored("a", 1.0, "b", 0.0),
+		},
+		{
+			name:  "ties by ID",
+			lists: [][]RetrievedDoc{scored("b", 1.0), scored("a", 1.0)},
+			want:  scored("a", 0.5, "b", 0.5),
+		},
+		{
+			name:  "counted once per retriever",
+			lists: [][]RetrievedDoc{scored("a", 1.0, "a", 0.5)},
+			want:  scored("a", 1.0),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var retrievers []Retriever
+			for _, list := range tc.lists {
+				retrievers = append(retrievers, &stubRetriever{results: map[string][]RetrievedDoc{"q": list}})
+			}
+			got, err := NewFusionRetriever(retrievers, tc.opts...).RetrieveText(context.Background(), "q", 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if docIDs(got) != docIDs(tc.want) {
+				t.Fatalf("got %s, want %s", docIDs(got), docIDs(tc.want))
+			}
+			for i := range got {
+				if math.Abs(float64(got[i].Score-tc.want[i].Score)) > 1e-6 {
+					t.Errorf("%s scored %v, want %v", got[i].ID, got[i].Score, tc.want[i].Score)
+				}
+				if got[i].Score < 0 || got[i].Score > 1 {
+					t.Errorf("%s scored %v, outside [0, 1]", got[i].ID, got[i].Score)
+				}
+			}
+		})
+	}
+}
+
+func TestFusionKeepsFirstFields(t *testing.T) {
+	first := &stubRetriever{results: map[string][]RetrievedDoc{"q": {
+		{ID: "a", Text: "from first", Metadata: map[string]any{"by": "first"}, Score: 0.9},
+	}}}
+	second := &stubRetriever{results: map[string][]RetrievedDoc{"q": {
+		{ID: "b", Text: "only in second", Score: 0.8},
+		{ID: "a", Text: "from second", Metadata: map[string]any{"by": "second"}, Score: 0.7},
+	}}}
+
+	got, err := NewFusionRetriever([]Retriever{first, second}).RetrieveText(context.Background(), "q", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if docIDs(got) != "a,b" {
+		t.Fatalf("got %s, want a,b", docIDs(got))
+	}
+	if got[0].Text != "from first" || got[0].Metadata["by"] != "first" {
+		t.Errorf("a = %+v, want the fields of the first retriever", got[0])
+	}
+	if got[1].Text != "only in second" {
+		t.Errorf("b = %+v, want the fields of the second retriever", got[1])
+	}
+}
+
+func TestFusionCandidates(t *testing.T) {
+	retrievers := []*stubRetriever{
+		{results: map[string][]RetrievedDoc{"q": makeDocs("a", "b", "c")}},
+		{results: map[string][]RetrievedDoc{"q": makeDocs("x", "y", "c")}},
+	}
+	for _, tc := range []struct {
+		name       string
+		opts       []FusionOption
+		wantFetch  int
+		wantResult string
+	}{
+		{"default", nil, 1, "a"},
+		// c, ranked third by both, is only seen with deeper candidate lists
+		{"candidates", []FusionOption{WithFusionCandidates(3)}, 3, "c"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := NewFusionRetriever([]Retriever{retrievers[0], retrievers[1]}, tc.opts...)
+			got, err := f.RetrieveText(context.Background(), "q", 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if docIDs(got) != tc.wantResult {
+				t.Errorf("got %s, want %s", docIDs(got), tc.wantResult)
+			}
+			for _, r := range retrievers {
+				if topK := r.topKs[len(r.topKs)-1]; topK != tc.wantFetch {
+					t.Errorf("fetched %d candidates, want %d", topK, tc.wantFetch)
+				}
+			}
+		})
+	}
+}
+
+func TestFusionErrors(t *testing.T) {
+	ok := &stubRetriever{results: map[string][]RetrievedDoc{"q": makeDocs("a")}}
+	errFirst, errSecond := errors.New("first failed"), errors.New("second failed")
+
+	_, err := NewFusionRetriever([]Retriever{&stubRetriever{err: errFirst}, ok, &stubRetriever{err: errSecond}}).
+		RetrieveText(context.Background(), "q", 1)
+	if !errors.Is(err, errFirst) || !errors.Is(err, errSecond) {
+		t.Errorf("err = %v, want both retriever errors", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		opts []FusionOption
+	}{
+		{"too few weights", []FusionOption{WithFusionWeights(1)}},
+		{"too many weights", []FusionOption{WithFusionWeights(1, 1, 1)}},
+		{"negative weight", []FusionOption{WithFusionWeights(1, -1)}},
+		{"negative k", []FusionOption{WithRRFConstant(-1)}},
+		{"unknown method", []FusionOption{WithFusionMethod("max")}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &stubRetriever{results: ok.results}
+			_, err := NewFusionRetriever([]Retriever{s, s}, tc.opts...).RetrieveText(context.Background(), "q", 1)
+			if err == nil {
+				t.Error("invalid configuration accepted")
+			}
+			if len(s.queries) != 0 {
+				t.Errorf("retrievers queried %d times before validation", len(s.queries))
+			}
+		})
+	}
+}
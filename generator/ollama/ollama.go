@@ -0,0 +1,131 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+
+	ollama_api "github.com/ollama/ollama/api"
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+var (
+	_ ragkit.Generator          = &Ollama{}
+	_ ragkit.StreamingGenerator = &Ollama{}
+)
+
+// errStopped stops a streamed chat when the iteration stops
+var errStopped = errors.New("stopped")
+
+type Ollama struct {
+	client  *ollama_api.Client
+	model   string
+	options map[string]any
+}
+
+// Option configures an Ollama generator
+type Option func(*Ollama)
+
+// WithTemperature sets the sampling temperature (default: the model's)
+func WithTemperature(temperature float64) Option {
+	return func(o *Ollama) {
+		o.options["temperature"] = temperature
+	}
+}
+
+// WithMaxTokens bounds the number of tokens of a reply (default: unbounded)
+func WithMaxTokens(n int) Option {
+	return func(o *Ollama) {
+		o.options["num_predict"] = n
+	}
+}
+
+// WithOptions sets model options of Ollama, like "num_ctx" or "seed"
+func WithOptions(options map[string]any) Option {
+	return func(o *Ollama) {
+		for k, v := range options {
+			o.options[k] = v
+		}
+	}
+}
+
+func New(client *ollama_api.Client, model string, opts ...Option) *Ollama {
+	o := &Ollama{
+		client:  client,
+		model:   model,
+		options: make(map[string]any),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func (o *Ollama) String() string {
+	return fmt.Sprintf("Ollama(model: %s)", o.model)
+}
+
+func (o *Ollama) Generate(ctx context.Context, messages ...ragkit.Message) (string, error) {
+	var reply string
+	done := false
+	err := o.client.Chat(ctx, o.request(messages, false), func(resp ollama_api.ChatResponse) error {
+		reply += resp.Message.Content
+		done = resp.Done
+		return nil
+	})
+	if err == nil && !done {
+		err = incomplete(ctx)
+	}
+	if err != nil {
+		return "", err
+	}
+	return reply, nil
+}
+
+// GenerateStream streams the reply as Ollama sends it
+func (o *Ollama) GenerateStream(ctx context.Context, messages ...ragkit.Message) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		// Chat calls back in this goroutine, for every line of the response
+		done := false
+		err := o.client.Chat(ctx, o.request(messages, true), func(resp ollama_api.ChatResponse) error {
+			if resp.Message.Content != "" && !yield(resp.Message.Content, nil) {
+				return errStopped
+			}
+			done = resp.Done
+			return nil
+		})
+		if err == nil && !done {
+			err = incomplete(ctx)
+		}
+		if err != nil && !errors.Is(err, errStopped) {
+			yield("", err)
+		}
+	}
+}
+
+// incomplete returns the error of a reply that ended before Ollama marked it done,
+// as the client doesn't report the failure to read the rest of it
+func incomplete(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return io.ErrUnexpectedEOF
+}
+
+func (o *Ollama) request(messages []ragkit.Message, stream bool) *ollama_api.ChatRequest {
+	req := &ollama_api.ChatRequest{
+		Model:    o.model,
+		Messages: make([]ollama_api.Message, len(messages)),
+		Stream:   &stream,
+		Options:  o.options,
+	}
+	for i, m := range messages {
+		req.Messages[i] = ollama_api.Message{Role: string(m.Role), Content: m.Content}
+	}
+	return req
+}
@@ -0,0 +1,195 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	ollama_api "github.com/ollama/ollama/api"
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+// chatRequest is a chat request received by the test server
+type chatRequest struct {
+	ollama_api.ChatRequest
+	ctx context.Context // Context of the HTTP request
+}
+
+// newTestGenerator returns a generator whose chat requests are answered by handle,
+// and the requests it received
+func newTestGenerator(t *testing.T, handle func(w http.ResponseWriter, req chatRequest), opts ...Option) (*Ollama, *[]chatRequest) {
+	t.Helper()
+	var requests []chatRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			http.NotFound(w, r)
+			return
+		}
+		req := chatRequest{ctx: r.Context()}
+		if err := json.NewDecoder(r.Body).Decode(&req.ChatRequest); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		requests = append(requests, req)
+		handle(w, req)
+	}))
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(ollama_api.NewClient(u, srv.Client()), "test-model", opts...), &requests
+}
+
+// writeChat writes the lines of a chat response, each piece of content in a line, the last one marked done
+func writeChat(w http.ResponseWriter, done bool, pieces ...string) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	for i, piece := range pieces {
+		enc.Encode(ollama_api.ChatResponse{
+			Model:   "test-model",
+			Message: ollama_api.Message{Role: "assistant", Content: piece},
+			Done:    done && i == len(pieces)-1,
+		})
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+}
+
+var chat = []ragkit.Message{
+	{Role: ragkit.RoleSystem, Content: "be brief"},
+	{Role: ragkit.RoleUser, Content: "hi"},
+	{Role: ragkit.RoleAssistant, Content: "hello"},
+	{Role: ragkit.RoleUser, Content: "how are you?"},
+}
+
+func TestGenerate(t *testing.T) {
+	o, requests := newTestGenerator(t, func(w http.ResponseWriter, req chatRequest) {
+		writeChat(w, true, "fine")
+	}, WithTemperature(0.2), WithMaxTokens(64), WithOptions(map[string]any{"seed": 7}))
+
+	got, err := o.Generate(context.Background(), chat...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "fine" {
+		t.Errorf("got %q, want fine", got)
+	}
+
+	req := (*requests)[0]
+	if req.Model != "test-model" || req.Stream == nil || *req.Stream {
+		t.Errorf("model %q, stream %v, want test-model without streaming", req.Model, req.Stream)
+	}
+	if len(req.Messages) != len(chat) {
+		t.Fatalf("sent %d messages, want %d", len(req.Messages), len(chat))
+	}
+	for i, m := range chat {
+		if req.Messages[i].Role != string(m.Role) || req.Messages[i].Content != m.Content {
+			t.Errorf("message %d = %+v, want %+v", i, req.Messages[i], m)
+		}
+	}
+	// options are decoded from JSON
+	if req.Options["temperature"] != 0.2 || req.Options["num_predict"] != 64.0 || req.Options["seed"] != 7.0 {
+		t.Errorf("options = %v", req.Options)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	o, _ := newTestGenerator(t, func(w http.ResponseWriter, req chatRequest) {
+		writeChat(w, false, "cut")
+	})
+	if _, err := o.Generate(context.Background(), chat...); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("reply not done: got error %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	o, _ = newTestGenerator(t, func(w http.ResponseWriter, req chatRequest) {
+		http.Error(w, `{"error": "model not found"}`, http.StatusNotFound)
+	})
+	if _, err := o.Generate(context.Background(), chat...); err == nil {
+		t.Error("error status: no error")
+	}
+}
+
+func TestGenerateStream(t *testing.T) {
+	o, requests := newTestGenerator(t, func(w http.ResponseWriter, req chatRequest) {
+		writeChat(w, true, "Hel", "", "lo", " world")
+	})
+
+	var pieces []string
+	for piece, err := range o.GenerateStream(context.Background(), chat...) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		pieces = append(pieces, piece)
+	}
+	if !slices.Equal(pieces, []string{"Hel", "lo", " world"}) {
+		t.Errorf("got %q", pieces)
+	}
+	if req := (*requests)[0]; req.Stream == nil || !*req.Stream {
+		t.Error("request not streamed")
+	}
+}
+
+func TestGenerateStreamIncomplete(t *testing.T) {
+	o, _ := newTestGenerator(t, func(w http.ResponseWriter, req chatRequest) {
+		writeChat(w, false, "cut", "short")
+	})
+	var text string
+	var err error
+	for piece, e := range o.GenerateStream(context.Background(), chat...) {
+		text, err = text+piece, e
+	}
+	if text != "cutshort" || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("got %q, %v, want cutshort then %v", text, err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestGenerateStreamCancel(t *testing.T) {
+	// the server sends a piece, then waits for the client to go away
+	gone := make(chan struct{})
+	o, _ := newTestGenerator(t, func(w http.ResponseWriter, req chatRequest) {
+		writeChat(w, false, "first")
+		<-req.ctx.Done()
+		gone <- struct{}{}
+	})
+
+	// stopping the iteration cancels the request, without error
+	for _, err := range o.GenerateStream(context.Background(), chat...) {
+		if err != nil {
+			t.Errorf("got error %v after stopping", err)
+		}
+		break
+	}
+	select {
+	case <-gone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request not canceled after the iteration stopped")
+	}
+
+	// canceling the context mid-stream ends the sequence with an error
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var pieces []string
+	var err error
+	for piece, e := range o.GenerateStream(ctx, chat...) {
+		if e != nil {
+			err = e
+			break
+		}
+		pieces = append(pieces, piece)
+		cancel()
+	}
+	<-gone
+	if !slices.Equal(pieces, []string{"first"}) || !errors.Is(err, context.Canceled) {
+		t.Errorf("got %q, %v, want first then context.Canceled", pieces, err)
+	}
+}
@@ -0,0 +1,134 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"iter"
+
+	oai "github.com/openai/openai-go"
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+var (
+	_ ragkit.Generator          = &OpenAI{}
+	_ ragkit.StreamingGenerator = &OpenAI{}
+)
+
+type OpenAI struct {
+	client      *oai.Client
+	model       string
+	temperature *float64
+	maxTokens   int
+}
+
+// Option configures an OpenAI generator
+type Option func(*OpenAI)
+
+// WithTemperature sets the sampling temperature (default: the model's)
+func WithTemperature(temperature float64) Option {
+	return func(o *OpenAI) {
+		o.temperature = &temperature
+	}
+}
+
+// WithMaxTokens bounds the number of tokens of a reply (default: the model's limit)
+func WithMaxTokens(n int) Option {
+	return func(o *OpenAI) {
+		o.maxTokens = n
+	}
+}
+
+func New(client *oai.Client, model string, opts ...Option) *OpenAI {
+	o := &OpenAI{
+		client: client,
+		model:  model,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func (o *OpenAI) String() string {
+	return fmt.Sprintf("OpenAI(%s)", o.model)
+}
+
+func (o *OpenAI) Generate(ctx context.Context, messages ...ragkit.Message) (string, error) {
+	params, err := o.params(messages)
+	if err != nil {
+		return "", err
+	}
+	resp, err := o.client.Chat.Completions.New(ctx, params)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no choices in response")
+	}
+
+	msg := resp.Choices[0].Message
+	if msg.Content == "" && msg.Refusal != "" {
+		return "", fmt.Errorf("refused: %s", msg.Refusal)
+	}
+	return msg.Content, nil
+}
+
+// GenerateStream streams the reply with server-sent events
+func (o *OpenAI) GenerateStream(ctx context.Context, messages ...ragkit.Message) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		params, err := o.params(messages)
+		if err != nil {
+			yield("", err)
+			return
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		stream := o.client.Chat.Completions.NewStreaming(ctx, params)
+		defer stream.Close()
+
+		for stream.Next() {
+			chunk := stream.Current()
+			if len(chunk.Choices) == 0 {
+				continue // usage
+			}
+			delta := chunk.Choices[0].Delta
+			if delta.Refusal != "" {
+				yield("", fmt.Errorf("refused: %s", delta.Refusal))
+				return
+			}
+			if delta.Content != "" && !yield(delta.Content, nil) {
+				return
+			}
+		}
+		if err := stream.Err(); err != nil {
+			yield("", err)
+		}
+	}
+}
+
+func (o *OpenAI) params(messages []ragkit.Message) (oai.ChatCompletionNewParams, error) {
+	params := oai.ChatCompletionNewParams{
+		Model:    o.model,
+		Messages: make([]oai.ChatCompletionMessageParamUnion, len(messages)),
+	}
+	for i, m := range messages {
+		switch m.Role {
+		case ragkit.RoleSystem:
+			params.Messages[i] = oai.SystemMessage(m.Content)
+		case ragkit.RoleUser:
+			params.Messages[i] = oai.UserMessage(m.Content)
+		case ragkit.RoleAssistant:
+			params.Messages[i] = oai.AssistantMessage(m.Content)
+		default:
+			return params, fmt.Errorf("unknown role %q", m.Role)
+		}
+	}
+	if o.temperature != nil {
+		params.Temperature = oai.Float(*o.temperature)
+	}
+	if o.maxTokens > 0 {
+		params.MaxCompletionTokens = oai.Int(int64(o.maxTokens))
+	}
+	return params, nil
+}
@@ -0,0 +1,253 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	oai "github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+// chatRequest is the part of a chat completion request the tests check
+type chatRequest struct {
+	Model    string `json:"model"`
+	Messages []struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	} `json:"messages"`
+	Temperature         *float64 `json:"temperature"`
+	MaxCompletionTokens *int     `json:"max_completion_tokens"`
+	Stream              bool     `json:"stream"`
+
+	ctx context.Context // Context of the HTTP request
+}
+
+// newTestGenerator returns a generator whose chat completion requests are answered by handle,
+// and the requests it received
+func newTestGenerator(t *testing.T, handle func(w http.ResponseWriter, req chatRequest), opts ...Option) (*OpenAI, *[]chatRequest) {
+	t.Helper()
+	var requests []chatRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.ctx = r.Context()
+		requests = append(requests, req)
+		handle(w, req)
+	}))
+	t.Cleanup(srv.Close)
+
+	client := oai.NewClient(option.WithBaseURL(srv.URL), option.WithAPIKey("test"), option.WithMaxRetries(0))
+	return New(&client, "test-model", opts...), &requests
+}
+
+// reply writes a chat completion of message
+func reply(w http.ResponseWriter, message map[string]any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"id":      "1",
+		"object":  "chat.completion",
+		"model":   "test-model",
+		"choices": []map[string]any{{"index": 0, "finish_reason": "stop", "message": message}},
+	})
+}
+
+var chat = []ragkit.Message{
+	{Role: ragkit.RoleSystem, Content: "be brief"},
+	{Role: ragkit.RoleUser, Content: "hi"},
+	{Role: ragkit.RoleAssistant, Content: "hello"},
+	{Role: ragkit.RoleUser, Content: "how are you?"},
+}
+
+func TestGenerate(t *testing.T) {
+	o, requests := newTestGenerator(t, func(w http.ResponseWriter, req chatRequest) {
+		reply(w, map[string]any{"role": "assistant", "content": "fine"})
+	}, WithTemperature(0.2), WithMaxTokens(64))
+
+	got, err := o.Generate(context.Background(), chat...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "fine" {
+		t.Errorf("got %q, want fine", got)
+	}
+
+	req := (*requests)[0]
+	if req.Model != "test-model" || req.Stream {
+		t.Errorf("model %q, stream %v", req.Model, req.Stream)
+	}
+	if len(req.Messages) != len(chat) {
+		t.Fatalf("sent %d messages, want %d", len(req.Messages), len(chat))
+	}
+	for i, m := range chat {
+		if req.Messages[i].Role != string(m.Role) || req.Messages[i].Content != m.Content {
+			t.Errorf("message %d = %+v, want %+v", i, req.Messages[i], m)
+		}
+	}
+	if req.Temperature == nil || *req.Temperature != 0.2 || req.MaxCompletionTokens == nil || *req.MaxCompletionTokens != 64 {
+		t.Errorf("temperature %v, max tokens %v, want 0.2 and 64", req.Temperature, req.MaxCompletionTokens)
+	}
+}
+
+func TestGenerateDefaults(t *testing.T) {
+	o, requests := newTestGenerator(t, func(w http.ResponseWriter, req chatRequest) {
+		reply(w, map[string]any{"role": "assistant", "content": "ok"})
+	})
+	if _, err := o.Generate(context.Background(), chat[1]); err != nil {
+		t.Fatal(err)
+	}
+	if req := (*requests)[0]; req.Temperature != nil || req.MaxCompletionTokens != nil {
+		t.Errorf("temperature %v, max tokens %v, want none", req.Temperature, req.MaxCompletionTokens)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		handle   func(w http.ResponseWriter, req chatRequest)
+		messages []ragkit.Message
+		want     string
+	}{
+		{
+			"refusal",
+			func(w http.ResponseWriter, req chatRequest) {
+				reply(w, map[string]any{"role": "assistant", "content": "", "refusal": "no way"})
+			},
+			chat, "refused: no way",
+		},
+		{
+			"no choices",
+			func(w http.ResponseWriter, req chatRequest) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"id": "1", "object": "chat.completion", "choices": []}`))
+			},
+			chat, "no choices",
+		},
+		{
+			"status",
+			func(w http.ResponseWriter, req chatRequest) {
+				http.Error(w, `{"error": {"message": "bad key"}}`, http.StatusUnauthorized)
+			},
+			chat, "401",
+		},
+		{
+			"unknown role",
+			func(w http.ResponseWriter, req chatRequest) { t.Error("request sent") },
+			[]ragkit.Message{{Role: "tool", Content: "x"}}, `unknown role "tool"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, _ := newTestGenerator(t, tt.handle)
+			_, err := o.Generate(context.Background(), tt.messages...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// writeEvents writes chat completion chunks of pieces as server-sent events, flushing each
+func writeEvents(w http.ResponseWriter, pieces ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, piece := range pieces {
+		b, _ := json.Marshal(map[string]any{
+			"id":      "1",
+			"object":  "chat.completion.chunk",
+			"model":   "test-model",
+			"choices": []map[string]any{{"index": 0, "delta": map[string]any{"content": piece}}},
+		})
+		w.Write([]byte("data: " + string(b) + "\n\n"))
+		w.(http.Flusher).Flush()
+	}
+}
+
+func TestGenerateStream(t *testing.T) {
+	o, requests := newTestGenerator(t, func(w http.ResponseWriter, req chatRequest) {
+		writeEvents(w, "Hel", "", "lo", " world")
+		// a usage chunk without choices
+		w.Write([]byte(`data: {"id": "1", "object": "chat.completion.chunk", "choices": [], "usage": {"total_tokens": 3}}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	})
+
+	var pieces []string
+	for piece, err := range o.GenerateStream(context.Background(), chat...) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		pieces = append(pieces, piece)
+	}
+	if !slices.Equal(pieces, []string{"Hel", "lo", " world"}) {
+		t.Errorf("got %q", pieces)
+	}
+	if !(*requests)[0].Stream {
+		t.Error("request not streamed")
+	}
+}
+
+func TestGenerateStreamRefusal(t *testing.T) {
+	o, _ := newTestGenerator(t, func(w http.ResponseWriter, req chatRequest) {
+		writeEvents(w, "Sure")
+		w.Write([]byte(`data: {"id": "1", "object": "chat.completion.chunk", "choices": [{"index": 0, "delta": {"refusal": "no way"}}]}` + "\n\n"))
+	})
+	var text string
+	var err error
+	for piece, e := range o.GenerateStream(context.Background(), chat...) {
+		text, err = text+piece, e
+	}
+	if text != "Sure" || err == nil || !strings.Contains(err.Error(), "refused: no way") {
+		t.Errorf("got %q, %v, want Sure then a refusal", text, err)
+	}
+}
+
+func TestGenerateStreamCancel(t *testing.T) {
+	// the server sends a piece, then waits for the client to go away
+	gone := make(chan struct{})
+	o, _ := newTestGenerator(t, func(w http.ResponseWriter, req chatRequest) {
+		writeEvents(w, "first")
+		<-req.ctx.Done()
+		gone <- struct{}{}
+	})
+
+	// stopping the iteration cancels the request
+	for range o.GenerateStream(context.Background(), chat...) {
+		break
+	}
+	select {
+	case <-gone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request not canceled after the iteration stopped")
+	}
+
+	// canceling the context mid-stream ends the sequence with an error
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var pieces []string
+	var err error
+	for piece, e := range o.GenerateStream(ctx, chat...) {
+		if e != nil {
+			err = e
+			break
+		}
+		pieces = append(pieces, piece)
+		cancel()
+	}
+	<-gone
+	if !slices.Equal(pieces, []string{"first"}) || !errors.Is(err, context.Canceled) {
+		t.Errorf("got %q, %v, want first then context.Canceled", pieces, err)
+	}
+}
@@ -2,6 +2,7 @@ package helper
 
 import (
 	"cmp"
+	"context"
 
 	oai "github.com/openai/openai-go"
 	oai_option "github.com/openai/openai-go/option"
@@ -27,7 +28,10 @@ func NewPGVectorOpenAIVectorStore(
 	embedder := oai_embedder.New(&oaiClient, cmp.Or(oaiEmbedModel, DefaultOAIEmbedModel))
 
 	// initialize pgvector
-	pgvector := pgvector_vstore.New(pgvectorConnStr, 1536, vectorDBClassName, embedder)
+	pgvector, err := pgvector_vstore.Connect(context.Background(), pgvectorConnStr, 1536, vectorDBClassName, embedder)
+	if err != nil {
+		return nil, err
+	}
 
 	return pgvector, nil
 }
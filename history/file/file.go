@@ -0,0 +1,124 @@
+// Package file provides a ragkit.HistoryStore keeping conversations in a local directory.
+//
+// Each conversation is a file of JSON lines, one per message, which Append adds to.
+// A line torn by a crash is skipped when reading.
+// A directory must be used by one process at a time.
+package file
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+var _ ragkit.HistoryStore = &File{}
+
+// ext is the extension of conversation files
+const ext = ".jsonl"
+
+type File struct {
+	dir string
+	mu  sync.Mutex // serializes writes to the files
+}
+
+// line is a message as written in a conversation file
+type line struct {
+	Role    ragkit.Role `json:"role"`
+	Content string      `json:"content"`
+}
+
+// Open opens the store in dir, creating the directory if it doesn't exist
+func Open(dir string) (*File, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &File{dir: dir}, nil
+}
+
+func (f *File) Messages(ctx context.Context, conversationID string) ([]ragkit.Message, error) {
+	r, err := os.Open(f.path(conversationID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var messages []ragkit.Message
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64<<20)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var l line
+		if err := json.Unmarshal(scanner.Bytes(), &l); err != nil {
+			continue // torn line
+		}
+		messages = append(messages, ragkit.Message{Role: l.Role, Content: l.Content})
+	}
+	return messages, scanner.Err()
+}
+
+func (f *File) Append(ctx context.Context, conversationID string, messages ...ragkit.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	// a line starts the write, so that a line torn before doesn't swallow the first message
+	b := []byte{'\n'}
+	for _, m := range messages {
+		l, err := json.Marshal(line{Role: m.Role, Content: m.Content})
+		if err != nil {
+			return err
+		}
+		b = append(append(b, l...), '\n')
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w, err := os.OpenFile(f.path(conversationID), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(b); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Sync(); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (f *File) Clear(ctx context.Context, conversationID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	err := os.Remove(f.path(conversationID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// path returns the file of a conversation, named after the hex of its ID so that any ID makes a safe file name.
+// Long IDs are hashed to keep the name within file system limits.
+func (f *File) path(conversationID string) string {
+	name := hex.EncodeToString([]byte(conversationID))
+	if len(name) > 128 {
+		sum := sha256.Sum256([]byte(conversationID))
+		name = "h" + hex.EncodeToString(sum[:])
+	}
+	return filepath.Join(f.dir, name+ext)
+}
@@ -0,0 +1,138 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+var conversation = []ragkit.Message{
+	{Role: ragkit.RoleUser, Content: "Who designed Go?"},
+	{Role: ragkit.RoleAssistant, Content: "Robert Griesemer, Rob Pike and Ken Thompson.\nAt Google."},
+	{Role: ragkit.RoleUser, Content: `And "Rust"?`},
+}
+
+func messages(t *testing.T, f *File, id string) []ragkit.Message {
+	t.Helper()
+	got, err := f.Messages(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "history")
+	f, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := messages(t, f, "c1"); got != nil {
+		t.Errorf("unknown conversation: got %v", got)
+	}
+	if err := f.Append(ctx, "c1", conversation[:2]...); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Append(ctx, "c1", conversation[2]); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Append(ctx, "c1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Append(ctx, "c2", conversation[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	// reopened, the store reads the same conversations
+	f, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := messages(t, f, "c1"); !slices.Equal(got, conversation) {
+		t.Errorf("c1 = %q, want %q", got, conversation)
+	}
+	if got := messages(t, f, "c2"); !slices.Equal(got, conversation[:1]) {
+		t.Errorf("c2 = %q, want %q", got, conversation[:1])
+	}
+
+	if err := f.Clear(ctx, "c1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := messages(t, f, "c1"); got != nil {
+		t.Errorf("cleared conversation: got %v", got)
+	}
+	if err := f.Clear(ctx, "missing"); err != nil {
+		t.Errorf("clearing a missing conversation: %v", err)
+	}
+}
+
+func TestConversationIDs(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	f, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := []string{"", "../escape", "a/b", "한국어", strings.Repeat("long", 100), strings.Repeat("long", 100) + "!"}
+	for i, id := range ids {
+		if err := f.Append(ctx, id, conversation[i%len(conversation)]); err != nil {
+			t.Fatalf("%q: %v", id, err)
+		}
+	}
+	for i, id := range ids {
+		if got := messages(t, f, id); !slices.Equal(got, conversation[i%len(conversation):i%len(conversation)+1]) {
+			t.Errorf("%q: got %q", id, got)
+		}
+	}
+
+	// every conversation is a file of the directory
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(ids) {
+		t.Errorf("%d files for %d conversations", len(entries), len(ids))
+	}
+	for _, e := range entries {
+		if len(e.Name()) > 255 || filepath.Ext(e.Name()) != ext {
+			t.Errorf("file name %q", e.Name())
+		}
+	}
+}
+
+func TestTornLine(t *testing.T) {
+	ctx := context.Background()
+	f, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Append(ctx, "c", conversation[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	// a crash tore the next line
+	w, err := os.OpenFile(f.path("c"), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString(`{"role": "assistant", "cont`)
+	w.Close()
+
+	if got := messages(t, f, "c"); !slices.Equal(got, conversation[:1]) {
+		t.Errorf("got %q, want the first message only", got)
+	}
+	if err := f.Append(ctx, "c", conversation[2]); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := messages(t, f, "c"), []ragkit.Message{conversation[0], conversation[2]}; !slices.Equal(got, want) {
+		t.Errorf("after appending: got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,43 @@
+// Package memory provides a ragkit.HistoryStore keeping conversations in memory.
+package memory
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+var _ ragkit.HistoryStore = &Memory{}
+
+type Memory struct {
+	mu            sync.RWMutex
+	conversations map[string][]ragkit.Message
+}
+
+func New() *Memory {
+	return &Memory{
+		conversations: make(map[string][]ragkit.Message),
+	}
+}
+
+func (m *Memory) Messages(ctx context.Context, conversationID string) ([]ragkit.Message, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return slices.Clone(m.conversations[conversationID]), nil
+}
+
+func (m *Memory) Append(ctx context.Context, conversationID string, messages ...ragkit.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.conversations[conversationID] = append(m.conversations[conversationID], messages...)
+	return nil
+}
+
+func (m *Memory) Clear(ctx context.Context, conversationID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.conversations, conversationID)
+	return nil
+}
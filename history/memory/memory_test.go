@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	conversation := []ragkit.Message{
+		{Role: ragkit.RoleUser, Content: "hi"},
+		{Role: ragkit.RoleAssistant, Content: "hello"},
+	}
+
+	if got, _ := m.Messages(ctx, "c"); got != nil {
+		t.Errorf("unknown conversation: got %v", got)
+	}
+	m.Append(ctx, "c", conversation[0])
+	m.Append(ctx, "c", conversation[1])
+	m.Append(ctx, "other", conversation[1])
+
+	got, _ := m.Messages(ctx, "c")
+	if !slices.Equal(got, conversation) {
+		t.Errorf("got %q, want %q", got, conversation)
+	}
+	// the returned messages are a copy
+	got[0].Content = "changed"
+	if got, _ := m.Messages(ctx, "c"); got[0].Content != "hi" {
+		t.Error("Messages returned the stored slice")
+	}
+
+	m.Clear(ctx, "c")
+	if got, _ := m.Messages(ctx, "c"); got != nil {
+		t.Errorf("cleared conversation: got %v", got)
+	}
+	if got, _ := m.Messages(ctx, "other"); len(got) != 1 {
+		t.Errorf("other conversation: got %v", got)
+	}
+}
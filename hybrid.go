@@ -0,0 +1,85 @@
+package ragkit
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// ValidateAlpha checks that alpha, the weight of vector search in a hybrid search, is in [0, 1]
+func ValidateAlpha(alpha float32) error {
+	if !(alpha >= 0 && alpha <= 1) { // also rejects NaN
+		return fmt.Errorf("alpha %v out of [0, 1]", alpha)
+	}
+	return nil
+}
+
+// FuseRelativeScores merges the results of a vector search and a keyword search of the same query
+// with relative score fusion, the strategy of Weaviate's hybrid search:
+//
+//   - the scores of each list are min-max normalized to [0, 1], a single result scoring 1
+//   - a document scores alpha*vector + (1-alpha)*keyword, counting 0 for the list missing it
+//
+// It returns the topK best documents ordered by the fused Score, ties broken by ID.
+// The other fields come from the vector result when a document is in both lists.
+// The score of a keyword result can be any relevance measure where higher is better, like BM25.
+func FuseRelativeScores(vector, keyword []RetrievedDoc, alpha float32, topK int) []RetrievedDoc {
+	if topK <= 0 {
+		return nil
+	}
+
+	fused := make(map[string]*RetrievedDoc)
+	var order []string // IDs in first seen order, for the fields
+	add := func(results []RetrievedDoc, weight float32) {
+		norm := normalizeScores(results)
+		for i, r := range results {
+			d, ok := fused[r.ID]
+			if !ok {
+				r.Score = 0
+				d = &r
+				fused[r.ID] = d
+				order = append(order, r.ID)
+			}
+			d.Score += weight * norm[i]
+		}
+	}
+	add(vector, alpha)
+	add(keyword, 1-alpha)
+
+	results := make([]RetrievedDoc, 0, len(order))
+	for _, id := range order {
+		results = append(results, *fused[id])
+	}
+	slices.SortFunc(results, func(a, b RetrievedDoc) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	return results
+}
+
+// normalizeScores returns the scores of results min-max normalized to [0, 1]
+func normalizeScores(results []RetrievedDoc) []float32 {
+	if len(results) == 0 {
+		return nil
+	}
+	lo, hi := results[0].Score, results[0].Score
+	for _, r := range results[1:] {
+		lo = min(lo, r.Score)
+		hi = max(hi, r.Score)
+	}
+
+	norm := make([]float32, len(results))
+	for i, r := range results {
+		if hi == lo {
+			norm[i] = 1
+		} else {
+			norm[i] = (r.Score - lo) / (hi - lo)
+		}
+	}
+	return norm
+}
@@ -0,0 +1,81 @@
+package ragkit
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFuseRelativeScores(t *testing.T) {
+	// normalized, a: 1, b: 0.5, c: 0
+	vector := []RetrievedDoc{
+		{ID: "a", Text: "from vector", Score: 0.9},
+		{ID: "b", Score: 0.5},
+		{ID: "c", Score: 0.1},
+	}
+	// normalized, c: 1, d: 1/3, a: 0
+	keyword := []RetrievedDoc{
+		{ID: "c", Score: 12},
+		{ID: "d", Score: 6},
+		{ID: "a", Text: "from keyword", Score: 3},
+	}
+
+	tests := []struct {
+		name            string
+		vector, keyword []RetrievedDoc
+		alpha           float32
+		topK            int
+		want            []RetrievedDoc
+	}{
+		{"vector only", vector, keyword, 1, 10,
+			scored("a", 1.0, "b", 0.5, "c", 0.0, "d", 0.0)},
+		{"keyword only", vector, keyword, 0, 10,
+			scored("c", 1.0, "d", 1.0/3, "a", 0.0, "b", 0.0)},
+		{"balanced", vector, keyword, 0.5, 10,
+			scored("a", 0.5, "c", 0.5, "b", 0.25, "d", 1.0/6)},
+		{"weighted", vector, keyword, 0.75, 10,
+			scored("a", 0.75, "b", 0.375, "c", 0.25, "d", 0.25/3)},
+		{"topK", vector, keyword, 0.5, 2,
+			scored("a", 0.5, "c", 0.5)},
+		{"zero topK", vector, keyword, 0.5, 0, nil},
+		{"single vector result", scored("x", 0.2), nil, 0.5, 10,
+			scored("x", 0.5)},
+		{"single keyword result", nil, scored("y", 7.0), 0.25, 10,
+			scored("y", 0.75)},
+		{"equal scores", scored("x", 0.4, "y", 0.4), scored("y", 2.0), 0.5, 10,
+			scored("y", 1.0, "x", 0.5)},
+		{"no results", nil, nil, 0.5, 10, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := FuseRelativeScores(tc.vector, tc.keyword, tc.alpha, tc.topK)
+			if docIDs(got) != docIDs(tc.want) {
+				t.Fatalf("got %s, want %s", docIDs(got), docIDs(tc.want))
+			}
+			for i := range got {
+				if math.Abs(float64(got[i].Score-tc.want[i].Score)) > 1e-6 {
+					t.Errorf("%s scored %v, want %v", got[i].ID, got[i].Score, tc.want[i].Score)
+				}
+			}
+		})
+	}
+
+	// a document in both lists keeps the fields of the vector result
+	for _, doc := range FuseRelativeScores(vector, keyword, 0.5, 10) {
+		if doc.ID == "a" && doc.Text != "from vector" {
+			t.Errorf("a = %+v, want the fields of the vector result", doc)
+		}
+	}
+}
+
+func TestValidateAlpha(t *testing.T) {
+	for _, alpha := range []float32{0, 0.3, 1} {
+		if err := ValidateAlpha(alpha); err != nil {
+			t.Errorf("alpha %v: %v", alpha, err)
+		}
+	}
+	for _, alpha := range []float32{-0.1, 1.1, float32(math.NaN())} {
+		if ValidateAlpha(alpha) == nil {
+			t.Errorf("alpha %v accepted", alpha)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package ragkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var _ Retriever = &HyDERetriever{}
+
+// DefaultHyDEPrompt asks for a passage answering a question.
+// It is formatted with the question.
+const DefaultHyDEPrompt = `Write a short passage answering the question below, as it could appear in a document.
+Keep the language of the question. Reply with the passage only.
+
+Question: %s`
+
+// HyDERetriever is a Retriever using Hypothetical Document Embeddings (HyDE):
+// a Generator writes a passage answering a text query, which is retrieved instead of the query,
+// as it is worded like the documents holding the answer. Its facts may be wrong; only its wording matters.
+//
+// With an embedder (see WithHyDEEmbedder), the passages and the query are embedded and their mean vector is retrieved,
+// as in the original paper. It must be the embedder of the wrapped Retriever.
+// Otherwise the passages are retrieved as text, joined.
+type HyDERetriever struct {
+	retriever Retriever
+	generator Generator
+	embedder  Embedder
+	prompt    string
+	documents int
+	query     bool
+}
+
+// HyDEOption configures a HyDERetriever
+type HyDEOption func(*HyDERetriever)
+
+// WithHyDEPrompt sets the prompt, a format string taking the question (default: DefaultHyDEPrompt)
+func WithHyDEPrompt(prompt string) HyDEOption {
+	return func(h *HyDERetriever) {
+		h.prompt = prompt
+	}
+}
+
+// WithHyDEEmbedder embeds the passages with embedder and retrieves their mean vector
+func WithHyDEEmbedder(embedder Embedder) HyDEOption {
+	return func(h *HyDERetriever) {
+		h.embedder = embedder
+	}
+}
+
+// WithHyDEDocuments sets the number of passages generated concurrently for a query (default: 1)
+func WithHyDEDocuments(n int) HyDEOption {
+	return func(h *HyDERetriever) {
+		h.documents = n
+	}
+}
+
+// WithHyDEQuery retrieves the query along with the passages, keeping its terms in the search
+func WithHyDEQuery() HyDEOption {
+	return func(h *HyDERetriever) {
+		h.query = true
+	}
+}
+
+// NewHyDERetriever creates a HyDERetriever writing passages with generator and retrieving them with retriever
+func NewHyDERetriever(retriever Retriever, generator Generator, opts ...HyDEOption) *HyDERetriever {
+	h := &HyDERetriever{
+		retriever: retriever,
+		generator: generator,
+		prompt:    DefaultHyDEPrompt,
+		documents: 1,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func (h *HyDERetriever) String() string {
+	return fmt.Sprintf("HyDERetriever(%s, documents: %d)", h.generator, h.documents)
+}
+
+// Retrieve returns the topK documents of the wrapped retriever for the query vector, which can't be answered
+func (h *HyDERetriever) Retrieve(ctx context.Context, query []float32, topK int, metadataFieldNames ...string) ([]RetrievedDoc, error) {
+	return h.retriever.Retrieve(ctx, query, topK, metadataFieldNames...)
+}
+
+// RetrieveText returns the topK documents retrieved for the passages generated for text
+func (h *HyDERetriever) RetrieveText(ctx context.Context, text string, topK int, metadataFieldNames ...string) ([]RetrievedDoc, error) {
+	if topK <= 0 {
+		return nil, ctx.Err()
+	}
+	texts, err := h.Hypothesize(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	if h.query {
+		texts = append(texts, text)
+	}
+
+	if h.embedder == nil {
+		return h.retriever.RetrieveText(ctx, strings.Join(texts, "\n\n"), topK, metadataFieldNames...)
+	}
+	vectors, err := h.embedder.EmbedTexts(ctx, texts...)
+	if err != nil {
+		return nil, fmt.Errorf("embed: %w", err)
+	}
+	mean, err := meanVector(vectors)
+	if err != nil {
+		return nil, err
+	}
+	return h.retriever.Retrieve(ctx, mean, topK, metadataFieldNames...)
+}
+
+// Hypothesize returns the passages generated for text
+func (h *HyDERetriever) Hypothesize(ctx context.Context, text string) ([]string, error) {
+	if h.documents <= 0 {
+		return nil, fmt.Errorf("invalid document count %d", h.documents)
+	}
+	prompt := Message{Role: RoleUser, Content: fmt.Sprintf(h.prompt, text)}
+
+	passages := make([]string, h.documents)
+	errs := make([]error, h.documents)
+	var wg sync.WaitGroup
+	for i := range passages {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			passages[i], errs[i] = h.generator.Generate(ctx, prompt)
+			passages[i] = strings.TrimSpace(passages[i])
+			if errs[i] == nil && passages[i] == "" {
+				errs[i] = errors.New("empty passage")
+			}
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("hypothesize: %w", errs[i])
+			}
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return passages, nil
+}
+
+// meanVector returns the mean of vectors, which must have the same dimension
+func meanVector(vectors [][]float32) ([]float32, error) {
+	if len(vectors) == 0 {
+		return nil, errors.New("no vector")
+	}
+	mean := make([]float32, len(vectors[0]))
+	for _, v := range vectors {
+		if len(v) != len(mean) {
+			return nil, fmt.Errorf("dimension mismatch: %d != %d", len(v), len(mean))
+		}
+		for i, x := range v {
+			mean[i] += x
+		}
+	}
+	for i := range mean {
+		mean[i] /= float32(len(vectors))
+	}
+	return mean, nil
+}
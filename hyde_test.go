@@ -0,0 +1,144 @@
+package ragkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// stubEmbedder embeds texts by looking them up in vectors
+type stubEmbedder struct {
+	vectors map[string][]float32
+	texts   []string
+}
+
+func (e *stubEmbedder) EmbedText(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := e.EmbedTexts(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+func (e *stubEmbedder) EmbedTexts(ctx context.Context, texts ...string) ([][]float32, error) {
+	e.texts = append(e.texts, texts...)
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		v, ok := e.vectors[text]
+		if !ok {
+			return nil, fmt.Errorf("no vector for %q", text)
+		}
+		vectors[i] = v
+	}
+	return vectors, nil
+}
+
+func (e *stubEmbedder) String() string { return "stub" }
+
+// numbered returns a generator replying "passage 1", "passage 2"...
+func numbered() *stubGenerator {
+	var n atomic.Int32
+	return &stubGenerator{reply: func(string) (string, error) {
+		return fmt.Sprintf("  passage %d\n", n.Add(1)), nil
+	}}
+}
+
+func TestHypothesize(t *testing.T) {
+	g := numbered()
+	h := NewHyDERetriever(&stubRetriever{}, g, WithHyDEDocuments(3), WithHyDEPrompt("answer: %s"))
+	passages, err := h.Hypothesize(context.Background(), "Who created Go?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(passages)
+	if !slices.Equal(passages, []string{"passage 1", "passage 2", "passage 3"}) {
+		t.Errorf("got %q", passages)
+	}
+	if !slices.Equal(g.prompts, []string{"answer: Who created Go?", "answer: Who created Go?", "answer: Who created Go?"}) {
+		t.Errorf("prompts = %q", g.prompts)
+	}
+
+	errDown := errors.New("down")
+	tests := map[string]*HyDERetriever{
+		"no document": NewHyDERetriever(&stubRetriever{}, g, WithHyDEDocuments(0)),
+		"empty":       NewHyDERetriever(&stubRetriever{}, replying(" \n")),
+		"generating":  NewHyDERetriever(&stubRetriever{}, &stubGenerator{reply: func(string) (string, error) { return "", errDown }}),
+	}
+	for name, h := range tests {
+		if _, err := h.Hypothesize(context.Background(), "q"); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestHyDERetrieveText(t *testing.T) {
+	ctx := context.Background()
+	stub := &stubRetriever{results: map[string][]RetrievedDoc{
+		"passage 1":             makeDocs("a", "b"),
+		"passage 1\n\nWho? Go!": makeDocs("c"),
+	}}
+
+	got, err := NewHyDERetriever(stub, numbered()).RetrieveText(ctx, "Who? Go!", 2)
+	if err != nil || docIDs(got) != "a,b" {
+		t.Errorf("got %v, %v, want a,b", got, err)
+	}
+	got, err = NewHyDERetriever(stub, numbered(), WithHyDEQuery()).RetrieveText(ctx, "Who? Go!", 2)
+	if err != nil || docIDs(got) != "c" {
+		t.Errorf("with query: got %v, %v, want c", got, err)
+	}
+	if got, err := NewHyDERetriever(stub, numbered()).RetrieveText(ctx, "q", 0); got != nil || err != nil {
+		t.Errorf("topK 0: got %v, %v", got, err)
+	}
+}
+
+func TestHyDEEmbedder(t *testing.T) {
+	ctx := context.Background()
+	embedder := &stubEmbedder{vectors: map[string][]float32{
+		"passage 1": {1, 0, 0},
+		"passage 2": {0, 1, 0},
+		"question":  {0, 0, 1},
+	}}
+	stub := &stubRetriever{results: map[string][]RetrievedDoc{
+		fmt.Sprint([]float32{0.5, 0.5, 0}):               makeDocs("passages"),
+		fmt.Sprint([]float32{1.0 / 3, 1.0 / 3, 1.0 / 3}): makeDocs("with question"),
+	}}
+
+	h := NewHyDERetriever(stub, numbered(), WithHyDEEmbedder(embedder), WithHyDEDocuments(2))
+	got, err := h.RetrieveText(ctx, "question", 1)
+	if err != nil || docIDs(got) != "passages" {
+		t.Errorf("got %v, %v, want the documents of the mean of the passages", got, err)
+	}
+
+	embedder.texts = nil
+	h = NewHyDERetriever(stub, numbered(), WithHyDEEmbedder(embedder), WithHyDEDocuments(2), WithHyDEQuery())
+	got, err = h.RetrieveText(ctx, "question", 1)
+	if err != nil || docIDs(got) != "with question" {
+		t.Errorf("with query: got %v, %v, want the documents of the mean with the question", got, err)
+	}
+	if slices.Sort(embedder.texts); !slices.Equal(embedder.texts, []string{"passage 1", "passage 2", "question"}) {
+		t.Errorf("embedded %q", embedder.texts)
+	}
+
+	// embedding failures are reported
+	_, err = NewHyDERetriever(stub, replying("unknown"), WithHyDEEmbedder(embedder)).RetrieveText(ctx, "question", 1)
+	if err == nil || !strings.HasPrefix(err.Error(), "embed: ") {
+		t.Errorf("got error %v, want an embed error", err)
+	}
+}
+
+func TestMeanVector(t *testing.T) {
+	mean, err := meanVector([][]float32{{1, 2}, {3, 4}})
+	if err != nil || !slices.Equal(mean, []float32{2, 3}) {
+		t.Errorf("got %v, %v, want [2 3]", mean, err)
+	}
+	if _, err := meanVector(nil); err == nil {
+		t.Error("no vector: no error")
+	}
+	if _, err := meanVector([][]float32{{1, 2}, {3}}); err == nil {
+		t.Error("dimension mismatch: no error")
+	}
+}
@@ -0,0 +1,476 @@
+// Package hnsw implements a Hierarchical Navigable Small World graph
+// for approximate nearest neighbour search over vectors identified by string IDs.
+//
+// Local ragkit.VectorStore implementations plug it in to avoid scanning every vector
+// on each query. Deleted vectors are tombstoned: they still route searches
+// but are never returned, until Compact rebuilds the graph without them.
+package hnsw
+
+import (
+	"cmp"
+	"container/heap"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sync"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+// Config is the configuration of an Index. Zero fields take their defaults.
+type Config struct {
+	M              int                   // Max neighbours per node on upper layers, twice this on the bottom layer (default: 16)
+	EfConstruction int                   // Candidate list size while inserting (default: 200)
+	EfSearch       int                   // Candidate list size while searching, at least k (default: 64)
+	Metric         ragkit.DistanceMetric // Distance metric (default: cosine)
+	Seed           uint64                // Seed for level generation, for reproducible graphs
+}
+
+func (c Config) withDefaults() Config {
+	c.M = cmp.Or(c.M, 16)
+	c.EfConstruction = cmp.Or(c.EfConstruction, 200)
+	c.EfSearch = cmp.Or(c.EfSearch, 64)
+	c.Metric = cmp.Or(c.Metric, ragkit.DistanceCosine)
+	return c
+}
+
+// Result is a vector found by Search
+type Result struct {
+	ID       string
+	Distance float32
+}
+
+type node struct {
+	id      string
+	vec     []float32
+	friends [][]uint32 // neighbours per layer, up to the node's level
+	deleted bool
+}
+
+// Index is a HNSW graph. It is safe for concurrent use.
+type Index struct {
+	cfg       Config
+	dist      func(a, b []float32) float32
+	levelMult float64
+	rng       *rand.Rand
+
+	nodes    []*node
+	ids      map[string]uint32 // live nodes by ID
+	entry    uint32
+	maxLevel int
+
+	mu sync.RWMutex
+}
+
+// New creates an empty index
+func New(cfg Config) *Index {
+	cfg = cfg.withDefaults()
+	return &Index{
+		cfg:       cfg,
+		dist:      distanceFunc(cfg.Metric),
+		levelMult: 1 / math.Log(float64(max(cfg.M, 2))),
+		rng:       rand.New(rand.NewPCG(cfg.Seed, cfg.Seed^0x9e3779b97f4a7c15)),
+		ids:       make(map[string]uint32),
+		maxLevel:  -1,
+	}
+}
+
+// Config returns the configuration of the index with defaults filled in
+func (h *Index) Config() Config {
+	return h.cfg
+}
+
+// Len returns the number of live vectors
+func (h *Index) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.ids)
+}
+
+// Tombstones returns the number of deleted vectors still kept in the graph
+func (h *Index) Tombstones() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.nodes) - len(h.ids)
+}
+
+// Insert adds vec under id. An existing vector with the same id is replaced.
+func (h *Index) Insert(id string, vec []float32) error {
+	if len(vec) == 0 {
+		return fmt.Errorf("empty vector")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.nodes) > 0 && len(vec) != len(h.nodes[0].vec) {
+		return fmt.Errorf("expected %d dimensions, not %d", len(h.nodes[0].vec), len(vec))
+	}
+	if len(h.nodes) >= math.MaxUint32 {
+		return fmt.Errorf("index is full")
+	}
+	if old, ok := h.ids[id]; ok {
+		h.nodes[old].deleted = true
+	}
+	h.insert(id, vec)
+	return nil
+}
+
+// insert adds vec under id to the graph. The caller must hold the write lock.
+func (h *Index) insert(id string, vec []float32) {
+	level := int(-math.Log(1-h.rng.Float64()) * h.levelMult)
+	n := &node{
+		id:      id,
+		vec:     h.prepare(vec),
+		friends: make([][]uint32, level+1),
+	}
+	nid := uint32(len(h.nodes))
+	h.nodes = append(h.nodes, n)
+	h.ids[id] = nid
+
+	if h.maxLevel < 0 {
+		h.entry, h.maxLevel = nid, level
+		return
+	}
+
+	ep := []candidate{{id: h.entry, dist: h.distance(n.vec, h.entry)}}
+	for l := h.maxLevel; l > level; l-- {
+		ep = h.searchLayer(n.vec, ep, 1, l)
+	}
+	for l := min(level, h.maxLevel); l >= 0; l-- {
+		found := h.searchLayer(n.vec, ep, h.cfg.EfConstruction, l)
+		n.friends[l] = h.selectNeighbours(found, h.cfg.M)
+
+		for _, f := range n.friends[l] {
+			h.link(f, nid, l)
+		}
+		ep = found
+	}
+
+	if level > h.maxLevel {
+		h.entry, h.maxLevel = nid, level
+	}
+}
+
+// Delete tombstones the vector of id. It reports whether id was present.
+func (h *Index) Delete(id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	nid, ok := h.ids[id]
+	if !ok {
+		return false
+	}
+	h.nodes[nid].deleted = true
+	delete(h.ids, id)
+	return true
+}
+
+// Compact rebuilds the graph from the live vectors, dropping the tombstones.
+// Searches and inserts wait until it is done.
+func (h *Index) Compact() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	nodes := h.nodes
+	h.nodes = make([]*node, 0, len(h.ids))
+	h.ids = make(map[string]uint32, len(h.ids))
+	h.entry, h.maxLevel = 0, -1
+	for _, n := range nodes {
+		if !n.deleted {
+			h.insert(n.id, n.vec)
+		}
+	}
+}
+
+// Search returns up to k live vectors nearest to query, nearest first.
+// ef overrides Config.EfSearch when positive.
+// Tombstones crowding the candidate list widen it until k live vectors are found.
+// If accept is not nil, only vectors whose ID it accepts are returned;
+// rejected vectors still route the search, so very selective filters may return fewer than k results.
+func (h *Index) Search(query []float32, k, ef int, accept func(id string) bool) []Result {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if k <= 0 || h.maxLevel < 0 || len(query) != len(h.nodes[0].vec) {
+		return nil
+	}
+	ef = max(cmp.Or(ef, h.cfg.EfSearch), k)
+	query = h.prepare(query)
+
+	ep := []candidate{{id: h.entry, dist: h.distance(query, h.entry)}}
+	for l := h.maxLevel; l > 0; l-- {
+		ep = h.searchLayer(query, ep, 1, l)
+	}
+
+	for {
+		found := h.searchLayer(query, ep, ef, 0)
+
+		results := make([]Result, 0, k)
+		for _, c := range found {
+			n := h.nodes[c.id]
+			if n.deleted || (accept != nil && !accept(n.id)) {
+				continue
+			}
+			results = append(results, Result{ID: n.id, Distance: c.dist})
+			if len(results) == k {
+				break
+			}
+		}
+		if len(results) == k || len(results) == len(h.ids) || accept != nil || len(found) < ef || ef >= len(h.nodes) {
+			return results
+		}
+		ef = min(2*ef, len(h.nodes))
+	}
+}
+
+// snapshot is the serialized form of an Index
+type snapshot struct {
+	Config   Config
+	IDs      []string
+	Vectors  [][]float32
+	Friends  [][][]uint32
+	Deleted  []bool
+	Entry    uint32
+	MaxLevel int
+}
+
+// Save serializes the index to w
+func (h *Index) Save(w io.Writer) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	s := snapshot{
+		Config:   h.cfg,
+		IDs:      make([]string, len(h.nodes)),
+		Vectors:  make([][]float32, len(h.nodes)),
+		Friends:  make([][][]uint32, len(h.nodes)),
+		Deleted:  make([]bool, len(h.nodes)),
+		Entry:    h.entry,
+		MaxLevel: h.maxLevel,
+	}
+	for i, n := range h.nodes {
+		s.IDs[i], s.Vectors[i], s.Friends[i], s.Deleted[i] = n.id, n.vec, n.friends, n.deleted
+	}
+	return gob.NewEncoder(w).Encode(&s)
+}
+
+// Load deserializes an index saved by Save
+func Load(r io.Reader) (*Index, error) {
+	var s snapshot
+	if err := gob.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	if len(s.Vectors) != len(s.IDs) || len(s.Friends) != len(s.IDs) || len(s.Deleted) != len(s.IDs) {
+		return nil, fmt.Errorf("corrupted index: inconsistent node count")
+	}
+
+	h := New(s.Config)
+	h.entry, h.maxLevel = s.Entry, s.MaxLevel
+	h.nodes = make([]*node, len(s.IDs))
+	for i := range s.IDs {
+		for _, friends := range s.Friends[i] {
+			for _, f := range friends {
+				if int(f) >= len(s.IDs) {
+					return nil, fmt.Errorf("corrupted index: neighbour %d out of range", f)
+				}
+			}
+		}
+		h.nodes[i] = &node{id: s.IDs[i], vec: s.Vectors[i], friends: s.Friends[i], deleted: s.Deleted[i]}
+		if !s.Deleted[i] {
+			h.ids[s.IDs[i]] = uint32(i)
+		}
+	}
+	if len(h.nodes) > 0 && int(h.entry) >= len(h.nodes) {
+		return nil, fmt.Errorf("corrupted index: entry point out of range")
+	}
+	if len(h.nodes) == 0 {
+		h.maxLevel = -1
+	}
+	return h, nil
+}
+
+func (h *Index) distance(q []float32, id uint32) float32 {
+	return h.dist(q, h.nodes[id].vec)
+}
+
+// prepare copies vec, normalizing it for cosine distance so that it reduces to a dot product
+func (h *Index) prepare(vec []float32) []float32 {
+	vec = slices.Clone(vec)
+	if h.cfg.Metric != ragkit.DistanceCosine {
+		return vec
+	}
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		return vec
+	}
+	norm = math.Sqrt(norm)
+	for i := range vec {
+		vec[i] = float32(float64(vec[i]) / norm)
+	}
+	return vec
+}
+
+// distanceFunc returns the distance function of metric over prepared vectors
+func distanceFunc(metric ragkit.DistanceMetric) func(a, b []float32) float32 {
+	switch metric {
+	case ragkit.DistanceCosine:
+		return func(a, b []float32) float32 { return 1 - dot(a, b) }
+	case ragkit.DistanceDot:
+		return func(a, b []float32) float32 { return -dot(a, b) }
+	default:
+		return func(a, b []float32) float32 { return ragkit.Distance(metric, a, b) }
+	}
+}
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// link adds to as a neighbour of from on layer l, pruning from's neighbours if it has too many
+func (h *Index) link(from, to uint32, l int) {
+	n := h.nodes[from]
+	n.friends[l] = append(n.friends[l], to)
+
+	maxFriends := h.cfg.M
+	if l == 0 {
+		maxFriends = 2 * h.cfg.M
+	}
+	if len(n.friends[l]) <= maxFriends {
+		return
+	}
+
+	cands := make([]candidate, len(n.friends[l]))
+	for i, f := range n.friends[l] {
+		cands[i] = candidate{id: f, dist: h.distance(n.vec, f)}
+	}
+	slices.SortFunc(cands, compareCandidates)
+	n.friends[l] = h.selectNeighbours(cands, maxFriends)
+}
+
+// selectNeighbours picks up to m of cands (sorted nearest first) with the HNSW heuristic:
+// a candidate is kept only if it is closer to the base than to any kept neighbour,
+// which spreads links in different directions. Pruned candidates fill the remaining slots.
+func (h *Index) selectNeighbours(cands []candidate, m int) []uint32 {
+	selected := make([]uint32, 0, m)
+	var pruned []uint32
+	for _, c := range cands {
+		if len(selected) == m {
+			break
+		}
+		keep := true
+		for _, s := range selected {
+			if h.dist(h.nodes[c.id].vec, h.nodes[s].vec) < c.dist {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			selected = append(selected, c.id)
+		} else {
+			pruned = append(pruned, c.id)
+		}
+	}
+	for _, p := range pruned {
+		if len(selected) == m {
+			break
+		}
+		selected = append(selected, p)
+	}
+	return selected
+}
+
+// searchLayer returns up to ef nodes nearest to q on layer l, nearest first
+func (h *Index) searchLayer(q []float32, entries []candidate, ef, l int) []candidate {
+	visited := make([]uint64, (len(h.nodes)+63)/64)
+	cands := &minHeap{}
+	found := &maxHeap{}
+	for _, e := range entries {
+		visited[e.id/64] |= 1 << (e.id % 64)
+		heap.Push(cands, e)
+		heap.Push(found, e)
+		if found.Len() > ef {
+			heap.Pop(found)
+		}
+	}
+
+	for cands.Len() > 0 {
+		c := heap.Pop(cands).(candidate)
+		if found.Len() >= ef && c.dist > (*found)[0].dist {
+			break
+		}
+		friends := h.nodes[c.id].friends
+		if l >= len(friends) {
+			continue
+		}
+		for _, f := range friends[l] {
+			if visited[f/64]&(1<<(f%64)) != 0 {
+				continue
+			}
+			visited[f/64] |= 1 << (f % 64)
+
+			d := h.distance(q, f)
+			if found.Len() < ef || d < (*found)[0].dist {
+				heap.Push(cands, candidate{id: f, dist: d})
+				heap.Push(found, candidate{id: f, dist: d})
+				if found.Len() > ef {
+					heap.Pop(found)
+				}
+			}
+		}
+	}
+
+	ret := []candidate(*found)
+	slices.SortFunc(ret, compareCandidates)
+	return ret
+}
+
+type candidate struct {
+	id   uint32
+	dist float32
+}
+
+func compareCandidates(a, b candidate) int {
+	if c := cmp.Compare(a.dist, b.dist); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.id, b.id)
+}
+
+type minHeap []candidate
+
+func (h minHeap) Len() int           { return len(h) }
+func (h minHeap) Less(i, j int) bool { return h[i].dist < h[j].dist }
+func (h minHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *minHeap) Push(x any)        { *h = append(*h, x.(candidate)) }
+func (h *minHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+type maxHeap []candidate
+
+func (h maxHeap) Len() int           { return len(h) }
+func (h maxHeap) Less(i, j int) bool { return h[i].dist > h[j].dist }
+func (h maxHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x any)        { *h = append(*h, x.(candidate)) }
+func (h *maxHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,235 @@
+package hnsw
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+func randomVectors(rng *rand.Rand, n, dim int) [][]float32 {
+	vecs := make([][]float32, n)
+	for i := range vecs {
+		vecs[i] = make([]float32, dim)
+		for j := range vecs[i] {
+			vecs[i][j] = rng.Float32()*2 - 1
+		}
+	}
+	return vecs
+}
+
+// exact returns the IDs of the k vectors nearest to query by brute force
+func exact(metric ragkit.DistanceMetric, vecs [][]float32, query []float32, k int) []string {
+	type scored struct {
+		id   int
+		dist float32
+	}
+	all := make([]scored, len(vecs))
+	for i, v := range vecs {
+		all[i] = scored{i, ragkit.Distance(metric, query, v)}
+	}
+	slices.SortFunc(all, func(a, b scored) int {
+		switch {
+		case a.dist < b.dist:
+			return -1
+		case a.dist > b.dist:
+			return 1
+		}
+		return a.id - b.id
+	})
+	ids := make([]string, k)
+	for i := range ids {
+		ids[i] = fmt.Sprint(all[i].id)
+	}
+	return ids
+}
+
+func build(t testing.TB, cfg Config, vecs [][]float32) *Index {
+	t.Helper()
+	h := New(cfg)
+	for i, v := range vecs {
+		if err := h.Insert(fmt.Sprint(i), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return h
+}
+
+// recall returns the fraction of the exact k nearest neighbours of queries found by h
+func recall(h *Index, metric ragkit.DistanceMetric, vecs, queries [][]float32, k int) float64 {
+	hits := 0
+	for _, q := range queries {
+		want := exact(metric, vecs, q, k)
+		for _, r := range h.Search(q, k, 0, nil) {
+			if slices.Contains(want, r.ID) {
+				hits++
+			}
+		}
+	}
+	return float64(hits) / float64(k*len(queries))
+}
+
+func TestRecall(t *testing.T) {
+	for _, metric := range []ragkit.DistanceMetric{ragkit.DistanceCosine, ragkit.DistanceL2, ragkit.DistanceDot} {
+		t.Run(string(metric), func(t *testing.T) {
+			rng := rand.New(rand.NewPCG(1, 2))
+			vecs := randomVectors(rng, 2000, 32)
+			queries := randomVectors(rng, 50, 32)
+			h := build(t, Config{Metric: metric, Seed: 1}, vecs)
+
+			if r := recall(h, metric, vecs, queries, 10); r < 0.9 {
+				t.Errorf("recall@10 = %.3f, want >= 0.9", r)
+			}
+		})
+	}
+}
+
+func TestSearchSmall(t *testing.T) {
+	h := New(Config{})
+	if got := h.Search([]float32{1, 0}, 3, 0, nil); got != nil {
+		t.Errorf("empty index: got %v", got)
+	}
+
+	h.Insert("a", []float32{1, 0})
+	h.Insert("b", []float32{0, 1})
+	got := h.Search([]float32{1, 0.1}, 5, 0, nil)
+	if len(got) != 2 || got[0].ID != "a" || got[1].ID != "b" {
+		t.Errorf("got %v, want a then b", got)
+	}
+	if got := h.Search([]float32{1, 0, 0}, 1, 0, nil); got != nil {
+		t.Errorf("wrong dimension: got %v", got)
+	}
+	if err := h.Insert("c", []float32{1, 0, 0}); err == nil {
+		t.Error("inserting a wrong dimension: no error")
+	}
+}
+
+func TestSearchAccept(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	vecs := randomVectors(rng, 500, 16)
+	h := build(t, Config{Seed: 1}, vecs)
+
+	even := func(id string) bool { return id[len(id)-1]%2 == 0 }
+	got := h.Search(vecs[0], 10, 0, even)
+	if len(got) != 10 {
+		t.Fatalf("got %d results, want 10", len(got))
+	}
+	for _, r := range got {
+		if !even(r.ID) {
+			t.Errorf("%s isn't accepted", r.ID)
+		}
+	}
+}
+
+func TestDeleteAndReplace(t *testing.T) {
+	h := New(Config{Seed: 1})
+	h.Insert("a", []float32{1, 0})
+	h.Insert("b", []float32{0, 1})
+
+	if !h.Delete("a") {
+		t.Error("Delete(a) = false, want true")
+	}
+	if h.Delete("a") {
+		t.Error("second Delete(a) = true, want false")
+	}
+	if got := h.Search([]float32{1, 0}, 2, 0, nil); len(got) != 1 || got[0].ID != "b" {
+		t.Errorf("after delete: got %v, want b only", got)
+	}
+
+	h.Insert("b", []float32{1, 0})
+	if h.Len() != 1 || h.Tombstones() != 2 {
+		t.Errorf("Len, Tombstones = %d, %d, want 1, 2", h.Len(), h.Tombstones())
+	}
+	got := h.Search([]float32{1, 0}, 2, 0, nil)
+	if len(got) != 1 || got[0].ID != "b" || got[0].Distance > 1e-6 {
+		t.Errorf("after replace: got %v, want b at distance 0", got)
+	}
+}
+
+func TestTombstonesDontShortenResults(t *testing.T) {
+	rng := rand.New(rand.NewPCG(5, 6))
+	vecs := randomVectors(rng, 500, 16)
+	h := build(t, Config{Seed: 1}, vecs)
+	for range 3 {
+		for i, v := range vecs {
+			h.Insert(fmt.Sprint(i), v)
+		}
+	}
+	if h.Tombstones() != 1500 {
+		t.Fatalf("Tombstones = %d, want 1500", h.Tombstones())
+	}
+
+	for _, q := range randomVectors(rng, 10, 16) {
+		if got := h.Search(q, 50, 0, nil); len(got) != 50 {
+			t.Fatalf("got %d results, want 50", len(got))
+		}
+	}
+	if got := h.Search(vecs[0], 1000, 0, nil); len(got) != 500 {
+		t.Errorf("k above Len: got %d results, want 500", len(got))
+	}
+
+	h.Compact()
+	if h.Len() != 500 || h.Tombstones() != 0 {
+		t.Errorf("after Compact: Len, Tombstones = %d, %d, want 500, 0", h.Len(), h.Tombstones())
+	}
+	if r := recall(h, ragkit.DistanceCosine, vecs, randomVectors(rng, 20, 16), 10); r < 0.9 {
+		t.Errorf("recall@10 after Compact = %.3f, want >= 0.9", r)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	rng := rand.New(rand.NewPCG(7, 8))
+	vecs := randomVectors(rng, 300, 8)
+	h := build(t, Config{M: 8, Metric: ragkit.DistanceL2, Seed: 1}, vecs)
+	h.Delete("3")
+
+	var buf bytes.Buffer
+	if err := h.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Config() != h.Config() {
+		t.Errorf("Config = %+v, want %+v", loaded.Config(), h.Config())
+	}
+	if loaded.Len() != h.Len() || loaded.Tombstones() != h.Tombstones() {
+		t.Errorf("Len, Tombstones = %d, %d, want %d, %d", loaded.Len(), loaded.Tombstones(), h.Len(), h.Tombstones())
+	}
+	for _, q := range randomVectors(rng, 10, 8) {
+		want := h.Search(q, 5, 0, nil)
+		if got := loaded.Search(q, 5, 0, nil); !slices.Equal(got, want) {
+			t.Errorf("Search = %v, want %v", got, want)
+		}
+	}
+
+	if _, err := Load(bytes.NewReader([]byte("garbage"))); err == nil {
+		t.Error("loading garbage: no error")
+	}
+}
+
+func BenchmarkSearch(b *testing.B) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	vecs := randomVectors(rng, 10000, 64)
+	queries := randomVectors(rng, 100, 64)
+
+	b.Run("hnsw", func(b *testing.B) {
+		h := build(b, Config{Seed: 1}, vecs)
+		b.ResetTimer()
+		for i := range b.N {
+			h.Search(queries[i%len(queries)], 10, 0, nil)
+		}
+		b.StopTimer()
+		b.ReportMetric(recall(h, ragkit.DistanceCosine, vecs, queries, 10), "recall@10")
+	})
+	b.Run("exact", func(b *testing.B) {
+		for i := range b.N {
+			exact(ragkit.DistanceCosine, vecs, queries[i%len(queries)], 10)
+		}
+	})
+}
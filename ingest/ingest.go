@@ -0,0 +1,301 @@
+// Package ingest keeps a vector store in sync with a set of source files.
+//
+// A Pipeline records the content hash of every ingested file and the IDs of its chunks in a
+// Manifest, so that each run only loads, splits and embeds the files that changed since the
+// last one, and deletes the chunks of the files that changed or disappeared.
+package ingest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	ragkit "github.com/suapapa/go_ragkit"
+	"github.com/suapapa/go_ragkit/splitter"
+)
+
+// Source is a type that lists files and loads their documents, like *loader.Dir
+type Source interface {
+	// Files: Return the paths of the files to ingest
+	Files(ctx context.Context) ([]string, error)
+
+	// LoadFile: Load the documents of the file at path
+	LoadFile(ctx context.Context, path string) ([]ragkit.Document, error)
+}
+
+// Pipeline ingests the files of a Source into an Indexer, usually a ragkit.VectorStore
+type Pipeline struct {
+	source   Source
+	indexer  ragkit.Indexer
+	manifest string
+	splitter splitter.Splitter
+	version  string
+}
+
+// Option configures a Pipeline
+type Option func(*Pipeline)
+
+// WithSplitter splits the loaded documents into chunks with s (default: documents are indexed whole)
+func WithSplitter(s splitter.Splitter) Option {
+	return func(p *Pipeline) {
+		p.splitter = s
+	}
+}
+
+// WithVersion sets the version of the pipeline recorded in the manifest for every source.
+// Sources ingested by another version are ingested again, replacing their chunks even if
+// unchanged, so change it along with the loaders, the splitter or the embedder.
+func WithVersion(version string) Option {
+	return func(p *Pipeline) {
+		p.version = version
+	}
+}
+
+// New creates a Pipeline ingesting the files of source into indexer, tracked by the manifest file at manifestPath
+func New(source Source, indexer ragkit.Indexer, manifestPath string, opts ...Option) *Pipeline {
+	p := &Pipeline{
+		source:   source,
+		indexer:  indexer,
+		manifest: manifestPath,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Report is a type that reports what a run of a Pipeline did, by source path
+type Report struct {
+	Added     []string // Sources ingested for the first time
+	Updated   []string // Sources whose content changed
+	Unchanged []string // Sources skipped
+	Removed   []string // Sources gone, their chunks deleted
+	Failed    []string // Sources that failed (see Run)
+
+	Indexed int // Number of chunks indexed
+	Deleted int // Number of chunks deleted
+}
+
+// Run ingests the files that changed since the last run and deletes the chunks of the ones gone.
+//
+// A file failing to load or index doesn't stop the run: its new chunks are deleted, its previous
+// ones kept, and it is retried by the next run. The errors of the failed files are joined in the
+// returned error. The manifest is written at the end of the run, even if it failed.
+func (p *Pipeline) Run(ctx context.Context) (*Report, error) {
+	m, err := ReadManifest(p.manifest)
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	files, err := p.source.Files(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{}
+	var errs []error
+	fail := func(path string, err error) {
+		report.Failed = append(report.Failed, path)
+		errs = append(errs, fmt.Errorf("%s: %w", path, err))
+	}
+
+	present := make(map[string]bool, len(files))
+	for _, path := range files {
+		present[path] = true
+		if ctx.Err() != nil {
+			break
+		}
+
+		hash, err := hashFile(path)
+		if err != nil {
+			fail(path, err)
+			continue
+		}
+		entry, known := m.Sources[path]
+		if known && entry.Hash == hash && entry.Version == p.version {
+			report.Unchanged = append(report.Unchanged, path)
+			continue
+		}
+
+		entry, err = p.ingest(ctx, path, hash, entry, report)
+		if known || len(entry.Chunks) > 0 {
+			m.Sources[path] = entry
+		}
+		if err != nil {
+			fail(path, err)
+		} else if known {
+			report.Updated = append(report.Updated, path)
+		} else {
+			report.Added = append(report.Added, path)
+		}
+	}
+
+	if ctx.Err() == nil {
+		var gone []string
+		for path := range m.Sources {
+			if !present[path] {
+				gone = append(gone, path)
+			}
+		}
+		slices.Sort(gone)
+		for _, path := range gone {
+			entry := m.Sources[path]
+			left, err := p.delete(ctx, entry.Chunks, report)
+			if err != nil {
+				entry.Hash, entry.Chunks = "", left
+				m.Sources[path] = entry
+				fail(path, err)
+				continue
+			}
+			delete(m.Sources, path)
+			report.Removed = append(report.Removed, path)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := m.Write(p.manifest); err != nil {
+		errs = append(errs, fmt.Errorf("write manifest: %w", err))
+	}
+	return report, errors.Join(errs...)
+}
+
+// ingest indexes the chunks of the source at path and deletes the ones of entry it no longer has.
+// It returns the entry to record, which keeps the previous chunks if the source failed.
+func (p *Pipeline) ingest(ctx context.Context, path, hash string, entry Entry, report *Report) (Entry, error) {
+	failed := entry
+	failed.Hash = "" // retry next run
+
+	docs, err := p.source.LoadFile(ctx, path)
+	if err != nil {
+		return failed, err
+	}
+	if p.splitter != nil {
+		docs = splitter.SplitDocuments(p.splitter, docs...)
+	}
+
+	previous := make(map[string]bool, len(entry.Chunks))
+	for _, id := range entry.Chunks {
+		previous[id] = true
+	}
+	replace := entry.Version != p.version
+	var chunks []string
+	var toIndex []ragkit.Document
+	seen := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		if doc.ID == "" {
+			doc.ID = ragkit.GenerateID(doc.Text, doc.Metadata)
+		}
+		if seen[doc.ID] {
+			continue
+		}
+		seen[doc.ID] = true
+		chunks = append(chunks, doc.ID)
+		if replace || !previous[doc.ID] {
+			toIndex = append(toIndex, doc)
+		}
+	}
+
+	if len(toIndex) > 0 {
+		added, removed, err := p.index(ctx, toIndex, previous, replace, report)
+		if err != nil && !onlyExisting(err) {
+			// leave the store as it was, recording the chunks that couldn't be deleted
+			left, derr := p.delete(ctx, added, report)
+			failed.Chunks = slices.DeleteFunc(slices.Clone(failed.Chunks), func(id string) bool { return removed[id] })
+			failed.Chunks = append(failed.Chunks, left...)
+			return failed, errors.Join(err, derr)
+		}
+	}
+
+	var stale []string
+	for _, id := range entry.Chunks {
+		if !seen[id] {
+			stale = append(stale, id)
+		}
+	}
+	left, err := p.delete(ctx, stale, report)
+	// chunks failing to be deleted stay recorded, to be deleted with the source
+	return Entry{Hash: hash, Version: p.version, Chunks: append(chunks, left...), Updated: time.Now().UTC()}, err
+}
+
+// index indexes docs, returning the IDs it added and the ones of previous it deleted.
+// With replace, the documents of previous are replaced, so that they are embedded again:
+// upserted if the indexer is a ragkit.Upserter, deleted and indexed again otherwise.
+func (p *Pipeline) index(ctx context.Context, docs []ragkit.Document, previous map[string]bool, replace bool, report *Report) ([]string, map[string]bool, error) {
+	removed := make(map[string]bool)
+	if replace {
+		if u, ok := p.indexer.(ragkit.Upserter); ok {
+			result, err := u.Upsert(ctx, docs...)
+			report.Indexed += len(result.Inserted) + len(result.Updated)
+			return result.Inserted, removed, err
+		}
+
+		var replaced []string
+		for _, doc := range docs {
+			if previous[doc.ID] {
+				replaced = append(replaced, doc.ID)
+			}
+		}
+		left, err := p.delete(ctx, replaced, report)
+		for _, id := range replaced {
+			removed[id] = !slices.Contains(left, id)
+		}
+		if err != nil {
+			return nil, removed, err
+		}
+	}
+
+	ids, err := p.indexer.Index(ctx, docs...)
+	report.Indexed += len(ids)
+	return ids, removed, err
+}
+
+// delete deletes the chunks of ids, returning the ones it failed to delete
+func (p *Pipeline) delete(ctx context.Context, ids []string, report *Report) ([]string, error) {
+	var left []string
+	var errs []error
+	for _, id := range ids {
+		if err := p.indexer.Delete(ctx, id); err != nil {
+			left = append(left, id)
+			errs = append(errs, fmt.Errorf("delete %s: %w", id, err))
+			continue
+		}
+		report.Deleted++
+	}
+	return left, errors.Join(errs...)
+}
+
+// onlyExisting reports whether err is an IndexError of documents already indexed only,
+// left by a run interrupted before writing the manifest
+func onlyExisting(err error) bool {
+	var ie *ragkit.IndexError
+	if !errors.As(err, &ie) {
+		return false
+	}
+	for _, de := range ie.Failed {
+		if !errors.Is(de.Err, ragkit.ErrDocumentExists) {
+			return false
+		}
+	}
+	return true
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,302 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	ragkit "github.com/suapapa/go_ragkit"
+	"github.com/suapapa/go_ragkit/embedder/fake"
+	"github.com/suapapa/go_ragkit/splitter"
+	"github.com/suapapa/go_ragkit/vector_store/memory"
+)
+
+// dirSource ingests the .txt files of a directory as documents of their text, identified by file name
+type dirSource struct {
+	root string
+}
+
+func (s dirSource) Files(ctx context.Context) ([]string, error) {
+	return filepath.Glob(filepath.Join(s.root, "*.txt"))
+}
+
+func (s dirSource) LoadFile(ctx context.Context, path string) ([]ragkit.Document, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return []ragkit.Document{{ID: filepath.Base(path), Text: string(b), Metadata: map[string]any{"source": filepath.Base(path)}}}, nil
+}
+
+// indexOnly hides the Upsert method of an Indexer
+type indexOnly struct {
+	ragkit.Indexer
+}
+
+// errBroken fails the embedding of texts containing "BROKEN"
+var errBroken = errors.New("broken text")
+
+type fixture struct {
+	t        *testing.T
+	root     string
+	manifest string
+	store    *memory.Memory
+}
+
+func newFixture(t *testing.T) *fixture {
+	embedder := fake.New(32, fake.WithErrorFunc(func(call int, texts []string) error {
+		for _, text := range texts {
+			if strings.Contains(text, "BROKEN") {
+				return errBroken
+			}
+		}
+		return nil
+	}))
+	root := t.TempDir()
+	return &fixture{
+		t:        t,
+		root:     root,
+		manifest: filepath.Join(root, "manifest.json"),
+		// one document per batch, so that a broken chunk doesn't fail the others
+		store: memory.New(embedder, memory.WithBatchSize(1)),
+	}
+}
+
+func (f *fixture) write(name, text string) {
+	f.t.Helper()
+	if err := os.WriteFile(filepath.Join(f.root, name), []byte(text), 0o644); err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+func (f *fixture) remove(name string) {
+	f.t.Helper()
+	if err := os.Remove(filepath.Join(f.root, name)); err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+func (f *fixture) pipeline(indexer ragkit.Indexer, opts ...Option) *Pipeline {
+	opts = append([]Option{WithSplitter(splitter.NewRecursive(25, 0))}, opts...)
+	return New(dirSource{f.root}, indexer, f.manifest, opts...)
+}
+
+// entry returns the manifest entry of the file name
+func (f *fixture) entry(name string) (Entry, bool) {
+	f.t.Helper()
+	m, err := ReadManifest(f.manifest)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	e, ok := m.Sources[filepath.Join(f.root, name)]
+	return e, ok
+}
+
+// checkStore checks that the store holds exactly the chunks recorded in the manifest
+func (f *fixture) checkStore() {
+	f.t.Helper()
+	m, err := ReadManifest(f.manifest)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	n := 0
+	for path, e := range m.Sources {
+		for _, id := range e.Chunks {
+			if ok, _ := f.store.Exists(context.Background(), id); !ok {
+				f.t.Errorf("%s: chunk %s missing from the store", path, id)
+			}
+			n++
+		}
+	}
+	if f.store.Len() != n {
+		f.t.Errorf("store has %d chunks, manifest %d", f.store.Len(), n)
+	}
+}
+
+// names returns the base names of paths
+func names(paths []string) []string {
+	var out []string
+	for _, p := range paths {
+		out = append(out, filepath.Base(p))
+	}
+	return out
+}
+
+func checkReport(t *testing.T, r *Report, added, updated, unchanged, removed, failed []string, indexed, deleted int) {
+	t.Helper()
+	for _, c := range []struct {
+		name      string
+		got, want []string
+	}{
+		{"Added", names(r.Added), added},
+		{"Updated", names(r.Updated), updated},
+		{"Unchanged", names(r.Unchanged), unchanged},
+		{"Removed", names(r.Removed), removed},
+		{"Failed", names(r.Failed), failed},
+	} {
+		if !slices.Equal(c.got, c.want) {
+			t.Errorf("%s = %q, want %q", c.name, c.got, c.want)
+		}
+	}
+	if r.Indexed != indexed || r.Deleted != deleted {
+		t.Errorf("Indexed, Deleted = %d, %d, want %d, %d", r.Indexed, r.Deleted, indexed, deleted)
+	}
+}
+
+func TestRunCycle(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	p := f.pipeline(f.store)
+
+	f.write("a.txt", "the first paragraph\n\nthe second paragraph")
+	f.write("b.txt", "beta")
+	r, err := p.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkReport(t, r, []string{"a.txt", "b.txt"}, nil, nil, nil, nil, 3, 0)
+	f.checkStore()
+	first, _ := f.entry("a.txt")
+	if len(first.Chunks) != 2 || first.Hash == "" {
+		t.Fatalf("entry of a.txt = %+v", first)
+	}
+
+	// nothing changed
+	r, err = p.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkReport(t, r, nil, nil, []string{"a.txt", "b.txt"}, nil, nil, 0, 0)
+
+	// a.txt changed, b.txt removed, c.txt added
+	f.write("a.txt", "the first paragraph\n\nan edited paragraph")
+	f.remove("b.txt")
+	f.write("c.txt", "gamma")
+	r, err = p.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// only the edited chunk of a.txt is indexed, its previous version deleted
+	checkReport(t, r, []string{"c.txt"}, []string{"a.txt"}, nil, []string{"b.txt"}, nil, 2, 2)
+	f.checkStore()
+	second, _ := f.entry("a.txt")
+	if second.Hash == first.Hash || second.Chunks[0] != first.Chunks[0] || second.Chunks[1] == first.Chunks[1] {
+		t.Errorf("entry of a.txt = %+v, was %+v", second, first)
+	}
+	if _, ok := f.entry("b.txt"); ok {
+		t.Error("removed b.txt still in the manifest")
+	}
+
+	// a new version replaces every chunk, deleting them first without Upsert
+	for _, tt := range []struct {
+		version string
+		indexer ragkit.Indexer
+		deleted int
+	}{
+		{"v2", f.store, 0},
+		{"v3", indexOnly{f.store}, 3},
+	} {
+		r, err = f.pipeline(tt.indexer, WithVersion(tt.version)).Run(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkReport(t, r, nil, []string{"a.txt", "c.txt"}, nil, nil, nil, 3, tt.deleted)
+		f.checkStore()
+		if e, _ := f.entry("c.txt"); e.Version != tt.version {
+			t.Errorf("version = %q, want %q", e.Version, tt.version)
+		}
+	}
+}
+
+func TestRunRollback(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	p := f.pipeline(f.store)
+
+	f.write("a.txt", "the first paragraph\n\nthe second paragraph")
+	if _, err := p.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := f.entry("a.txt")
+
+	// a.txt gets a broken chunk along with a good new one; d.txt is broken
+	f.write("a.txt", "a new good paragraph\n\na BROKEN paragraph")
+	f.write("d.txt", "BROKEN")
+	r, err := p.Run(ctx)
+	if !errors.Is(err, errBroken) {
+		t.Fatalf("got error %v, want %v", err, errBroken)
+	}
+	checkReport(t, r, nil, nil, nil, nil, []string{"a.txt", "d.txt"}, 1, 1)
+
+	// the good chunk was deleted again and the previous chunks kept
+	after, _ := f.entry("a.txt")
+	if after.Hash != "" || !slices.Equal(after.Chunks, before.Chunks) {
+		t.Errorf("entry of a.txt = %+v, want no hash and chunks %q", after, before.Chunks)
+	}
+	if _, ok := f.entry("d.txt"); ok {
+		t.Error("failed new file recorded in the manifest")
+	}
+	f.checkStore()
+
+	// the failed files are retried by the next run
+	f.write("a.txt", "a new good paragraph\n\na fixed paragraph")
+	f.write("d.txt", "delta")
+	r, err = p.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkReport(t, r, []string{"d.txt"}, []string{"a.txt"}, nil, nil, nil, 3, 2)
+	f.checkStore()
+}
+
+func TestRunCanceled(t *testing.T) {
+	f := newFixture(t)
+	f.write("a.txt", "alpha")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r, err := f.pipeline(f.store).Run(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want context.Canceled", err)
+	}
+	if len(r.Added) != 0 || f.store.Len() != 0 {
+		t.Errorf("canceled run ingested %q", r.Added)
+	}
+	if _, err := os.Stat(f.manifest); err != nil {
+		t.Errorf("manifest not written: %v", err)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	m, err := ReadManifest(path)
+	if err != nil || len(m.Sources) != 0 {
+		t.Fatalf("missing manifest: got %v, %v", m, err)
+	}
+
+	m.Sources["a.txt"] = Entry{Hash: "h", Version: "v", Chunks: []string{"1", "2"}}
+	if err := m.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := read.Sources["a.txt"]; e.Hash != "h" || e.Version != "v" || !slices.Equal(e.Chunks, []string{"1", "2"}) {
+		t.Errorf("read %+v", e)
+	}
+	if leftovers, _ := filepath.Glob(path + ".*"); len(leftovers) != 0 {
+		t.Errorf("temporary files left: %q", leftovers)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadManifest(path); err == nil {
+		t.Error("reading a corrupt manifest: no error")
+	}
+}
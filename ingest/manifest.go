@@ -0,0 +1,69 @@
+package ingest
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Manifest records the sources ingested into a store and the chunks indexed for each
+type Manifest struct {
+	Sources map[string]Entry `json:"sources"` // Entries by source path
+}
+
+// Entry is a type that records an ingested source
+type Entry struct {
+	Hash    string    `json:"hash"`              // Hex SHA-256 of the content, empty to ingest the source again
+	Version string    `json:"version,omitempty"` // Version of the pipeline that ingested the source (see WithVersion)
+	Chunks  []string  `json:"chunks"`            // IDs of the chunks indexed from the source
+	Updated time.Time `json:"updated"`           // Time the source was last ingested
+}
+
+// ReadManifest reads the manifest at path, or returns an empty one if the file doesn't exist
+func ReadManifest(path string) (*Manifest, error) {
+	m := &Manifest{Sources: make(map[string]Entry)}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, m); err != nil {
+		return nil, err
+	}
+	if m.Sources == nil {
+		m.Sources = make(map[string]Entry)
+	}
+	return m, nil
+}
+
+// Write writes m to path, replacing the file at once so that a crash leaves the previous manifest intact
+func (m *Manifest) Write(path string) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -3,6 +3,7 @@ package ragkit
 import (
 	"context"
 	"fmt"
+	"iter"
 )
 
 // Embedder is a type that can embed texts into vectors
@@ -39,6 +40,21 @@ type Indexer interface {
 	Exists(ctx context.Context, id string) (bool, error)
 }
 
+// Upserter is an Indexer that can also replace documents already indexed
+type Upserter interface {
+	Indexer
+
+	// Upsert: Index documents, atomically replacing text, metadata and vector of existing IDs
+	// Returns: IDs of inserted and updated documents
+	Upsert(ctx context.Context, docs ...Document) (UpsertResult, error)
+}
+
+// UpsertResult is a type that reports which documents of an Upsert were inserted or updated
+type UpsertResult struct {
+	Inserted []string // IDs that didn't exist before
+	Updated  []string // IDs whose document was replaced
+}
+
 // Document is a type that represents a document
 type Document struct {
 	ID       string         // Unique ID
@@ -47,6 +63,12 @@ type Document struct {
 	Vector   []float32      // Optional: Embedding vector (generated by Embeder if not provided)
 }
 
+// Metadata keys of documents shared between packages, set by the loader package
+const (
+	MetaSource = "source" // Path of the file the document was loaded from
+	MetaPage   = "page"   // Number of the page of the document in its file, from 1
+)
+
 // Retriever is a type that can retrieve documents from a vector database
 type Retriever interface {
 	// Retrieve: Return top-K documents based on query vector
@@ -56,10 +78,87 @@ type Retriever interface {
 	RetrieveText(ctx context.Context, text string, topK int, metadataFieldNames ...string) ([]RetrievedDoc, error)
 }
 
+// FilteredRetriever is a Retriever that can narrow the search down by document metadata
+type FilteredRetriever interface {
+	Retriever
+
+	// RetrieveFiltered: Return top-K documents matching filter based on query vector
+	RetrieveFiltered(ctx context.Context, query []float32, topK int, filter *Filter, metadataFieldNames ...string) ([]RetrievedDoc, error)
+
+	// RetrieveTextFiltered: Return top-K documents matching filter based on text query
+	RetrieveTextFiltered(ctx context.Context, text string, topK int, filter *Filter, metadataFieldNames ...string) ([]RetrievedDoc, error)
+}
+
+// HybridRetriever is a Retriever that can also match the words of a text query (keyword search).
+// Keyword and vector results are merged as documented by FuseRelativeScores.
+type HybridRetriever interface {
+	Retriever
+
+	// RetrieveHybrid: Return top-K documents matching filter (nil for all) based on text query,
+	// searched both by keywords and by vector.
+	// alpha weighs vector search against keyword search, from 0 (keyword only) to 1 (vector only)
+	RetrieveHybrid(ctx context.Context, text string, topK int, alpha float32, filter *Filter, metadataFieldNames ...string) ([]RetrievedDoc, error)
+}
+
+// Reranker is a type that can reorder retrieved documents by their relevance to a query
+type Reranker interface {
+	// Rerank: Return the topN documents most relevant to query, most relevant first
+	// Returns: Copies of docs with Score replaced by the relevance in [0, 1]
+	Rerank(ctx context.Context, query string, docs []RetrievedDoc, topN int) ([]RetrievedDoc, error)
+
+	fmt.Stringer
+}
+
+// Generator is a type that can generate the reply of a language model to a chat
+type Generator interface {
+	// Generate: Return the reply of the model to messages, oldest first
+	Generate(ctx context.Context, messages ...Message) (string, error)
+
+	fmt.Stringer
+}
+
+// StreamingGenerator is a Generator that can stream the reply as it is generated
+type StreamingGenerator interface {
+	Generator
+
+	// GenerateStream: Return the pieces of the reply of the model to messages as they are generated.
+	// The sequence ends after the first error. Stopping the iteration cancels the generation.
+	GenerateStream(ctx context.Context, messages ...Message) iter.Seq2[string, error]
+}
+
+// Role is a type that represents the author of a chat message
+type Role string
+
+const (
+	RoleSystem    Role = "system"    // Instructions to the model
+	RoleUser      Role = "user"      // Message of the user
+	RoleAssistant Role = "assistant" // Reply of the model
+)
+
+// Message is a type that represents a message of a chat
+type Message struct {
+	Role    Role
+	Content string
+}
+
+// HistoryStore is a type that can keep the messages of conversations
+type HistoryStore interface {
+	// Messages: Return the messages of a conversation, oldest first (none if it doesn't exist)
+	Messages(ctx context.Context, conversationID string) ([]Message, error)
+
+	// Append: Add messages at the end of a conversation, creating it if needed
+	Append(ctx context.Context, conversationID string, messages ...Message) error
+
+	// Clear: Delete a conversation
+	Clear(ctx context.Context, conversationID string) error
+}
+
 // RetrievedDoc is a type that represents a retrieved document from vector database
 type RetrievedDoc struct {
-	// ID       string         // Document ID
-	// Score    float32        // Similarity score
+	ID       string         // Document ID
+	Score    float32        // Normalized similarity score in [0, 1], higher is more similar
+	Distance float32        // Raw distance reported by the vector database
+	Metric   DistanceMetric // Distance metric used to compute Distance
 	Vector   []float32      // Retrieved vector
 	Text     string         // Retrieved text
 	Metadata map[string]any // Optional metadata
@@ -0,0 +1,131 @@
+package loader
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+var _ Loader = &CSV{}
+
+// CSV loads every row of a CSV file with a header as a document.
+// By default the text lists every column as "name: value" lines and the metadata holds MetaRow only.
+type CSV struct {
+	comma           rune
+	textColumns     []string
+	metadataColumns []string
+}
+
+// CSVOption configures a CSV loader
+type CSVOption func(*CSV)
+
+// WithComma sets the field delimiter (default: ',')
+func WithComma(comma rune) CSVOption {
+	return func(c *CSV) {
+		c.comma = comma
+	}
+}
+
+// WithTextColumns sets the columns making the text, joined by new lines as their bare values
+func WithTextColumns(columns ...string) CSVOption {
+	return func(c *CSV) {
+		c.textColumns = columns
+	}
+}
+
+// WithMetadataColumns sets the columns copied into the metadata, keyed by column name
+func WithMetadataColumns(columns ...string) CSVOption {
+	return func(c *CSV) {
+		c.metadataColumns = columns
+	}
+}
+
+func NewCSV(opts ...CSVOption) *CSV {
+	c := &CSV{comma: ','}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// NewTSV creates a CSV loader for tab-separated values
+func NewTSV(opts ...CSVOption) *CSV {
+	return NewCSV(append([]CSVOption{WithComma('\t')}, opts...)...)
+}
+
+func (c *CSV) Load(ctx context.Context, r io.Reader) ([]ragkit.Document, error) {
+	cr := csv.NewReader(r)
+	cr.Comma = c.comma
+	cr.FieldsPerRecord = -1
+	if c.comma == '\t' {
+		cr.LazyQuotes = true // TSV rarely quotes
+	}
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\uFEFF") // byte order mark
+	}
+	for _, col := range slices.Concat(c.textColumns, c.metadataColumns) {
+		if !slices.Contains(header, col) {
+			return nil, fmt.Errorf("no column %q", col)
+		}
+	}
+
+	var docs []ragkit.Document
+	for row := 0; ; row++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		values := make(map[string]string, len(header))
+		for i, name := range header {
+			if i < len(record) {
+				values[name] = record[i]
+			}
+		}
+
+		var lines []string
+		if c.textColumns != nil {
+			for _, col := range c.textColumns {
+				if v := strings.TrimSpace(values[col]); v != "" {
+					lines = append(lines, v)
+				}
+			}
+		} else {
+			for _, col := range header {
+				if v := strings.TrimSpace(values[col]); v != "" {
+					lines = append(lines, col+": "+v)
+				}
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+
+		metadata := map[string]any{MetaRow: row}
+		for _, col := range c.metadataColumns {
+			metadata[col] = values[col]
+		}
+		docs = append(docs, ragkit.Document{Text: strings.Join(lines, "\n"), Metadata: metadata})
+	}
+	return docs, nil
+}
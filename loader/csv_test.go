@@ -0,0 +1,65 @@
+package loader
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const products = "\uFEFFid,name,description,price\n" +
+	"1,Lamp,\"A desk lamp, with a \"\"warm\"\" light\",25\n" +
+	"2,Chair,,40\n" +
+	"3,,,\n" +
+	"4,Short\n"
+
+func TestCSV(t *testing.T) {
+	docs := load(t, NewCSV(), products)
+	want := []struct {
+		text string
+		row  int
+	}{
+		{"id: 1\nname: Lamp\ndescription: A desk lamp, with a \"warm\" light\nprice: 25", 0},
+		{"id: 2\nname: Chair\nprice: 40", 1},
+		{"id: 3", 2},
+		{"id: 4\nname: Short", 3},
+	}
+	if len(docs) != len(want) {
+		t.Fatalf("got %d documents, want %d", len(docs), len(want))
+	}
+	for i, w := range want {
+		if docs[i].Text != w.text || docs[i].Metadata[MetaRow] != w.row || len(docs[i].Metadata) != 1 {
+			t.Errorf("document %d = %q %v, want %q at row %d", i, docs[i].Text, docs[i].Metadata, w.text, w.row)
+		}
+	}
+}
+
+func TestCSVColumns(t *testing.T) {
+	docs := load(t, NewCSV(WithTextColumns("name", "description"), WithMetadataColumns("id", "price")), products)
+	// row 2 has no text and is skipped
+	if len(docs) != 3 {
+		t.Fatalf("got %d documents, want 3", len(docs))
+	}
+	if docs[0].Text != "Lamp\nA desk lamp, with a \"warm\" light" {
+		t.Errorf("text = %q", docs[0].Text)
+	}
+	if m := docs[1].Metadata; docs[1].Text != "Chair" || m["id"] != "2" || m["price"] != "40" || m[MetaRow] != 1 {
+		t.Errorf("document 1 = %q %v", docs[1].Text, m)
+	}
+	if m := docs[2].Metadata; m[MetaRow] != 3 || m["price"] != "" {
+		t.Errorf("short record: metadata %v, want row 3 and an empty price", m)
+	}
+
+	if _, err := NewCSV(WithMetadataColumns("missing")).Load(context.Background(), strings.NewReader(products)); err == nil {
+		t.Error("missing column: no error")
+	}
+	if docs := load(t, NewCSV(), ""); docs != nil {
+		t.Errorf("empty file: got %v", docs)
+	}
+}
+
+func TestTSV(t *testing.T) {
+	docs := load(t, NewTSV(WithTextColumns("quote")), "author\tquote\nX\tsay \"hi\" now\n")
+	if len(docs) != 1 || docs[0].Text != `say "hi" now` {
+		t.Errorf("got %v", docs)
+	}
+}
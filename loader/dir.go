@@ -0,0 +1,187 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+// Dir loads the files of a directory tree, each with the Loader registered for its extension.
+// Files without a loader are skipped.
+//
+// Include and exclude patterns use the syntax of path.Match. A pattern without "/" matches
+// the name of a file or directory anywhere in the tree; a pattern with "/" matches the path
+// relative to the root, where "**" matches any number of directories, like "docs/**/*.md".
+type Dir struct {
+	root    string
+	loaders map[string]Loader
+	include []string
+	exclude []string
+}
+
+// DirOption configures a Dir
+type DirOption func(*Dir)
+
+// WithLoader sets the loader of the files with extension ext, like ".txt".
+// A nil loader skips those files.
+func WithLoader(ext string, l Loader) DirOption {
+	return func(d *Dir) {
+		d.loaders[strings.ToLower(ext)] = l
+	}
+}
+
+// WithInclude loads only the files matching any of patterns
+func WithInclude(patterns ...string) DirOption {
+	return func(d *Dir) {
+		d.include = append(d.include, patterns...)
+	}
+}
+
+// WithExclude skips the files and directories matching any of patterns,
+// in addition to the hidden ones (".*")
+func WithExclude(patterns ...string) DirOption {
+	return func(d *Dir) {
+		d.exclude = append(d.exclude, patterns...)
+	}
+}
+
+// NewDir creates a Dir loading the tree at root.
+// Loaders are registered for .txt, .md, .markdown, .html, .htm, .csv, .tsv, .json, .jsonl, .ndjson, .pdf and .docx.
+func NewDir(root string, opts ...DirOption) *Dir {
+	d := &Dir{
+		root: root,
+		loaders: map[string]Loader{
+			".txt":      NewText(),
+			".md":       NewMarkdown(),
+			".markdown": NewMarkdown(),
+			".html":     NewHTML(),
+			".htm":      NewHTML(),
+			".csv":      NewCSV(),
+			".tsv":      NewTSV(),
+			".json":     NewJSON(),
+			".jsonl":    NewJSON(),
+			".ndjson":   NewJSON(),
+			".pdf":      NewPDF(),
+			".docx":     NewDOCX(),
+		},
+		exclude: []string{".*"},
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Files returns the paths of the files to load, in lexical order
+func (d *Dir) Files(ctx context.Context) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(d.root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(d.root, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if matchAny(d.exclude, rel) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || !entry.Type().IsRegular() {
+			return nil
+		}
+		if len(d.include) > 0 && !matchAny(d.include, rel) {
+			return nil
+		}
+		if d.loader(p) == nil {
+			return nil
+		}
+		files = append(files, p)
+		return nil
+	})
+	return files, err
+}
+
+// LoadFile loads the file at path with the loader of its extension (see LoadFile)
+func (d *Dir) LoadFile(ctx context.Context, path string) ([]ragkit.Document, error) {
+	l := d.loader(path)
+	if l == nil {
+		return nil, fmt.Errorf("%s: no loader for %q", path, filepath.Ext(path))
+	}
+	return LoadFile(ctx, l, path)
+}
+
+// Load loads every file of the tree
+func (d *Dir) Load(ctx context.Context) ([]ragkit.Document, error) {
+	files, err := d.Files(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []ragkit.Document
+	for _, p := range files {
+		loaded, err := d.LoadFile(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, loaded...)
+	}
+	return docs, nil
+}
+
+func (d *Dir) loader(p string) Loader {
+	return d.loaders[strings.ToLower(filepath.Ext(p))]
+}
+
+// matchAny reports whether the slash-separated relative path rel matches any of patterns
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+				return true
+			}
+			continue
+		}
+		if matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments matches path segments against pattern segments, "**" matching any number of segments
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
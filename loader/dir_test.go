@@ -0,0 +1,117 @@
+package loader
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+// upper loads a file as a single upper-cased document
+type upper struct{}
+
+func (upper) Load(ctx context.Context, r io.Reader) ([]ragkit.Document, error) {
+	b, err := io.ReadAll(r)
+	return []ragkit.Document{{Text: strings.ToUpper(string(b))}}, err
+}
+
+var tree = map[string]string{
+	"a.txt":                "a",
+	"B.TXT":                "b",
+	"notes.md":             "# Notes",
+	"data.csv":             "k,v\n1,2\n",
+	"image.png":            "not loaded",
+	".hidden.txt":          "hidden",
+	".git/config.txt":      "hidden",
+	"docs/guide.md":        "guide",
+	"docs/deep/nested.md":  "nested",
+	"docs/deep/skip.txt":   "txt in docs",
+	"vendor/lib/x.txt":     "vendored",
+	"vendor/lib/README.md": "vendored",
+}
+
+// relFiles returns the files of d relative to root
+func relFiles(t *testing.T, d *Dir, root string) []string {
+	t.Helper()
+	files, err := d.Files(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rel []string
+	for _, f := range files {
+		r, _ := filepath.Rel(root, f)
+		rel = append(rel, filepath.ToSlash(r))
+	}
+	return rel
+}
+
+func TestDirFiles(t *testing.T) {
+	root := writeFiles(t, tree)
+	tests := []struct {
+		name string
+		opts []DirOption
+		want []string
+	}{
+		{"default", nil, []string{"B.TXT", "a.txt", "data.csv", "docs/deep/nested.md", "docs/deep/skip.txt", "docs/guide.md", "notes.md", "vendor/lib/README.md", "vendor/lib/x.txt"}},
+		{"include name", []DirOption{WithInclude("*.md")}, []string{"docs/deep/nested.md", "docs/guide.md", "notes.md", "vendor/lib/README.md"}},
+		{"include path", []DirOption{WithInclude("docs/**/*.md")}, []string{"docs/deep/nested.md", "docs/guide.md"}},
+		{"include top level", []DirOption{WithInclude("*/*.md")}, []string{"docs/guide.md"}},
+		{"exclude dir", []DirOption{WithExclude("vendor", "deep")}, []string{"B.TXT", "a.txt", "data.csv", "docs/guide.md", "notes.md"}},
+		{"exclude path", []DirOption{WithExclude("docs/**/*.txt")}, []string{"B.TXT", "a.txt", "data.csv", "docs/deep/nested.md", "docs/guide.md", "notes.md", "vendor/lib/README.md", "vendor/lib/x.txt"}},
+		{"no loader", []DirOption{WithLoader(".txt", nil), WithLoader(".MD", nil)}, []string{"data.csv"}},
+		{"added loader", []DirOption{WithLoader(".png", upper{}), WithInclude("*.png")}, []string{"image.png"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := relFiles(t, NewDir(root, tt.opts...), root); !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDirLoad(t *testing.T) {
+	root := writeFiles(t, tree)
+	d := NewDir(root, WithLoader(".txt", upper{}), WithExclude("vendor"))
+	docs, err := d.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	texts := make(map[string]string)
+	for _, doc := range docs {
+		rel, _ := filepath.Rel(root, doc.Metadata[MetaSource].(string))
+		texts[filepath.ToSlash(rel)] = doc.Text
+		if doc.Metadata[MetaModified] == nil {
+			t.Errorf("%s: no modification time", rel)
+		}
+	}
+	want := map[string]string{
+		"a.txt":               "A",
+		"B.TXT":               "B",
+		"notes.md":            "# Notes",
+		"data.csv":            "k: 1\nv: 2",
+		"docs/guide.md":       "guide",
+		"docs/deep/nested.md": "nested",
+		"docs/deep/skip.txt":  "TXT IN DOCS",
+	}
+	if len(texts) != len(want) {
+		t.Errorf("loaded %d files, want %d: %v", len(texts), len(want), texts)
+	}
+	for name, text := range want {
+		if texts[name] != text {
+			t.Errorf("%s: text %q, want %q", name, texts[name], text)
+		}
+	}
+
+	if _, err := d.LoadFile(context.Background(), filepath.Join(root, "image.png")); err == nil {
+		t.Error("loading a file without loader: no error")
+	}
+	if _, err := NewDir(filepath.Join(root, "missing")).Load(context.Background()); err == nil {
+		t.Error("loading a missing directory: no error")
+	}
+}
@@ -0,0 +1,343 @@
+package loader
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+var _ Loader = &DOCX{}
+
+// DOCX loads a Word document as one document per section and page, a section being a heading
+// and the paragraphs following it. The metadata holds MetaSection, the text of the heading,
+// and MetaPage, the page the first paragraph starts on.
+//
+// Word files don't store a layout, so pages follow the page breaks Word recorded when it last
+// saved the file, or the explicit page breaks if it recorded none.
+// Table rows become paragraphs with their cells separated by " | ".
+type DOCX struct{}
+
+func NewDOCX() *DOCX {
+	return &DOCX{}
+}
+
+// docxFrame is an open paragraph, table row or table cell
+type docxFrame struct {
+	kind  string // "p", "tr" or "tc"
+	parts []string
+	page  int // page of the first text, 0 if none yet
+	style string
+	level int // heading level given by the paragraph itself, 0 if none
+}
+
+func (d *DOCX) Load(ctx context.Context, r io.Reader) ([]ragkit.Document, error) {
+	ra, size, err := readerAt(r)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(ra, size)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := readZipFile(zr, "word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+	levels, err := docxHeadingLevels(zr)
+	if err != nil {
+		return nil, err
+	}
+	title, err := docxTitle(zr)
+	if err != nil {
+		return nil, err
+	}
+	rendered := bytes.Contains(body, []byte("lastRenderedPageBreak"))
+
+	var docs []ragkit.Document
+	var section string
+	var paragraphs []string
+	docPage := 0
+	flush := func() {
+		if len(paragraphs) == 0 {
+			return
+		}
+		metadata := map[string]any{MetaPage: docPage}
+		if section != "" {
+			metadata[MetaSection] = section
+		}
+		docs = append(docs, ragkit.Document{Text: strings.Join(paragraphs, "\n\n"), Metadata: metadata})
+		paragraphs = nil
+	}
+
+	page := 1
+	var stack []*docxFrame
+	top := func() *docxFrame {
+		if len(stack) == 0 {
+			return nil
+		}
+		return stack[len(stack)-1]
+	}
+	addText := func(s string) {
+		if f := top(); f != nil && s != "" {
+			f.parts = append(f.parts, s)
+			if f.page == 0 {
+				f.page = page
+			}
+		}
+	}
+	inPPr, inText := false, false
+
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "Fallback" {
+				// duplicate content for readers without support of the alternative
+				if err := dec.Skip(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if !isWordML(t.Name.Space) {
+				continue
+			}
+			switch t.Name.Local {
+			case "p", "tr", "tc":
+				stack = append(stack, &docxFrame{kind: t.Name.Local})
+			case "pPr":
+				inPPr = true
+			case "pStyle":
+				if f := top(); inPPr && f != nil {
+					f.style = attr(t, "val")
+				}
+			case "outlineLvl":
+				if lvl, err := strconv.Atoi(attr(t, "val")); inPPr && err == nil && lvl < 9 && top() != nil {
+					top().level = lvl + 1
+				}
+			case "pageBreakBefore":
+				if inPPr && !rendered && attr(t, "val") != "0" && attr(t, "val") != "false" {
+					page++
+				}
+			case "lastRenderedPageBreak":
+				if rendered {
+					page++
+				}
+			case "br":
+				switch attr(t, "type") {
+				case "page":
+					if !rendered {
+						page++
+					}
+				case "", "textWrapping":
+					addText("\n")
+				}
+			case "tab":
+				if !inPPr {
+					addText("\t")
+				}
+			case "t":
+				inText = true
+			case "del", "instrText":
+				// deleted revisions and field codes
+				if err := dec.Skip(); err != nil {
+					return nil, err
+				}
+			}
+		case xml.EndElement:
+			if !isWordML(t.Name.Space) {
+				continue
+			}
+			switch t.Name.Local {
+			case "pPr":
+				inPPr = false
+			case "t":
+				inText = false
+			case "p", "tr", "tc":
+				f := top()
+				if f == nil || f.kind != t.Name.Local {
+					return nil, fmt.Errorf("unbalanced %s element", t.Name.Local)
+				}
+				stack = stack[:len(stack)-1]
+
+				var text string
+				switch f.kind {
+				case "p":
+					text = strings.TrimSpace(collapseSpace(strings.Join(f.parts, "")))
+				case "tc":
+					text = strings.Join(f.parts, " ")
+				case "tr":
+					text = strings.Join(f.parts, " | ")
+				}
+				if text == "" {
+					continue
+				}
+
+				if parent := top(); parent != nil {
+					if parent.kind == "p" {
+						text = " " + text // text box inside a paragraph
+					}
+					parent.parts = append(parent.parts, text)
+					if parent.page == 0 {
+						parent.page = f.page
+					}
+					continue
+				}
+
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				level, heading := levels[f.style]
+				if f.level > 0 {
+					level, heading = f.level, true
+				}
+				if heading && f.kind == "p" {
+					if level == 0 && title == "" {
+						title = text
+					}
+					flush()
+					section = text
+					docPage = f.page
+				} else if len(paragraphs) > 0 && f.page != docPage {
+					flush()
+					docPage = f.page
+				} else if len(paragraphs) == 0 {
+					docPage = f.page
+				}
+				paragraphs = append(paragraphs, text)
+			}
+		case xml.CharData:
+			if inText {
+				addText(string(t))
+			}
+		}
+	}
+	flush()
+
+	if title != "" {
+		for _, doc := range docs {
+			doc.Metadata[MetaTitle] = title
+		}
+	}
+	return docs, nil
+}
+
+// docxHeadingLevels returns the heading level of the paragraph styles making headings:
+// 0 for the title, 1 to 9 for "heading 1" to "heading 9" and the styles with an outline level
+func docxHeadingLevels(zr *zip.Reader) (map[string]int, error) {
+	b, err := readZipFile(zr, "word/styles.xml")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	type val struct {
+		Val string `xml:"val,attr"`
+	}
+	var styles struct {
+		Styles []struct {
+			ID         string `xml:"styleId,attr"`
+			Type       string `xml:"type,attr"`
+			Name       val    `xml:"name"`
+			BasedOn    val    `xml:"basedOn"`
+			OutlineLvl *val   `xml:"pPr>outlineLvl"`
+		} `xml:"style"`
+	}
+	if err := xml.Unmarshal(b, &styles); err != nil {
+		return nil, fmt.Errorf("word/styles.xml: %w", err)
+	}
+
+	basedOn := make(map[string]string)
+	levels := make(map[string]int)
+	for _, s := range styles.Styles {
+		if s.Type != "paragraph" {
+			continue
+		}
+		basedOn[s.ID] = s.BasedOn.Val
+		name := strings.ToLower(s.Name.Val)
+		if name == "title" {
+			levels[s.ID] = 0
+		} else if n, ok := strings.CutPrefix(name, "heading "); ok {
+			if lvl, err := strconv.Atoi(n); err == nil && lvl >= 1 && lvl <= 9 {
+				levels[s.ID] = lvl
+			}
+		} else if s.OutlineLvl != nil {
+			if lvl, err := strconv.Atoi(s.OutlineLvl.Val); err == nil && lvl < 9 {
+				levels[s.ID] = lvl + 1
+			}
+		}
+	}
+	// custom heading styles based on the built-in ones
+	for id := range basedOn {
+		for base, depth := basedOn[id], 0; base != "" && depth < 10; base, depth = basedOn[base], depth+1 {
+			if _, ok := levels[id]; ok {
+				break
+			}
+			if lvl, ok := levels[base]; ok {
+				levels[id] = lvl
+			}
+		}
+	}
+	return levels, nil
+}
+
+// docxTitle returns the title in the properties of a Word document, if any
+func docxTitle(zr *zip.Reader) (string, error) {
+	b, err := readZipFile(zr, "docProps/core.xml")
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	var core struct {
+		Title string `xml:"title"`
+	}
+	if err := xml.Unmarshal(b, &core); err != nil {
+		return "", fmt.Errorf("docProps/core.xml: %w", err)
+	}
+	return strings.TrimSpace(core.Title), nil
+}
+
+func readZipFile(zr *zip.Reader, name string) ([]byte, error) {
+	f, err := zr.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// isWordML reports whether space is the namespace of WordprocessingML, transitional or strict
+func isWordML(space string) bool {
+	return space == "http://schemas.openxmlformats.org/wordprocessingml/2006/main" ||
+		space == "http://purl.oclc.org/ooxml/wordprocessingml/main"
+}
+
+func attr(e xml.StartElement, local string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,181 @@
+package loader
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"testing"
+)
+
+// makeDOCX returns a Word document made of files, keyed by their name in the archive
+func makeDOCX(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func wordBody(body string) string {
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+  xmlns:mc="http://schemas.openxmlformats.org/markup-compatibility/2006"><w:body>` + body + `</w:body></w:document>`
+}
+
+const wordStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+  <w:style w:type="paragraph" w:styleId="Normal"><w:name w:val="Normal"/></w:style>
+  <w:style w:type="paragraph" w:styleId="Title"><w:name w:val="Title"/></w:style>
+  <w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/></w:style>
+  <w:style w:type="paragraph" w:styleId="Heading2"><w:name w:val="heading 2"/></w:style>
+  <w:style w:type="paragraph" w:styleId="Chapter"><w:name w:val="Chapter"/><w:basedOn w:val="Heading1"/></w:style>
+  <w:style w:type="paragraph" w:styleId="Custom"><w:name w:val="Custom"/><w:basedOn w:val="Chapter"/></w:style>
+  <w:style w:type="paragraph" w:styleId="Outline"><w:name w:val="Outline"/><w:pPr><w:outlineLvl w:val="2"/></w:pPr></w:style>
+  <w:style w:type="character" w:styleId="Heading1Char"><w:name w:val="heading 1 Char"/></w:style>
+</w:styles>`
+
+func para(style, text string) string {
+	p := `<w:p>`
+	if style != "" {
+		p += `<w:pPr><w:pStyle w:val="` + style + `"/></w:pPr>`
+	}
+	return p + `<w:r><w:t xml:space="preserve">` + text + `</w:t></w:r></w:p>`
+}
+
+func loadDOCX(t *testing.T, files map[string]string) []docSummary {
+	t.Helper()
+	docs, err := NewDOCX().Load(context.Background(), bytes.NewReader(makeDOCX(t, files)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []docSummary
+	for _, doc := range docs {
+		section, _ := doc.Metadata[MetaSection].(string)
+		title, _ := doc.Metadata[MetaTitle].(string)
+		got = append(got, docSummary{doc.Text, section, doc.Metadata[MetaPage].(int), title})
+	}
+	return got
+}
+
+type docSummary struct {
+	text    string
+	section string
+	page    int
+	title   string
+}
+
+func checkDocs(t *testing.T, got, want []docSummary) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d documents %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("document %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDOCXHeadings(t *testing.T) {
+	body := para("Title", "Report") +
+		para("", "Preface text.") +
+		para("Heading1", "Intro") +
+		para("", "First.") +
+		para("Normal", "Second.") +
+		para("Chapter", "Based on heading 1") +
+		para("", "Chapter text.") +
+		para("Custom", "Based twice") +
+		para("Outline", "Outline style") +
+		`<w:p><w:pPr><w:outlineLvl w:val="1"/></w:pPr><w:r><w:t>Direct outline</w:t></w:r></w:p>` +
+		para("", "Last.")
+	got := loadDOCX(t, map[string]string{"word/document.xml": wordBody(body), "word/styles.xml": wordStyles})
+	checkDocs(t, got, []docSummary{
+		{"Report\n\nPreface text.", "Report", 1, "Report"},
+		{"Intro\n\nFirst.\n\nSecond.", "Intro", 1, "Report"},
+		{"Based on heading 1\n\nChapter text.", "Based on heading 1", 1, "Report"},
+		{"Based twice", "Based twice", 1, "Report"},
+		{"Outline style", "Outline style", 1, "Report"},
+		{"Direct outline\n\nLast.", "Direct outline", 1, "Report"},
+	})
+}
+
+func TestDOCXCoreTitle(t *testing.T) {
+	core := `<?xml version="1.0" encoding="UTF-8"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties"
+  xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title> From properties </dc:title></cp:coreProperties>`
+	got := loadDOCX(t, map[string]string{
+		"word/document.xml": wordBody(para("Title", "In body") + para("", "Text.")),
+		"word/styles.xml":   wordStyles,
+		"docProps/core.xml": core,
+	})
+	checkDocs(t, got, []docSummary{{"In body\n\nText.", "In body", 1, "From properties"}})
+
+	// without styles, no paragraph is a heading
+	got = loadDOCX(t, map[string]string{"word/document.xml": wordBody(para("Heading1", "Not a heading") + para("", "Text."))})
+	checkDocs(t, got, []docSummary{{"Not a heading\n\nText.", "", 1, ""}})
+}
+
+func TestDOCXPageBreaks(t *testing.T) {
+	pageBreak := `<w:p><w:r><w:t>Before</w:t></w:r><w:r><w:br w:type="page"/></w:r><w:r><w:t>after</w:t></w:r></w:p>`
+	body := para("", "One.") + pageBreak +
+		para("", "Two.") +
+		`<w:p><w:pPr><w:pageBreakBefore/></w:pPr><w:r><w:t>Three.</w:t></w:r></w:p>` +
+		`<w:p><w:pPr><w:pageBreakBefore w:val="0"/></w:pPr><w:r><w:t>Still three.</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>Line</w:t><w:br/><w:t>break</w:t><w:tab/><w:t>tab</w:t></w:r></w:p>`
+	got := loadDOCX(t, map[string]string{"word/document.xml": wordBody(body), "word/styles.xml": wordStyles})
+	checkDocs(t, got, []docSummary{
+		{"One.\n\nBeforeafter", "", 1, ""},
+		{"Two.", "", 2, ""},
+		{"Three.\n\nStill three.\n\nLine\nbreak tab", "", 3, ""},
+	})
+
+	// rendered page breaks win over explicit ones
+	rendered := para("", "One.") +
+		`<w:p><w:r><w:lastRenderedPageBreak/><w:t>Two.</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:br w:type="page"/><w:t>Still two.</w:t></w:r></w:p>`
+	got = loadDOCX(t, map[string]string{"word/document.xml": wordBody(rendered)})
+	checkDocs(t, got, []docSummary{{"One.", "", 1, ""}, {"Two.\n\nStill two.", "", 2, ""}})
+}
+
+func TestDOCXTables(t *testing.T) {
+	row := func(cells ...string) string {
+		r := `<w:tr>`
+		for _, c := range cells {
+			r += `<w:tc>` + para("", c) + `</w:tc>`
+		}
+		return r + `</w:tr>`
+	}
+	body := para("Heading1", "Prices") +
+		`<w:tbl>` + row("Item", "Price") + row("Lamp", "25") + row("", "") + `</w:tbl>` +
+		`<w:p><w:r><w:t>Kept</w:t></w:r><w:del><w:r><w:delText>deleted</w:delText></w:r></w:del>` +
+		`<w:r><w:instrText>PAGE</w:instrText></w:r>` +
+		`<mc:AlternateContent><mc:Choice><w:t>choice</w:t></mc:Choice><mc:Fallback><w:t>fallback</w:t></mc:Fallback></mc:AlternateContent></w:p>`
+	got := loadDOCX(t, map[string]string{"word/document.xml": wordBody(body), "word/styles.xml": wordStyles})
+	checkDocs(t, got, []docSummary{{"Prices\n\nItem | Price\n\nLamp | 25\n\nKeptchoice", "Prices", 1, ""}})
+}
+
+func TestDOCXErrors(t *testing.T) {
+	tests := map[string][]byte{
+		"not a zip":   []byte("garbage"),
+		"no document": makeDOCX(t, map[string]string{"word/styles.xml": wordStyles}),
+		"bad xml":     makeDOCX(t, map[string]string{"word/document.xml": wordBody("<w:p><w:r>")}),
+		"unbalanced":  makeDOCX(t, map[string]string{"word/document.xml": wordBody("<w:tc><w:p></w:p><w:p></w:tc></w:p>")}),
+		"bad styles":  makeDOCX(t, map[string]string{"word/document.xml": wordBody(""), "word/styles.xml": "<w:styles"}),
+	}
+	for name, b := range tests {
+		if _, err := NewDOCX().Load(context.Background(), bytes.NewReader(b)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package loader
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	ragkit "github.com/suapapa/go_ragkit"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var _ Loader = &HTML{}
+
+// lineBreaks replaces the line breaks of HTML text, mere white space outside pre elements
+var lineBreaks = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ")
+
+// HTML loads the visible text of an HTML page as a single document,
+// with its title and the targets of its links in the metadata.
+// Block elements like paragraphs and list items become separate lines.
+type HTML struct{}
+
+func NewHTML() *HTML {
+	return &HTML{}
+}
+
+func (h *HTML) Load(ctx context.Context, r io.Reader) ([]ragkit.Document, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	root, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var sb strings.Builder
+	var title string
+	var links []string
+	seen := make(map[string]bool)
+	pre := 0 // depth of pre elements, keeping their line breaks
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			if pre > 0 {
+				sb.WriteString(n.Data)
+			} else {
+				sb.WriteString(lineBreaks.Replace(n.Data))
+			}
+			return
+		case html.ElementNode:
+			switch n.DataAtom {
+			case atom.Title:
+				if title == "" && n.FirstChild != nil {
+					title = strings.Join(strings.Fields(n.FirstChild.Data), " ")
+				}
+				return
+			case atom.Script, atom.Style, atom.Noscript, atom.Template, atom.Head, atom.Svg:
+				return
+			case atom.A:
+				for _, attr := range n.Attr {
+					if attr.Key == "href" && attr.Val != "" && !strings.HasPrefix(attr.Val, "#") && !seen[attr.Val] {
+						seen[attr.Val] = true
+						links = append(links, attr.Val)
+					}
+				}
+			case atom.Br:
+				sb.WriteString("\n")
+			case atom.Pre:
+				pre++
+				defer func() { pre-- }()
+			}
+		}
+
+		block := n.Type == html.ElementNode && isBlock(n.DataAtom)
+		if block {
+			sb.WriteString("\n")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if block {
+			sb.WriteString("\n")
+		} else if n.Type == html.ElementNode && (n.DataAtom == atom.Td || n.DataAtom == atom.Th) {
+			sb.WriteString(" ") // cells of a row stay on its line
+		}
+	}
+	// the title lives in head, which walk skips
+	if t := findElement(root, atom.Title); t != nil {
+		walk(t)
+	}
+	walk(root)
+
+	text := collapseSpace(sb.String())
+	if text == "" {
+		return nil, nil
+	}
+	metadata := map[string]any{}
+	if title != "" {
+		metadata[MetaTitle] = title
+	}
+	if len(links) > 0 {
+		metadata[MetaLinks] = links
+	}
+	return []ragkit.Document{{Text: text, Metadata: metadata}}, nil
+}
+
+func isBlock(a atom.Atom) bool {
+	switch a {
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Main, atom.Header, atom.Footer, atom.Nav, atom.Aside,
+		atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6,
+		atom.Ul, atom.Ol, atom.Li, atom.Dl, atom.Dt, atom.Dd,
+		atom.Table, atom.Tr, atom.Blockquote, atom.Pre, atom.Figure, atom.Figcaption, atom.Hr:
+		return true
+	}
+	return false
+}
+
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// collapseSpace collapses runs of spaces within lines and drops blank lines
+func collapseSpace(s string) string {
+	var lines []string
+	for line := range strings.Lines(s) {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,58 @@
+package loader
+
+import (
+	"slices"
+	"testing"
+)
+
+const page = `<!DOCTYPE html>
+<html>
+<head>
+  <title>  The
+  Title </title>
+  <style>body { color: red }</style>
+  <script>var tracking = 1;</script>
+</head>
+<body>
+  <nav><a href="/">Home</a> <a href="#top">Top</a></nav>
+  <h1>Heading</h1>
+  <p>First   paragraph with <b>bold</b>
+     and a <a href="https://example.com/a">link</a>.</p>
+  <noscript>Enable JavaScript</noscript>
+  <template><p>hidden template</p></template>
+  <svg><text>svg text</text></svg>
+  <ul><li>one</li><li>two</li></ul>
+  <p>line<br>break</p>
+  <pre>code
+  block</pre>
+  <table><tr><th>k</th><td>v</td></tr></table>
+  <a href="/">Home again</a>
+</body>
+</html>`
+
+func TestHTML(t *testing.T) {
+	docs := load(t, NewHTML(), page)
+	if len(docs) != 1 {
+		t.Fatalf("got %d documents, want 1", len(docs))
+	}
+	want := "Home Top\nHeading\nFirst paragraph with bold and a link.\none\ntwo\nline\nbreak\ncode\nblock\nk v\nHome again"
+	if docs[0].Text != want {
+		t.Errorf("text = %q, want %q", docs[0].Text, want)
+	}
+	if title := docs[0].Metadata[MetaTitle]; title != "The Title" {
+		t.Errorf("title = %q", title)
+	}
+	if links, _ := docs[0].Metadata[MetaLinks].([]string); !slices.Equal(links, []string{"/", "https://example.com/a"}) {
+		t.Errorf("links = %q", links)
+	}
+}
+
+func TestHTMLEmpty(t *testing.T) {
+	if docs := load(t, NewHTML(), "<html><head><title>T</title><script>x()</script></head><body> </body></html>"); docs != nil {
+		t.Errorf("page without text: got %v", docs)
+	}
+	docs := load(t, NewHTML(), "plain <i>fragment</i>")
+	if len(docs) != 1 || docs[0].Text != "plain fragment" || len(docs[0].Metadata) != 0 {
+		t.Errorf("fragment: got %v", docs)
+	}
+}
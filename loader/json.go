@@ -0,0 +1,157 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+var _ Loader = &JSON{}
+
+// JSON loads records of a JSON or JSON Lines file as documents.
+// The input is a sequence of JSON values, each an object or an array of objects, so a single
+// document, an array of documents and JSON Lines are all read the same way.
+//
+// Paths select values in a record by object keys and array indexes separated by dots, like "body.text"
+// or "authors.0.name".
+type JSON struct {
+	textPath      string
+	metadataPaths map[string]string
+}
+
+// JSONOption configures a JSON loader
+type JSONOption func(*JSON)
+
+// WithTextPath sets the path of the text of a record (default: "text")
+func WithTextPath(path string) JSONOption {
+	return func(j *JSON) {
+		j.textPath = path
+	}
+}
+
+// WithMetadataPath copies the value at path into the metadata under key, if present
+func WithMetadataPath(key, path string) JSONOption {
+	return func(j *JSON) {
+		j.metadataPaths[key] = path
+	}
+}
+
+func NewJSON(opts ...JSONOption) *JSON {
+	j := &JSON{
+		textPath:      "text",
+		metadataPaths: make(map[string]string),
+	}
+	for _, opt := range opts {
+		opt(j)
+	}
+	return j
+}
+
+func (j *JSON) Load(ctx context.Context, r io.Reader) ([]ragkit.Document, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	var docs []ragkit.Document
+	row := 0
+	add := func(record any) error {
+		defer func() { row++ }()
+
+		v, ok := lookup(record, j.textPath)
+		if !ok {
+			return fmt.Errorf("record %d: no text at %q", row, j.textPath)
+		}
+		text, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("record %d: text at %q isn't a string", row, j.textPath)
+		}
+		if strings.TrimSpace(text) == "" {
+			return nil
+		}
+
+		metadata := map[string]any{MetaRow: row}
+		for key, path := range j.metadataPaths {
+			if v, ok := lookup(record, path); ok {
+				metadata[key] = plain(v)
+			}
+		}
+		docs = append(docs, ragkit.Document{Text: text, Metadata: metadata})
+		return nil
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var value any
+		err := dec.Decode(&value)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if records, ok := value.([]any); ok {
+			for _, record := range records {
+				if err := add(record); err != nil {
+					return nil, err
+				}
+			}
+		} else if err := add(value); err != nil {
+			return nil, err
+		}
+	}
+	return docs, nil
+}
+
+// lookup returns the value at path in v
+func lookup(v any, path string) (any, bool) {
+	if path == "" {
+		return v, true
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			var ok bool
+			if v, ok = node[key]; !ok {
+				return nil, false
+			}
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// plain converts the json.Numbers of v into int64 or float64
+func plain(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []any:
+		for i := range v {
+			v[i] = plain(v[i])
+		}
+	case map[string]any:
+		for k := range v {
+			v[k] = plain(v[k])
+		}
+	}
+	return v
+}
@@ -0,0 +1,106 @@
+package loader
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"object", `{"text": "one"}`},
+		{"array", `[{"text": "one"}, {"text": "two"}]`},
+		{"lines", "{\"text\": \"one\"}\n{\"text\": \"two\"}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs := load(t, NewJSON(), tt.input)
+			want := strings.Count(tt.input, "text")
+			if len(docs) != want {
+				t.Fatalf("got %d documents, want %d", len(docs), want)
+			}
+			for i, doc := range docs {
+				if doc.Text != []string{"one", "two"}[i] || doc.Metadata[MetaRow] != i {
+					t.Errorf("document %d = %q %v", i, doc.Text, doc.Metadata)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONPaths(t *testing.T) {
+	input := `[
+		{"body": {"text": "first"}, "authors": [{"name": "Ann"}, {"name": "Bob"}], "stars": 5, "score": 0.5, "tags": ["a", 1]},
+		{"body": {"text": "  "}, "authors": []},
+		{"body": {"text": "third"}, "authors": [{"name": "Cy"}]}
+	]`
+	docs := load(t, NewJSON(
+		WithTextPath("body.text"),
+		WithMetadataPath("author", "authors.0.name"),
+		WithMetadataPath("second", "authors.1.name"),
+		WithMetadataPath("stars", "stars"),
+		WithMetadataPath("score", "score"),
+		WithMetadataPath("tags", "tags"),
+	), input)
+	if len(docs) != 2 {
+		t.Fatalf("got %d documents, want 2 (blank text skipped)", len(docs))
+	}
+
+	m := docs[0].Metadata
+	if docs[0].Text != "first" || m["author"] != "Ann" || m["second"] != "Bob" || m[MetaRow] != 0 {
+		t.Errorf("document 0 = %q %v", docs[0].Text, m)
+	}
+	if m["stars"] != int64(5) || m["score"] != 0.5 {
+		t.Errorf("numbers = %#v, %#v, want int64 5 and float64 0.5", m["stars"], m["score"])
+	}
+	if tags, _ := m["tags"].([]any); !slices.Equal(tags, []any{"a", int64(1)}) {
+		t.Errorf("tags = %#v", m["tags"])
+	}
+
+	m = docs[1].Metadata
+	if docs[1].Text != "third" || m[MetaRow] != 2 || m["author"] != "Cy" {
+		t.Errorf("document 1 = %q %v", docs[1].Text, m)
+	}
+	if _, ok := m["second"]; ok {
+		t.Error("missing path copied into the metadata")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	record := map[string]any{"a": map[string]any{"b": []any{"x", "y"}}}
+	tests := []struct {
+		path string
+		want any
+		ok   bool
+	}{
+		{"a.b.1", "y", true},
+		{"a.b.2", nil, false},
+		{"a.b.-1", nil, false},
+		{"a.b.x", nil, false},
+		{"a.c", nil, false},
+		{"a.b.0.z", nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := lookup(record, tt.path)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("lookup(%q) = %v, %v, want %v, %v", tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	for name, input := range map[string]string{
+		"no text":       `{"title": "x"}`,
+		"text not text": `{"text": 3}`,
+		"malformed":     `{"text": "a"`,
+		"not a record":  `"just a string"`,
+	} {
+		if _, err := NewJSON().Load(context.Background(), strings.NewReader(input)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
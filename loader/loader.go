@@ -0,0 +1,56 @@
+// Package loader reads files of common formats into ragkit.Documents, ready to be split and indexed.
+package loader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+// Metadata keys set by the loaders
+const (
+	MetaSource   = ragkit.MetaSource // Path of the loaded file
+	MetaModified = "modified"        // Modification time of the file, RFC 3339
+	MetaTitle    = "title"           // Title of a Markdown, HTML, PDF or DOCX document
+	MetaLinks    = "links"           // Link targets of an HTML document
+	MetaRow      = "row"             // Position of the record in a CSV or JSON file, from 0
+	MetaPage     = ragkit.MetaPage   // Number of the page of a PDF or DOCX document, from 1
+	MetaSection  = "section"         // Title of the section of a PDF or DOCX document
+)
+
+// Loader is a type that can read documents from the content of a file
+type Loader interface {
+	// Load: Read the documents of r
+	Load(ctx context.Context, r io.Reader) ([]ragkit.Document, error)
+}
+
+// LoadFile loads the file at path with l, recording MetaSource and MetaModified in every document
+func LoadFile(ctx context.Context, l Loader, path string) ([]ragkit.Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := l.Load(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i := range docs {
+		if docs[i].Metadata == nil {
+			docs[i].Metadata = make(map[string]any)
+		}
+		docs[i].Metadata[MetaSource] = path
+		docs[i].Metadata[MetaModified] = info.ModTime().UTC().Format(time.RFC3339)
+	}
+	return docs, nil
+}
@@ -0,0 +1,89 @@
+package loader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+// load loads text with l, failing the test on error
+func load(t *testing.T, l Loader, text string) []ragkit.Document {
+	t.Helper()
+	docs, err := l.Load(context.Background(), strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return docs
+}
+
+// writeFiles writes files, keyed by slash-separated path, under a temporary directory and returns it
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestText(t *testing.T) {
+	docs := load(t, NewText(), "\uFEFF  hello\nworld \n")
+	if len(docs) != 1 || docs[0].Text != "hello\nworld" {
+		t.Errorf("got %v, want the trimmed text", docs)
+	}
+	if docs := load(t, NewText(), " \n "); docs != nil {
+		t.Errorf("blank text: got %v", docs)
+	}
+}
+
+func TestMarkdownTitle(t *testing.T) {
+	tests := []struct {
+		text  string
+		title any
+	}{
+		{"Intro\n\n# Title #\n\n# Other", "Title"},
+		{"```\n# code\n```\n\n# Real", "Real"},
+		{"## Second level only", nil},
+	}
+	for _, tt := range tests {
+		docs := load(t, NewMarkdown(), tt.text)
+		if len(docs) != 1 || docs[0].Text != tt.text || docs[0].Metadata[MetaTitle] != tt.title {
+			t.Errorf("%q: got %v, want title %v", tt.text, docs, tt.title)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	root := writeFiles(t, map[string]string{"a.txt": "content"})
+	p := filepath.Join(root, "a.txt")
+	modified := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	if err := os.Chtimes(p, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+
+	docs, err := LoadFile(context.Background(), NewText(), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 1 || docs[0].Metadata[MetaSource] != p || docs[0].Metadata[MetaModified] != "2024-05-06T07:08:09Z" {
+		t.Errorf("got %v", docs)
+	}
+
+	if _, err := LoadFile(context.Background(), NewText(), filepath.Join(root, "missing.txt")); err == nil {
+		t.Error("loading a missing file: no error")
+	}
+	if _, err := LoadFile(context.Background(), NewJSON(), p); err == nil || !strings.Contains(err.Error(), p) {
+		t.Errorf("got error %v, want one naming the file", err)
+	}
+}
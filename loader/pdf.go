@@ -0,0 +1,399 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+var _ Loader = &PDF{}
+
+// PDF loads every page of a PDF file with text as a document, numbered by MetaPage.
+// The metadata also holds the title of the file and, if it has an outline (bookmarks),
+// MetaSection: the title of the last outline entry pointing at or before the page.
+//
+// Only the text of the pages is read, in the order of their content streams;
+// scanned pages without a text layer are skipped.
+type PDF struct{}
+
+func NewPDF() *PDF {
+	return &PDF{}
+}
+
+func (p *PDF) Load(ctx context.Context, r io.Reader) (docs []ragkit.Document, err error) {
+	ra, size, err := readerAt(r)
+	if err != nil {
+		return nil, err
+	}
+	// the reader panics on malformed files
+	defer func() {
+		if r := recover(); r != nil {
+			docs, err = nil, fmt.Errorf("pdf: %v", r)
+		}
+	}()
+
+	pr, err := pdf.NewReader(ra, size)
+	if err != nil {
+		return nil, err
+	}
+	if pr.Trailer().Key("Root").Key("Pages").Kind() != pdf.Dict {
+		return nil, errors.New("pdf: no page tree")
+	}
+
+	numPages := pr.NumPage()
+	pageNums := make(map[string]int, numPages) // page dictionary -> page number
+	for i := 1; i <= numPages; i++ {
+		pageNums[pr.Page(i).V.String()] = i
+	}
+	sections := outlineSections(pr, pageNums)
+	title := strings.TrimSpace(pr.Trailer().Key("Info").Key("Title").Text())
+
+	section := ""
+	for i := 1; i <= numPages; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if s, ok := sections[i]; ok {
+			section = s
+		}
+
+		page := pr.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		text := collapseSpace(pageText(page))
+		if text == "" {
+			continue
+		}
+
+		metadata := map[string]any{MetaPage: i}
+		if title != "" {
+			metadata[MetaTitle] = title
+		}
+		if section != "" {
+			metadata[MetaSection] = section
+		}
+		docs = append(docs, ragkit.Document{Text: text, Metadata: metadata})
+	}
+	return docs, nil
+}
+
+// span is a run of text shown at once, in page space
+type span struct {
+	x, y, width, size float64
+	text              string
+}
+
+// pdfFont caches what pageText needs of a font, as the reader resolves it anew on every access
+type pdfFont struct {
+	enc       pdf.TextEncoding
+	first     int
+	widths    []float64
+	codeWidth int // bytes per character code: 2 for composite fonts
+}
+
+// matrix is an affine transformation [a b c d e f], applied to row vectors
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+func (m matrix) mul(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2], m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2], m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4], m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+func translate(tx, ty float64) matrix {
+	return matrix{1, 0, 0, 1, tx, ty}
+}
+
+// pageText lays out the text of a page as lines of words.
+// It interprets the text operators of the content stream, ignoring text in form XObjects.
+func pageText(page pdf.Page) string {
+	contents := page.V.Key("Contents")
+	if contents.IsNull() {
+		return ""
+	}
+	resources := page.Resources().Key("Font")
+	fonts := make(map[string]*pdfFont)
+
+	type state struct {
+		ctm                         matrix
+		font                        *pdfFont
+		size, charSp, wordSp, scale float64
+		leading, rise               float64
+	}
+	gs := state{ctm: identity, scale: 1}
+	var stack []state
+	var tm, tlm matrix
+	var spans []span
+
+	show := func(s string) {
+		f := gs.font
+		if f == nil {
+			return
+		}
+		text := f.enc.Decode(s)
+
+		var w float64 // unscaled advance
+		if len(f.widths) > 0 && f.codeWidth == 1 {
+			for i := 0; i < len(s); i++ {
+				if c := int(s[i]) - f.first; c >= 0 && c < len(f.widths) {
+					w += f.widths[c] / 1000 * gs.size
+				}
+				w += gs.charSp
+				if s[i] == ' ' {
+					w += gs.wordSp
+				}
+			}
+		} else {
+			n := utf8.RuneCountInString(text)
+			w = float64(n) * (gs.size/2 + gs.charSp) // estimate
+		}
+		w *= gs.scale
+
+		trm := translate(0, gs.rise).mul(tm).mul(gs.ctm)
+		sx := math.Hypot(trm[0], trm[1])
+		sy := math.Hypot(trm[2], trm[3])
+		spans = append(spans, span{
+			x:     trm[4],
+			y:     trm[5],
+			width: w * sx,
+			size:  gs.size * sy,
+			text:  text,
+		})
+		tm = translate(w, 0).mul(tm)
+	}
+	nextLine := func(tx, ty float64) {
+		tlm = translate(tx, ty).mul(tlm)
+		tm = tlm
+	}
+
+	pdf.Interpret(contents, func(stk *pdf.Stack, op string) {
+		args := make([]pdf.Value, stk.Len())
+		for i := len(args) - 1; i >= 0; i-- {
+			args[i] = stk.Pop()
+		}
+		num := func(i int) float64 {
+			if i < len(args) {
+				return args[i].Float64()
+			}
+			return 0
+		}
+		mat := func() matrix {
+			return matrix{num(0), num(1), num(2), num(3), num(4), num(5)}
+		}
+
+		switch op {
+		case "q":
+			stack = append(stack, gs)
+		case "Q":
+			if n := len(stack); n > 0 {
+				gs, stack = stack[n-1], stack[:n-1]
+			}
+		case "cm":
+			gs.ctm = mat().mul(gs.ctm)
+		case "BT":
+			tm, tlm = identity, identity
+		case "Tf":
+			if len(args) != 2 {
+				return
+			}
+			name := args[0].Name()
+			f, ok := fonts[name]
+			if !ok {
+				v := resources.Key(name)
+				f = &pdfFont{
+					enc:       pdf.Font{V: v}.Encoder(),
+					first:     int(v.Key("FirstChar").Int64()),
+					codeWidth: 1,
+				}
+				if v.Key("Subtype").Name() == "Type0" {
+					f.codeWidth = 2
+				}
+				widths := v.Key("Widths")
+				for i := 0; i < widths.Len(); i++ {
+					f.widths = append(f.widths, widths.Index(i).Float64())
+				}
+				fonts[name] = f
+			}
+			gs.font, gs.size = f, num(1)
+		case "Tc":
+			gs.charSp = num(0)
+		case "Tw":
+			gs.wordSp = num(0)
+		case "Tz":
+			gs.scale = num(0) / 100
+		case "TL":
+			gs.leading = num(0)
+		case "Ts":
+			gs.rise = num(0)
+		case "Td":
+			nextLine(num(0), num(1))
+		case "TD":
+			gs.leading = -num(1)
+			nextLine(num(0), num(1))
+		case "Tm":
+			tm = mat()
+			tlm = tm
+		case "T*":
+			nextLine(0, -gs.leading)
+		case "Tj":
+			if len(args) == 1 {
+				show(args[0].RawString())
+			}
+		case "'":
+			if len(args) == 1 {
+				nextLine(0, -gs.leading)
+				show(args[0].RawString())
+			}
+		case "\"":
+			if len(args) == 3 {
+				gs.wordSp, gs.charSp = num(0), num(1)
+				nextLine(0, -gs.leading)
+				show(args[2].RawString())
+			}
+		case "TJ":
+			if len(args) != 1 {
+				return
+			}
+			for i := 0; i < args[0].Len(); i++ {
+				x := args[0].Index(i)
+				if x.Kind() == pdf.String {
+					show(x.RawString())
+				} else {
+					tm = translate(-x.Float64()/1000*gs.size*gs.scale, 0).mul(tm)
+				}
+			}
+		}
+	})
+	return layout(spans)
+}
+
+// layout joins spans into lines of words.
+// PDF files rarely store the spaces between words, so gaps wider than a fraction of the font size count as one.
+func layout(spans []span) string {
+	var sb strings.Builder
+	var prev *span
+	for i := range spans {
+		s := &spans[i]
+		if s.text == "" {
+			continue
+		}
+		if prev != nil {
+			size := max(s.size, prev.size, 1)
+			gap := s.x - (prev.x + prev.width)
+			switch {
+			case math.Abs(s.y-prev.y) > size/2:
+				sb.WriteString("\n")
+			case gap > size*0.15 || gap < -size:
+				sb.WriteString(" ")
+			}
+		}
+		sb.WriteString(s.text)
+		prev = s
+	}
+	return ligatures.Replace(sb.String())
+}
+
+// ligatures expands the ligature characters of fonts, so that words match what would be typed
+var ligatures = strings.NewReplacer("\uFB00", "ff", "\uFB01", "fi", "\uFB02", "fl", "\uFB03", "ffi", "\uFB04", "ffl", "\uFB05", "st", "\uFB06", "st")
+
+// outlineSections returns the title of the last outline entry pointing at each page that has one
+func outlineSections(pr *pdf.Reader, pageNums map[string]int) map[int]string {
+	sections := make(map[int]string)
+	root := pr.Trailer().Key("Root")
+	seen := make(map[string]bool) // guards against cycles in broken outlines
+
+	var walk func(entry pdf.Value)
+	walk = func(entry pdf.Value) {
+		for ; entry.Kind() == pdf.Dict; entry = entry.Key("Next") {
+			key := entry.String()
+			if seen[key] {
+				return
+			}
+			seen[key] = true
+
+			dest := entry.Key("Dest")
+			if dest.IsNull() {
+				if action := entry.Key("A"); action.Key("S").Name() == "GoTo" {
+					dest = action.Key("D")
+				}
+			}
+			title := strings.TrimSpace(entry.Key("Title").Text())
+			if page, ok := pageNums[destPage(root, dest).String()]; ok && title != "" {
+				sections[page] = title
+			}
+			walk(entry.Key("First"))
+		}
+	}
+	walk(root.Key("Outlines").Key("First"))
+	return sections
+}
+
+// destPage returns the page dictionary a destination points at, resolving named destinations
+func destPage(root, dest pdf.Value) pdf.Value {
+	switch dest.Kind() {
+	case pdf.Name:
+		dest = root.Key("Dests").Key(dest.Name())
+	case pdf.String:
+		dest = lookupName(root.Key("Names").Key("Dests"), dest.RawString())
+	}
+	if dest.Kind() == pdf.Dict {
+		dest = dest.Key("D")
+	}
+	if dest.Kind() != pdf.Array || dest.Len() == 0 {
+		return pdf.Value{}
+	}
+	return dest.Index(0)
+}
+
+// lookupName finds the value of key in a name tree
+func lookupName(node pdf.Value, key string) pdf.Value {
+	names := node.Key("Names")
+	for i := 0; i+1 < names.Len(); i += 2 {
+		if names.Index(i).RawString() == key {
+			return names.Index(i + 1)
+		}
+	}
+	kids := node.Key("Kids")
+	for i := 0; i < kids.Len(); i++ {
+		kid := kids.Index(i)
+		if limits := kid.Key("Limits"); limits.Len() == 2 &&
+			(key < limits.Index(0).RawString() || key > limits.Index(1).RawString()) {
+			continue
+		}
+		if v := lookupName(kid, key); !v.IsNull() {
+			return v
+		}
+	}
+	return pdf.Value{}
+}
+
+// readerAt gives random access to the content of r, reading it into memory unless r has it already
+func readerAt(r io.Reader) (io.ReaderAt, int64, error) {
+	if ra, ok := r.(io.ReaderAt); ok {
+		if s, ok := r.(io.Seeker); ok {
+			size, err := s.Seek(0, io.SeekEnd)
+			if err != nil {
+				return nil, 0, err
+			}
+			return ra, size, nil
+		}
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytes.NewReader(b), int64(len(b)), nil
+}
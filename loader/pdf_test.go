@@ -0,0 +1,133 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// makePDF returns a PDF file of objects, numbered from 1, with a catalog at 1 and the document information at 2
+func makePDF(objects ...string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 2 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func stream(content string) string {
+	return fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content)
+}
+
+// textPage returns the content stream of a page showing lines of text
+func textPage(lines ...string) string {
+	var sb strings.Builder
+	sb.WriteString("BT /F1 12 Tf 14 TL 72 720 Td")
+	for _, line := range lines {
+		fmt.Fprintf(&sb, " (%s) Tj T*", line)
+	}
+	sb.WriteString(" ET")
+	return stream(sb.String())
+}
+
+// book is a PDF of four pages, the third without text, with an outline of two chapters:
+// the first pointing at page 1 directly, the second at page 4 by a named destination
+func book() []byte {
+	const firstPage = 9
+	page := func(n int) string {
+		return fmt.Sprintf("<< /Type /Page /Parent 3 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 4 0 R >> >> /Contents %d 0 R >>", firstPage+4+n-1)
+	}
+	pageRef := func(n int) string { return fmt.Sprintf("%d 0 R", firstPage+n-1) }
+	return makePDF(
+		"<< /Type /Catalog /Pages 3 0 R /Outlines 5 0 R /Names << /Dests 8 0 R >> >>",
+		"<< /Title (The Book) >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s %s %s %s] /Count 4 >>", pageRef(1), pageRef(2), pageRef(3), pageRef(4)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Outlines /First 6 0 R /Last 7 0 R /Count 2 >>",
+		fmt.Sprintf("<< /Title (Chapter 1) /Parent 5 0 R /Next 7 0 R /Dest [%s /Fit] >>", pageRef(1)),
+		"<< /Title (Chapter 2) /Parent 5 0 R /Prev 6 0 R /A << /S /GoTo /D (ch2) >> >>",
+		fmt.Sprintf("<< /Names [(ch2) [%s /XYZ 0 792 0]] >>", pageRef(4)),
+		page(1), page(2), page(3), page(4),
+		textPage("Hello page one", "Second line"),
+		textPage("Still chapter one"),
+		stream(""),
+		textPage("Chapter two text"),
+	)
+}
+
+func TestPDF(t *testing.T) {
+	docs, err := NewPDF().Load(context.Background(), bytes.NewReader(book()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		text    string
+		page    int
+		section string
+	}{
+		{"Hello page one\nSecond line", 1, "Chapter 1"},
+		{"Still chapter one", 2, "Chapter 1"},
+		{"Chapter two text", 4, "Chapter 2"},
+	}
+	if len(docs) != len(want) {
+		t.Fatalf("got %d documents %v, want %d", len(docs), docs, len(want))
+	}
+	for i, w := range want {
+		m := docs[i].Metadata
+		if docs[i].Text != w.text || m[MetaPage] != w.page || m[MetaSection] != w.section || m[MetaTitle] != "The Book" {
+			t.Errorf("document %d = %q %v, want %q on page %d in %s", i, docs[i].Text, m, w.text, w.page, w.section)
+		}
+	}
+
+	// the reader reads through non-seekable readers too
+	docs, err = NewPDF().Load(context.Background(), struct{ *bytes.Buffer }{bytes.NewBuffer(book())})
+	if err != nil || len(docs) != 3 {
+		t.Errorf("got %d documents, %v, want 3", len(docs), err)
+	}
+}
+
+func TestPDFMalformed(t *testing.T) {
+	valid := book()
+	xref := bytes.LastIndex(valid, []byte("xref"))
+	tests := map[string][]byte{
+		"empty":     nil,
+		"garbage":   []byte("garbage"),
+		"header":    []byte("%PDF-1.4\n"),
+		"truncated": valid[:len(valid)/2],
+		"bad xref":  append(append([]byte{}, valid[:xref]...), "xref\n0 2\nnot an xref table\ntrailer\n<< >>\nstartxref\n9\n%%EOF\n"...),
+		"no root":   bytes.Replace(valid, []byte("/Root 1 0 R"), []byte("/Root 99 0 R"), 1),
+	}
+	for name, b := range tests {
+		t.Run(name, func(t *testing.T) {
+			docs, err := NewPDF().Load(context.Background(), bytes.NewReader(b))
+			if err == nil {
+				t.Errorf("got %d documents and no error", len(docs))
+			}
+		})
+	}
+}
+
+func TestLayout(t *testing.T) {
+	spans := []span{
+		{x: 0, y: 700, width: 30, size: 10, text: "Hello"},
+		{x: 30, y: 700, width: 20, size: 10, text: "ish"},   // touching: same word
+		{x: 55, y: 700, width: 20, size: 10, text: "world"}, // gap: new word
+		{x: 0, y: 686, width: 20, size: 10, text: "ﬁne"},    // lower: new line
+		{x: 0, y: 686, width: 0, size: 10, text: ""},
+		{x: -50, y: 687, width: 10, size: 10, text: "back"}, // far left on the same line
+	}
+	if got, want := layout(spans), "Helloish world\nfine back"; got != want {
+		t.Errorf("layout = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,69 @@
+package loader
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+var (
+	_ Loader = &Text{}
+	_ Loader = &Markdown{}
+)
+
+// Text loads a whole file as a single document
+type Text struct{}
+
+func NewText() *Text {
+	return &Text{}
+}
+
+func (t *Text) Load(ctx context.Context, r io.Reader) ([]ragkit.Document, error) {
+	text, err := readText(ctx, r)
+	if err != nil || text == "" {
+		return nil, err
+	}
+	return []ragkit.Document{{Text: text, Metadata: map[string]any{}}}, nil
+}
+
+// Markdown loads a whole file as a single document, titled by its first level 1 heading
+type Markdown struct{}
+
+func NewMarkdown() *Markdown {
+	return &Markdown{}
+}
+
+func (m *Markdown) Load(ctx context.Context, r io.Reader) ([]ragkit.Document, error) {
+	text, err := readText(ctx, r)
+	if err != nil || text == "" {
+		return nil, err
+	}
+
+	metadata := map[string]any{}
+	inCode := false
+	for line := range strings.Lines(text) {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			inCode = !inCode
+		}
+		if !inCode && strings.HasPrefix(line, "# ") {
+			metadata[MetaTitle] = strings.TrimSpace(strings.TrimRight(line[2:], "#"))
+			break
+		}
+	}
+	return []ragkit.Document{{Text: text, Metadata: metadata}}, nil
+}
+
+// readText reads r, dropping a UTF-8 byte order mark and surrounding white space
+func readText(ctx context.Context, r io.Reader) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(strings.TrimPrefix(string(b), "\uFEFF")), nil
+}
@@ -0,0 +1,137 @@
+package ragkit
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+var _ Retriever = &MMRRetriever{}
+
+// Defaults of MMRRetriever
+const (
+	DefaultMMRLambda     = 0.5
+	DefaultMMRCandidates = 20
+)
+
+// MMRRetriever is a Retriever that diversifies results with Maximal Marginal Relevance.
+// It retrieves a larger pool of candidates and picks topK of them one at a time,
+// each maximizing lambda*relevance - (1-lambda)*similarity, where relevance is the Score of the candidate
+// and similarity its highest cosine similarity to the documents already picked.
+// Similarity needs the stored vectors in RetrievedDoc.Vector; candidates without one count as dissimilar.
+type MMRRetriever struct {
+	retriever  Retriever
+	lambda     float32
+	candidates int
+}
+
+// MMROption configures an MMRRetriever
+type MMROption func(*MMRRetriever)
+
+// WithMMRLambda sets the tradeoff between relevance (1) and diversity (0) (default: DefaultMMRLambda)
+func WithMMRLambda(lambda float32) MMROption {
+	return func(m *MMRRetriever) {
+		m.lambda = lambda
+	}
+}
+
+// WithMMRCandidates sets the number of documents retrieved to pick from (default: DefaultMMRCandidates).
+// It is raised to topK when smaller.
+func WithMMRCandidates(n int) MMROption {
+	return func(m *MMRRetriever) {
+		m.candidates = n
+	}
+}
+
+// NewMMRRetriever creates an MMRRetriever diversifying the results of retriever
+func NewMMRRetriever(retriever Retriever, opts ...MMROption) *MMRRetriever {
+	m := &MMRRetriever{
+		retriever:  retriever,
+		lambda:     DefaultMMRLambda,
+		candidates: DefaultMMRCandidates,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Retrieve returns topK diverse documents for the query vector, in the order they were picked
+func (m *MMRRetriever) Retrieve(ctx context.Context, query []float32, topK int, metadataFieldNames ...string) ([]RetrievedDoc, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	if topK <= 0 {
+		return nil, ctx.Err()
+	}
+	candidates, err := m.retriever.Retrieve(ctx, query, max(m.candidates, topK), metadataFieldNames...)
+	if err != nil {
+		return nil, err
+	}
+	return MMR(candidates, topK, m.lambda), nil
+}
+
+// RetrieveText returns topK diverse documents for the text query, in the order they were picked
+func (m *MMRRetriever) RetrieveText(ctx context.Context, text string, topK int, metadataFieldNames ...string) ([]RetrievedDoc, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	if topK <= 0 {
+		return nil, ctx.Err()
+	}
+	candidates, err := m.retriever.RetrieveText(ctx, text, max(m.candidates, topK), metadataFieldNames...)
+	if err != nil {
+		return nil, err
+	}
+	return MMR(candidates, topK, m.lambda), nil
+}
+
+func (m *MMRRetriever) String() string {
+	return fmt.Sprintf("MMRRetriever(lambda: %v, candidates: %d)", m.lambda, m.candidates)
+}
+
+func (m *MMRRetriever) validate() error {
+	if m.lambda < 0 || m.lambda > 1 {
+		return fmt.Errorf("lambda %v out of [0, 1]", m.lambda)
+	}
+	return nil
+}
+
+// MMR picks topK of candidates by Maximal Marginal Relevance, as described by MMRRetriever.
+// The documents are returned in the order they were picked with their Score unchanged.
+func MMR(candidates []RetrievedDoc, topK int, lambda float32) []RetrievedDoc {
+	if topK <= 0 || len(candidates) == 0 {
+		return nil
+	}
+	topK = min(topK, len(candidates))
+
+	picked := make([]RetrievedDoc, 0, topK)
+	used := make([]bool, len(candidates))
+	maxSim := make([]float32, len(candidates)) // highest similarity to the picked documents
+	for len(picked) < topK {
+		best := -1
+		var bestScore float32 = float32(math.Inf(-1))
+		for i, c := range candidates {
+			if used[i] {
+				continue
+			}
+			score := lambda*c.Score - (1-lambda)*maxSim[i]
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best < 0 {
+			break // scores were NaN
+		}
+
+		used[best] = true
+		picked = append(picked, candidates[best])
+		for i, c := range candidates {
+			if used[i] || len(c.Vector) == 0 || len(c.Vector) != len(candidates[best].Vector) {
+				continue
+			}
+			maxSim[i] = max(maxSim[i], CosineSimilarity(c.Vector, candidates[best].Vector))
+		}
+	}
+	return picked
+}
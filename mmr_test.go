@@ -0,0 +1,93 @@
+package ragkit
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMMR(t *testing.T) {
+	candidates := []RetrievedDoc{
+		{ID: "a", Score: 0.9, Vector: []float32{1, 0}},
+		{ID: "a2", Score: 0.85, Vector: []float32{0.99, 0.14}}, // near-duplicate of a
+		{ID: "b", Score: 0.6, Vector: []float32{0, 1}},
+		{ID: "n", Score: 0.5}, // no vector
+	}
+
+	tests := []struct {
+		name   string
+		docs   []RetrievedDoc
+		topK   int
+		lambda float32
+		want   string
+	}{
+		{"near-duplicate suppressed", candidates, 2, 0.5, "a,b"},
+		{"relevance only", candidates, 4, 1, "a,a2,b,n"},
+		{"diversity only", candidates, 3, 0, "a,b,n"},
+		{"no vector is dissimilar", candidates, 3, 0.5, "a,b,n"},
+		{"mismatched dimension is dissimilar", []RetrievedDoc{
+			{ID: "a", Score: 0.9, Vector: []float32{1, 0}},
+			{ID: "a2", Score: 0.85, Vector: []float32{1, 0, 0}},
+			{ID: "b", Score: 0.3, Vector: []float32{0.9, 0.1}},
+		}, 2, 0.5, "a,a2"},
+		{"topK larger than pool", candidates, 10, 0.5, "a,b,n,a2"},
+		{"zero topK", candidates, 0, 0.5, ""},
+		{"no candidates", nil, 3, 0.5, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := MMR(tc.docs, tc.topK, tc.lambda)
+			if docIDs(got) != tc.want {
+				t.Errorf("got %s, want %s", docIDs(got), tc.want)
+			}
+			for _, doc := range got {
+				for _, c := range tc.docs {
+					if c.ID == doc.ID && c.Score != doc.Score {
+						t.Errorf("%s scored %v, want it unchanged at %v", doc.ID, doc.Score, c.Score)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMMRRetriever(t *testing.T) {
+	docs := makeDocs("a", "b", "c", "d", "e")
+
+	tests := []struct {
+		name      string
+		opts      []MMROption
+		topK      int
+		wantFetch int
+		wantLen   int
+	}{
+		{"default candidates", nil, 3, DefaultMMRCandidates, 3},
+		{"candidates", []MMROption{WithMMRCandidates(4)}, 2, 4, 2},
+		{"candidates raised to topK", []MMROption{WithMMRCandidates(2)}, 3, 3, 3},
+		{"topK larger than pool", nil, 10, DefaultMMRCandidates, len(docs)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &stubRetriever{results: map[string][]RetrievedDoc{"q": docs}}
+			got, err := NewMMRRetriever(s, tc.opts...).RetrieveText(context.Background(), "q", tc.topK)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tc.wantLen {
+				t.Errorf("got %d documents, want %d", len(got), tc.wantLen)
+			}
+			if len(s.topKs) != 1 || s.topKs[0] != tc.wantFetch {
+				t.Errorf("fetched %v candidates, want %d", s.topKs, tc.wantFetch)
+			}
+		})
+	}
+
+	for _, lambda := range []float32{-0.1, 1.1} {
+		s := &stubRetriever{results: map[string][]RetrievedDoc{"q": docs}}
+		if _, err := NewMMRRetriever(s, WithMMRLambda(lambda)).RetrieveText(context.Background(), "q", 3); err == nil {
+			t.Errorf("lambda %v accepted", lambda)
+		}
+		if len(s.queries) != 0 {
+			t.Errorf("lambda %v: retriever queried before validation", lambda)
+		}
+	}
+}
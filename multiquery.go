@@ -0,0 +1,170 @@
+package ragkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var _ Retriever = &MultiQueryRetriever{}
+
+// DefaultMultiQueryCount is the number of paraphrases a MultiQueryRetriever generates by default
+const DefaultMultiQueryCount = 3
+
+// DefaultMultiQueryPrompt asks for paraphrases of a question, one per line.
+// It is formatted with the number of paraphrases and the question.
+const DefaultMultiQueryPrompt = `Write %d different versions of the question below, to search a document database for it.
+Word them differently from the question and from each other, and keep its language.
+Reply with one question per line, without numbering or any other text.
+
+Question: %s`
+
+// listMarkerRe matches the marker of a list item, like "- ", "* " or "1. "
+var listMarkerRe = regexp.MustCompile(`^(?:[-*•]|\d+[.)])\s+`)
+
+// MultiQueryRetriever is a Retriever that expands a text query into paraphrases generated by a Generator,
+// retrieves each of them with the wrapped Retriever and merges the results by reciprocal rank fusion,
+// finding documents worded differently from the query.
+// The Score of the results is the fused score, as with FusionRetriever.
+type MultiQueryRetriever struct {
+	retriever  Retriever
+	generator  Generator
+	prompt     string
+	count      int
+	original   bool
+	candidates int
+}
+
+// MultiQueryOption configures a MultiQueryRetriever
+type MultiQueryOption func(*MultiQueryRetriever)
+
+// WithMultiQueryCount sets the number of paraphrases generated (default: DefaultMultiQueryCount)
+func WithMultiQueryCount(n int) MultiQueryOption {
+	return func(m *MultiQueryRetriever) {
+		m.count = n
+	}
+}
+
+// WithMultiQueryPrompt sets the prompt, a format string taking the number of paraphrases and the question
+// (default: DefaultMultiQueryPrompt). The reply must have one paraphrase per line.
+func WithMultiQueryPrompt(prompt string) MultiQueryOption {
+	return func(m *MultiQueryRetriever) {
+		m.prompt = prompt
+	}
+}
+
+// WithoutOriginalQuery retrieves the paraphrases only, not the query itself
+func WithoutOriginalQuery() MultiQueryOption {
+	return func(m *MultiQueryRetriever) {
+		m.original = false
+	}
+}
+
+// WithMultiQueryCandidates sets the number of documents fetched for each query (default: topK)
+func WithMultiQueryCandidates(n int) MultiQueryOption {
+	return func(m *MultiQueryRetriever) {
+		m.candidates = n
+	}
+}
+
+// NewMultiQueryRetriever creates a MultiQueryRetriever paraphrasing queries with generator and retrieving them with retriever
+func NewMultiQueryRetriever(retriever Retriever, generator Generator, opts ...MultiQueryOption) *MultiQueryRetriever {
+	m := &MultiQueryRetriever{
+		retriever: retriever,
+		generator: generator,
+		prompt:    DefaultMultiQueryPrompt,
+		count:     DefaultMultiQueryCount,
+		original:  true,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+func (m *MultiQueryRetriever) String() string {
+	return fmt.Sprintf("MultiQueryRetriever(%s, queries: %d)", m.generator, m.count)
+}
+
+// Retrieve returns the topK documents of the wrapped retriever for the query vector, which can't be paraphrased
+func (m *MultiQueryRetriever) Retrieve(ctx context.Context, query []float32, topK int, metadataFieldNames ...string) ([]RetrievedDoc, error) {
+	return m.retriever.Retrieve(ctx, query, topK, metadataFieldNames...)
+}
+
+// RetrieveText returns the topK documents retrieved for text and its paraphrases, fused.
+// It fails if any query fails.
+func (m *MultiQueryRetriever) RetrieveText(ctx context.Context, text string, topK int, metadataFieldNames ...string) ([]RetrievedDoc, error) {
+	if topK <= 0 {
+		return nil, ctx.Err()
+	}
+	queries, err := m.Queries(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+
+	n := topK
+	if m.candidates > 0 {
+		n = m.candidates
+	}
+
+	lists := make([][]RetrievedDoc, len(queries))
+	errs := make([]error, len(queries))
+	var wg sync.WaitGroup
+	for i, q := range queries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lists[i], errs[i] = m.retriever.RetrieveText(ctx, q, n, metadataFieldNames...)
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("query %q: %w", q, errs[i])
+			}
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	fusion := &FusionRetriever{method: FusionRRF, k: DefaultRRFConstant}
+	return fusion.fuse(lists, topK), nil
+}
+
+// Queries returns the queries retrieved for text: text itself, unless WithoutOriginalQuery is set,
+// and the distinct paraphrases generated for it
+func (m *MultiQueryRetriever) Queries(ctx context.Context, text string) ([]string, error) {
+	if m.count <= 0 {
+		return nil, fmt.Errorf("invalid query count %d", m.count)
+	}
+	reply, err := m.generator.Generate(ctx, Message{Role: RoleUser, Content: fmt.Sprintf(m.prompt, m.count, text)})
+	if err != nil {
+		return nil, fmt.Errorf("paraphrase: %w", err)
+	}
+
+	var queries []string
+	seen := make(map[string]bool)
+	if m.original {
+		queries = append(queries, text)
+		seen[strings.ToLower(strings.TrimSpace(text))] = true
+	}
+	paraphrases := 0
+	for line := range strings.Lines(reply) {
+		q := listMarkerRe.ReplaceAllString(strings.TrimSpace(line), "")
+		q = strings.Trim(q, `"'“”`)
+		key := strings.ToLower(q)
+		if q == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		queries = append(queries, q)
+		if paraphrases++; paraphrases == m.count {
+			break
+		}
+	}
+	if len(queries) == 0 {
+		return nil, errors.New("no paraphrase generated")
+	}
+	return queries, nil
+}
@@ -0,0 +1,121 @@
+package ragkit
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestQueries(t *testing.T) {
+	const question = "Who created Go?"
+	tests := []struct {
+		name  string
+		reply string
+		opts  []MultiQueryOption
+		want  []string
+	}{
+		{
+			"list markers and quotes",
+			"1. What is the origin of Go?\n2) Go language creators\n- \"Who made Go\"\n* “Who designed Golang”\n",
+			nil,
+			[]string{question, "What is the origin of Go?", "Go language creators", "Who made Go"},
+		},
+		{
+			"duplicates and blank lines",
+			"who created go?\n\n  Who made Go  \n• who made go\nWho designed Go\n",
+			[]MultiQueryOption{WithMultiQueryCount(5)},
+			[]string{question, "Who made Go", "Who designed Go"},
+		},
+		{
+			"without original",
+			"Who made Go\nWho designed Go\nWho wrote Go",
+			[]MultiQueryOption{WithoutOriginalQuery(), WithMultiQueryCount(2)},
+			[]string{"Who made Go", "Who designed Go"},
+		},
+		{"no paraphrase", "   \n", nil, []string{question}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMultiQueryRetriever(&stubRetriever{}, replying(tt.reply), tt.opts...)
+			got, err := m.Queries(context.Background(), question)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueriesPrompt(t *testing.T) {
+	g := replying("x")
+	NewMultiQueryRetriever(&stubRetriever{}, g, WithMultiQueryCount(4)).Queries(context.Background(), "Who created Go?")
+	if !strings.HasPrefix(g.prompts[0], "Write 4 different versions") || !strings.HasSuffix(g.prompts[0], "Question: Who created Go?") {
+		t.Errorf("prompt = %q", g.prompts[0])
+	}
+
+	g = replying("x")
+	NewMultiQueryRetriever(&stubRetriever{}, g, WithMultiQueryPrompt("%d|%s")).Queries(context.Background(), "q")
+	if g.prompts[0] != "3|q" {
+		t.Errorf("custom prompt = %q", g.prompts[0])
+	}
+}
+
+func TestQueriesErrors(t *testing.T) {
+	errDown := errors.New("down")
+	tests := map[string]*MultiQueryRetriever{
+		"count 0":    NewMultiQueryRetriever(&stubRetriever{}, replying("x"), WithMultiQueryCount(0)),
+		"nothing":    NewMultiQueryRetriever(&stubRetriever{}, replying("\n"), WithoutOriginalQuery()),
+		"generating": NewMultiQueryRetriever(&stubRetriever{}, &stubGenerator{reply: func(string) (string, error) { return "", errDown }}),
+	}
+	for name, m := range tests {
+		if _, err := m.Queries(context.Background(), "q"); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestMultiQueryRetrieve(t *testing.T) {
+	ctx := context.Background()
+	stub := &stubRetriever{results: map[string][]RetrievedDoc{
+		"q":  makeDocs("a", "b", "c"),
+		"p1": makeDocs("c", "d"),
+		"p2": makeDocs("c", "a"),
+	}}
+	m := NewMultiQueryRetriever(stub, replying("p1\np2"), WithMultiQueryCandidates(5))
+
+	got, err := m.RetrieveText(ctx, "q", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// c is found by every query, a by two, b and d by one at the same rank
+	if docIDs(got) != "c,a,b" {
+		t.Errorf("got %s, want c,a,b", docIDs(got))
+	}
+	if got[0].Score <= got[1].Score || got[0].Score > 1 {
+		t.Errorf("fused scores = %v, %v", got[0].Score, got[1].Score)
+	}
+	slices.Sort(stub.queries)
+	if !slices.Equal(stub.queries, []string{"p1", "p2", "q"}) || !slices.Equal(stub.topKs, []int{5, 5, 5}) {
+		t.Errorf("retrieved %q with topK %v", stub.queries, stub.topKs)
+	}
+
+	// without candidates, every query fetches topK
+	stub.topKs = nil
+	NewMultiQueryRetriever(stub, replying("p1")).RetrieveText(ctx, "q", 2)
+	if !slices.Equal(stub.topKs, []int{2, 2}) {
+		t.Errorf("topKs = %v, want [2 2]", stub.topKs)
+	}
+
+	if got, err := m.RetrieveText(ctx, "q", 0); got != nil || err != nil {
+		t.Errorf("topK 0: got %v, %v", got, err)
+	}
+
+	stub.err = errors.New("down")
+	if _, err := m.RetrieveText(ctx, "q", 3); !errors.Is(err, stub.err) {
+		t.Errorf("got error %v, want %v", err, stub.err)
+	}
+}
@@ -0,0 +1,225 @@
+// Package rag answers questions with a language model from the documents retrieved for them.
+package rag
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+	"text/template"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+// DefaultTopK is the number of documents retrieved for a question by default
+const DefaultTopK = 4
+
+// DefaultSystemPrompt instructs the model to answer from the retrieved documents only
+const DefaultSystemPrompt = `You answer questions using only the provided context.
+If the context doesn't contain the answer, say that you don't know.`
+
+// DefaultTemplate lists the retrieved documents, numbered from 1, before the question
+const DefaultTemplate = `Context:
+{{range .Docs}}
+[{{.N}}] {{.Text}}
+{{end}}
+Question: {{.Question}}`
+
+var defaultTemplate = template.Must(template.New("prompt").Parse(DefaultTemplate))
+
+// PromptData is the data the prompt template is executed with
+type PromptData struct {
+	Question string
+	Docs     []PromptDoc
+}
+
+// PromptDoc is a retrieved document numbered for the prompt
+type PromptDoc struct {
+	N int // Position of the document, from 1
+	ragkit.RetrievedDoc
+}
+
+// Answer is a type that represents the answer of a Chain
+type Answer struct {
+	Text    string                // Reply of the model
+	Sources []ragkit.RetrievedDoc // Documents given to the model, in prompt order
+
+	// In citation mode (see WithCitations)
+	Citations  []Citation // Citation markers of the reply, in order
+	Uncited    bool       // The reply cites no document
+	OutOfRange []int      // Cited numbers matching no document, in order of first citation
+}
+
+// Chunk is a type that represents a piece of a streamed answer
+type Chunk struct {
+	Sources []ragkit.RetrievedDoc // Documents given to the model, set in the first chunk only
+	Text    string                // Piece of the reply, empty in the first chunk
+}
+
+// Chain retrieves the documents relevant to a question and asks a Generator to answer it from them
+type Chain struct {
+	retriever          ragkit.Retriever
+	generator          ragkit.Generator
+	system             string
+	template           *template.Template
+	topK               int
+	metadataFieldNames []string
+	citations          bool
+}
+
+// Option configures a Chain
+type Option func(*Chain)
+
+// WithTopK sets the number of documents retrieved for a question (default: DefaultTopK)
+func WithTopK(k int) Option {
+	return func(c *Chain) {
+		c.topK = k
+	}
+}
+
+// WithSystemPrompt sets the system message of the chat (default: DefaultSystemPrompt).
+// An empty prompt sends no system message.
+func WithSystemPrompt(prompt string) Option {
+	return func(c *Chain) {
+		c.system = prompt
+	}
+}
+
+// WithTemplate sets the template of the user message, executed with a PromptData (default: DefaultTemplate)
+func WithTemplate(t *template.Template) Option {
+	return func(c *Chain) {
+		c.template = t
+	}
+}
+
+// WithMetadataFields sets the metadata fields retrieved with the documents, available to the template
+func WithMetadataFields(names ...string) Option {
+	return func(c *Chain) {
+		c.metadataFieldNames = names
+	}
+}
+
+// WithCitations asks the model to cite the numbers of the documents supporting each sentence,
+// appending CitationInstruction to the system prompt, and parses the citations of the answers.
+// The template must number the documents by PromptDoc.N, as DefaultTemplate does.
+func WithCitations() Option {
+	return func(c *Chain) {
+		c.citations = true
+	}
+}
+
+func New(retriever ragkit.Retriever, generator ragkit.Generator, opts ...Option) *Chain {
+	c := &Chain{
+		retriever: retriever,
+		generator: generator,
+		system:    DefaultSystemPrompt,
+		template:  defaultTemplate,
+		topK:      DefaultTopK,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *Chain) String() string {
+	return fmt.Sprintf("Chain(%s, topK: %d)", c.generator, c.topK)
+}
+
+// Answer retrieves the documents for question and generates the answer from them
+func (c *Chain) Answer(ctx context.Context, question string) (*Answer, error) {
+	docs, messages, err := c.prepare(ctx, question)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := c.generator.Generate(ctx, messages...)
+	if err != nil {
+		return nil, fmt.Errorf("generate: %w", err)
+	}
+	answer := &Answer{Text: strings.TrimSpace(reply), Sources: docs}
+	if c.citations {
+		answer.Citations = ParseCitations(answer.Text, docs)
+		answer.Uncited = !slices.ContainsFunc(answer.Citations, func(c Citation) bool { return c.Doc != nil })
+		for _, citation := range answer.Citations {
+			if citation.Doc == nil && !slices.Contains(answer.OutOfRange, citation.N) {
+				answer.OutOfRange = append(answer.OutOfRange, citation.N)
+			}
+		}
+	}
+	return answer, nil
+}
+
+// Stream retrieves the documents for question and streams the answer generated from them:
+// a first Chunk with the sources, then the pieces of the reply as they are generated.
+// In citation mode, the citations of the whole reply can be parsed by ParseCitations.
+// A Generator that isn't a ragkit.StreamingGenerator sends the reply in a single piece.
+// The sequence ends after the first error. Stopping the iteration cancels the generation.
+func (c *Chain) Stream(ctx context.Context, question string) iter.Seq2[Chunk, error] {
+	return func(yield func(Chunk, error) bool) {
+		docs, messages, err := c.prepare(ctx, question)
+		if err != nil {
+			yield(Chunk{}, err)
+			return
+		}
+		if !yield(Chunk{Sources: docs}, nil) {
+			return
+		}
+
+		sg, ok := c.generator.(ragkit.StreamingGenerator)
+		if !ok {
+			reply, err := c.generator.Generate(ctx, messages...)
+			if err != nil {
+				yield(Chunk{}, fmt.Errorf("generate: %w", err))
+				return
+			}
+			yield(Chunk{Text: reply}, nil)
+			return
+		}
+		for piece, err := range sg.GenerateStream(ctx, messages...) {
+			if err != nil {
+				yield(Chunk{}, fmt.Errorf("generate: %w", err))
+				return
+			}
+			if !yield(Chunk{Text: piece}, nil) {
+				return
+			}
+		}
+	}
+}
+
+// prepare retrieves the documents for question and makes the chat asking for the answer
+func (c *Chain) prepare(ctx context.Context, question string) ([]ragkit.RetrievedDoc, []ragkit.Message, error) {
+	docs, err := c.retriever.RetrieveText(ctx, question, c.topK, c.metadataFieldNames...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("retrieve: %w", err)
+	}
+	messages, err := c.Messages(question, docs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return docs, messages, nil
+}
+
+// Messages returns the chat asking the model to answer question from docs
+func (c *Chain) Messages(question string, docs []ragkit.RetrievedDoc) ([]ragkit.Message, error) {
+	data := PromptData{Question: question, Docs: make([]PromptDoc, len(docs))}
+	for i, doc := range docs {
+		data.Docs[i] = PromptDoc{N: i + 1, RetrievedDoc: doc}
+	}
+
+	var sb strings.Builder
+	if err := c.template.Execute(&sb, data); err != nil {
+		return nil, fmt.Errorf("prompt: %w", err)
+	}
+
+	system := c.system
+	if c.citations {
+		system = strings.TrimSpace(system + "\n\n" + CitationInstruction)
+	}
+	var messages []ragkit.Message
+	if system != "" {
+		messages = append(messages, ragkit.Message{Role: ragkit.RoleSystem, Content: system})
+	}
+	return append(messages, ragkit.Message{Role: ragkit.RoleUser, Content: sb.String()}), nil
+}
@@ -0,0 +1,272 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"slices"
+	"strings"
+	"testing"
+	"text/template"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+// stubRetriever returns docs for any text query, recording its arguments
+type stubRetriever struct {
+	docs []ragkit.RetrievedDoc
+	err  error
+
+	text   string
+	topK   int
+	fields []string
+}
+
+func (s *stubRetriever) Retrieve(ctx context.Context, query []float32, topK int, metadataFieldNames ...string) ([]ragkit.RetrievedDoc, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *stubRetriever) RetrieveText(ctx context.Context, text string, topK int, metadataFieldNames ...string) ([]ragkit.RetrievedDoc, error) {
+	s.text, s.topK, s.fields = text, topK, metadataFieldNames
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.docs[:min(topK, len(s.docs))], nil
+}
+
+// stubGenerator replies with reply, recording the messages it got
+type stubGenerator struct {
+	reply string
+	err   error
+
+	messages []ragkit.Message
+}
+
+func (g *stubGenerator) Generate(ctx context.Context, messages ...ragkit.Message) (string, error) {
+	g.messages = messages
+	return g.reply, g.err
+}
+
+func (g *stubGenerator) String() string { return "stub" }
+
+// streamGenerator streams pieces, then err if any, recording whether the iteration stopped early
+type streamGenerator struct {
+	stubGenerator
+	pieces []string
+
+	stopped bool
+}
+
+func (g *streamGenerator) GenerateStream(ctx context.Context, messages ...ragkit.Message) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		g.messages = messages
+		for _, piece := range g.pieces {
+			if !yield(piece, nil) {
+				g.stopped = true
+				return
+			}
+		}
+		if g.err != nil {
+			yield("", g.err)
+		}
+	}
+}
+
+var docs = []ragkit.RetrievedDoc{
+	{ID: "go", Text: "Go was designed at Google.", Metadata: map[string]any{"source": "go.md", "page": 3}},
+	{ID: "rust", Text: "Rust was designed at Mozilla.", Metadata: map[string]any{"source": "rust.md"}},
+	{ID: "zig", Text: "Zig was designed by Andrew Kelley."},
+}
+
+func TestMessages(t *testing.T) {
+	c := New(&stubRetriever{}, &stubGenerator{})
+	messages, err := c.Messages("Who designed Go?", docs[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ragkit.Message{
+		{Role: ragkit.RoleSystem, Content: DefaultSystemPrompt},
+		{Role: ragkit.RoleUser, Content: "Context:\n\n[1] Go was designed at Google.\n\n[2] Rust was designed at Mozilla.\n\nQuestion: Who designed Go?"},
+	}
+	if !slices.Equal(messages, want) {
+		t.Errorf("got %q, want %q", messages, want)
+	}
+
+	// no documents
+	messages, _ = c.Messages("Who designed Go?", nil)
+	if got := messages[1].Content; got != "Context:\n\nQuestion: Who designed Go?" {
+		t.Errorf("without documents: got %q", got)
+	}
+}
+
+func TestMessagesOptions(t *testing.T) {
+	tmpl := template.Must(template.New("t").Parse(`{{range .Docs}}{{.N}}:{{.ID}}:{{index .Metadata "source"}};{{end}} {{.Question}}`))
+	c := New(&stubRetriever{}, &stubGenerator{}, WithSystemPrompt(""), WithTemplate(tmpl))
+	messages, err := c.Messages("q", docs[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ragkit.Message{{Role: ragkit.RoleUser, Content: "1:go:go.md;2:rust:rust.md; q"}}
+	if !slices.Equal(messages, want) {
+		t.Errorf("got %q, want %q", messages, want)
+	}
+
+	// citation mode adds its instruction, even without a system prompt
+	for _, system := range []string{"", "Be brief."} {
+		c := New(&stubRetriever{}, &stubGenerator{}, WithSystemPrompt(system), WithCitations())
+		messages, _ := c.Messages("q", docs)
+		if want := strings.TrimSpace(system + "\n\n" + CitationInstruction); messages[0].Role != ragkit.RoleSystem || messages[0].Content != want {
+			t.Errorf("system %q: got %q, want %q", system, messages[0].Content, want)
+		}
+	}
+
+	bad := template.Must(template.New("bad").Parse(`{{.Missing}}`))
+	if _, err := New(&stubRetriever{}, &stubGenerator{}, WithTemplate(bad)).Messages("q", docs); err == nil {
+		t.Error("failing template: no error")
+	}
+}
+
+func TestAnswer(t *testing.T) {
+	r := &stubRetriever{docs: docs}
+	g := &stubGenerator{reply: "  Google designed Go.\n"}
+	c := New(r, g, WithTopK(2), WithMetadataFields("source"))
+
+	answer, err := c.Answer(context.Background(), "Who designed Go?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer.Text != "Google designed Go." {
+		t.Errorf("text = %q", answer.Text)
+	}
+	if len(answer.Sources) != 2 || answer.Sources[0].ID != "go" {
+		t.Errorf("sources = %v", answer.Sources)
+	}
+	if answer.Citations != nil || answer.Uncited || answer.OutOfRange != nil {
+		t.Errorf("citations outside citation mode: %+v", answer)
+	}
+	if r.text != "Who designed Go?" || r.topK != 2 || !slices.Equal(r.fields, []string{"source"}) {
+		t.Errorf("retrieved %q, %d, %q", r.text, r.topK, r.fields)
+	}
+	want, _ := c.Messages("Who designed Go?", docs[:2])
+	if !slices.Equal(g.messages, want) {
+		t.Errorf("generated from %q, want %q", g.messages, want)
+	}
+}
+
+func TestAnswerErrors(t *testing.T) {
+	errDown := errors.New("down")
+	if _, err := New(&stubRetriever{err: errDown}, &stubGenerator{}).Answer(context.Background(), "q"); !errors.Is(err, errDown) || !strings.HasPrefix(err.Error(), "retrieve: ") {
+		t.Errorf("retriever failing: got %v", err)
+	}
+	if _, err := New(&stubRetriever{docs: docs}, &stubGenerator{err: errDown}).Answer(context.Background(), "q"); !errors.Is(err, errDown) || !strings.HasPrefix(err.Error(), "generate: ") {
+		t.Errorf("generator failing: got %v", err)
+	}
+}
+
+// collect returns the chunks of a stream up to its first error
+func collect(stream iter.Seq2[Chunk, error]) ([]Chunk, error) {
+	var chunks []Chunk
+	for chunk, err := range stream {
+		if err != nil {
+			return chunks, err
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
+
+func TestStream(t *testing.T) {
+	g := &streamGenerator{pieces: []string{"Google", " designed", " Go [1]."}}
+	c := New(&stubRetriever{docs: docs}, g, WithTopK(2), WithCitations())
+	chunks, err := collect(c.Stream(context.Background(), "Who designed Go?"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 4 || len(chunks[0].Sources) != 2 || chunks[0].Text != "" {
+		t.Fatalf("got %+v, want the sources then 3 pieces", chunks)
+	}
+
+	var sb strings.Builder
+	for _, chunk := range chunks[1:] {
+		if chunk.Sources != nil {
+			t.Errorf("sources in a later chunk: %+v", chunk)
+		}
+		sb.WriteString(chunk.Text)
+	}
+	if sb.String() != "Google designed Go [1]." {
+		t.Errorf("accumulated %q", sb.String())
+	}
+	if citations := ParseCitations(sb.String(), chunks[0].Sources); len(citations) != 1 || citations[0].ID() != "go" {
+		t.Errorf("citations = %+v", citations)
+	}
+	want, _ := c.Messages("Who designed Go?", docs[:2])
+	if !slices.Equal(g.messages, want) {
+		t.Errorf("generated from %q, want %q", g.messages, want)
+	}
+}
+
+func TestStreamNotStreaming(t *testing.T) {
+	c := New(&stubRetriever{docs: docs}, &stubGenerator{reply: "whole reply"})
+	chunks, err := collect(c.Stream(context.Background(), "q"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 2 || len(chunks[0].Sources) != len(docs) || chunks[1].Text != "whole reply" {
+		t.Errorf("got %+v, want the sources then the whole reply", chunks)
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+	errDown := errors.New("down")
+
+	// retrieval fails before any chunk
+	chunks, err := collect(New(&stubRetriever{err: errDown}, &streamGenerator{}).Stream(context.Background(), "q"))
+	if len(chunks) != 0 || !errors.Is(err, errDown) {
+		t.Errorf("retriever failing: got %+v, %v", chunks, err)
+	}
+
+	// generation fails mid-stream, after the pieces already sent
+	g := &streamGenerator{pieces: []string{"par", "tial"}, stubGenerator: stubGenerator{err: errDown}}
+	var n int
+	for chunk, err := range New(&stubRetriever{docs: docs}, g).Stream(context.Background(), "q") {
+		if err != nil {
+			if n != 3 || !errors.Is(err, errDown) || !strings.HasPrefix(err.Error(), "generate: ") {
+				t.Errorf("got error %v after %d chunks, want generate: down after 3", err, n)
+			}
+			if chunk.Text != "" || chunk.Sources != nil {
+				t.Errorf("error chunk = %+v, want zero", chunk)
+			}
+			break
+		}
+		n++
+	}
+
+	chunks, err = collect(New(&stubRetriever{docs: docs}, &stubGenerator{err: errDown}).Stream(context.Background(), "q"))
+	if len(chunks) != 1 || !errors.Is(err, errDown) {
+		t.Errorf("non-streaming generator failing: got %d chunks, %v", len(chunks), err)
+	}
+}
+
+func TestStreamStop(t *testing.T) {
+	g := &streamGenerator{pieces: []string{"a", "b", "c"}}
+	for chunk, err := range New(&stubRetriever{docs: docs}, g).Stream(context.Background(), "q") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if chunk.Text == "a" {
+			break
+		}
+	}
+	if !g.stopped {
+		t.Error("generation not stopped with the iteration")
+	}
+
+	// stopping after the sources doesn't generate
+	g = &streamGenerator{pieces: []string{"a"}}
+	for range New(&stubRetriever{docs: docs}, g).Stream(context.Background(), "q") {
+		break
+	}
+	if g.messages != nil {
+		t.Error("generated after the iteration stopped")
+	}
+}
@@ -0,0 +1,142 @@
+package rag
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	ragkit "github.com/suapapa/go_ragkit"
+	"github.com/suapapa/go_ragkit/splitter"
+)
+
+// CitationInstruction is appended to the system prompt in citation mode (see WithCitations)
+const CitationInstruction = `Cite the numbers of the context documents supporting each sentence in brackets at its end, like [1] or [2][3].
+Only cite documents of the context.`
+
+// Citation is a type that represents a document cited by a [n] marker of an answer
+type Citation struct {
+	N     int                  // Number of the cited document, from 1
+	Start int                  // Byte offset of the marker in the answer
+	End   int                  // Byte offset after the marker
+	Doc   *ragkit.RetrievedDoc // Cited document, nil if N is out of range
+}
+
+// ID returns the ID of the cited document, or "" if out of range
+func (c Citation) ID() string {
+	if c.Doc == nil {
+		return ""
+	}
+	return c.Doc.ID
+}
+
+// Source returns the source path of the cited document (ragkit.MetaSource), if any
+func (c Citation) Source() string {
+	if c.Doc == nil {
+		return ""
+	}
+	s, _ := c.Doc.Metadata[ragkit.MetaSource].(string)
+	return s
+}
+
+// Page returns the page of the cited document (ragkit.MetaPage), or 0 if none
+func (c Citation) Page() int {
+	if c.Doc == nil {
+		return 0
+	}
+	// the type depends on how the vector store kept the metadata
+	switch page := c.Doc.Metadata[ragkit.MetaPage].(type) {
+	case int:
+		return page
+	case int64:
+		return int(page)
+	case float64:
+		return int(page)
+	case string:
+		n, _ := strconv.Atoi(page)
+		return n
+	}
+	return 0
+}
+
+// Sentence is a type that represents a sentence of an answer with the citations supporting it
+type Sentence struct {
+	Text      string
+	Start     int // Byte offset of the sentence in the answer
+	End       int
+	Citations []Citation
+}
+
+// markerRe matches citation markers like [1], [1, 2] and [1-3]
+var markerRe = regexp.MustCompile(`\[\s*\d+(?:\s*[,\-–]\s*\d+)*\s*\]`)
+
+// ParseCitations returns the citations of the [n] markers of text, in order,
+// resolving n against sources numbered from 1 as in the prompt.
+// A marker citing several documents, like [1, 2] or [1-3], makes a citation for each.
+func ParseCitations(text string, sources []ragkit.RetrievedDoc) []Citation {
+	var citations []Citation
+	for _, loc := range markerRe.FindAllStringIndex(text, -1) {
+		add := func(n int) {
+			c := Citation{N: n, Start: loc[0], End: loc[1]}
+			if n >= 1 && n <= len(sources) {
+				c.Doc = &sources[n-1]
+			}
+			citations = append(citations, c)
+		}
+
+		inner := text[loc[0]+1 : loc[1]-1]
+		for _, part := range strings.Split(inner, ",") {
+			lo, hi, isRange := strings.Cut(strings.ReplaceAll(part, "–", "-"), "-")
+			from, _ := strconv.Atoi(strings.TrimSpace(lo))
+			if !isRange {
+				add(from)
+				continue
+			}
+			to, _ := strconv.Atoi(strings.TrimSpace(hi))
+			if to < from || to-from > len(sources) {
+				add(from) // not a range of documents
+				add(to)
+				continue
+			}
+			for n := from; n <= to; n++ {
+				add(n)
+			}
+		}
+	}
+	return citations
+}
+
+// Cited returns the documents cited by the answer, in order of first citation
+func (a *Answer) Cited() []ragkit.RetrievedDoc {
+	var docs []ragkit.RetrievedDoc
+	seen := make(map[int]bool)
+	for _, c := range a.Citations {
+		if c.Doc != nil && !seen[c.N] {
+			seen[c.N] = true
+			docs = append(docs, *c.Doc)
+		}
+	}
+	return docs
+}
+
+// Sentences returns the sentences of the answer with the citations supporting them.
+// Markers following the end of a sentence, as in "Go is fun. [1] It is fast.", belong to it.
+func (a *Answer) Sentences() []Sentence {
+	// split the text with markers blanked out, so that they read as the space after a sentence
+	masked := []byte(a.Text)
+	for _, c := range a.Citations {
+		for i := c.Start; i < c.End; i++ {
+			masked[i] = ' '
+		}
+	}
+
+	var sentences []Sentence
+	i := 0
+	for _, chunk := range splitter.Sentences(string(masked)) {
+		s := Sentence{Text: a.Text[chunk.Start:chunk.End], Start: chunk.Start, End: chunk.End}
+		for ; i < len(a.Citations) && a.Citations[i].Start < chunk.End; i++ {
+			s.Citations = append(s.Citations, a.Citations[i])
+		}
+		sentences = append(sentences, s)
+	}
+	return sentences
+}
@@ -0,0 +1,121 @@
+package rag
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+func TestParseCitations(t *testing.T) {
+	tests := []struct {
+		text string
+		want []int // cited numbers, in order
+	}{
+		{"No citation.", nil},
+		{"Go [1].", []int{1}},
+		{"Go [1][2] and again [1].", []int{1, 2, 1}},
+		{"Go [1, 3].", []int{1, 3}},
+		{"Go [1-3].", []int{1, 2, 3}},
+		{"Go [ 2 – 3 ].", []int{2, 3}},
+		{"Go [3-1].", []int{3, 1}},            // reversed: not a range
+		{"Go [1-2000].", []int{1, 2000}},      // wider than the documents: not a range
+		{"Go [0] and [4].", []int{0, 4}},      // out of range
+		{"Go [a] [1.5] [] [-1].", []int{}},    // not markers
+		{"Go [1,2-3, 5].", []int{1, 2, 3, 5}}, // mixed
+	}
+	for _, tt := range tests {
+		citations := ParseCitations(tt.text, docs)
+		got := []int{}
+		for _, c := range citations {
+			got = append(got, c.N)
+			marker := tt.text[c.Start:c.End]
+			if marker[0] != '[' || marker[len(marker)-1] != ']' {
+				t.Errorf("%q: citation %d at [%d:%d] isn't a marker: %q", tt.text, c.N, c.Start, c.End, marker)
+			}
+			inRange := c.N >= 1 && c.N <= len(docs)
+			if inRange != (c.Doc != nil) || inRange && c.Doc.ID != docs[c.N-1].ID {
+				t.Errorf("%q: citation %d resolves to %v", tt.text, c.N, c.Doc)
+			}
+		}
+		if tt.want == nil {
+			tt.want = []int{}
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%q: cited %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestAnswerCitations(t *testing.T) {
+	ctx := context.Background()
+	g := &stubGenerator{reply: "Rust is older [2][5]. Go came later [1]. [2] Both are compiled [5][7][1]."}
+	answer, err := New(&stubRetriever{docs: docs}, g, WithCitations()).Answer(ctx, "q")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if answer.Uncited {
+		t.Error("Uncited = true")
+	}
+	if !slices.Equal(answer.OutOfRange, []int{5, 7}) {
+		t.Errorf("OutOfRange = %v, want [5 7]", answer.OutOfRange)
+	}
+	var cited []string
+	for _, doc := range answer.Cited() {
+		cited = append(cited, doc.ID)
+	}
+	if !slices.Equal(cited, []string{"rust", "go"}) {
+		t.Errorf("Cited = %q, want rust, go", cited)
+	}
+
+	// the marker after "later." belongs to its sentence
+	var sentences [][]int
+	for _, s := range answer.Sentences() {
+		var ns []int
+		for _, c := range s.Citations {
+			ns = append(ns, c.N)
+		}
+		sentences = append(sentences, ns)
+		if answer.Text[s.Start:s.End] != s.Text {
+			t.Errorf("sentence %q at wrong offsets", s.Text)
+		}
+	}
+	if want := [][]int{{2, 5}, {1, 2}, {5, 7, 1}}; !slices.EqualFunc(sentences, want, slices.Equal) {
+		t.Errorf("citations by sentence = %v, want %v", sentences, want)
+	}
+
+	// only out-of-range citations
+	g.reply = "Nothing supports this [9][9]."
+	answer, _ = New(&stubRetriever{docs: docs}, g, WithCitations()).Answer(ctx, "q")
+	if !answer.Uncited || !slices.Equal(answer.OutOfRange, []int{9}) || len(answer.Cited()) != 0 {
+		t.Errorf("got Uncited %v, OutOfRange %v, Cited %v", answer.Uncited, answer.OutOfRange, answer.Cited())
+	}
+}
+
+func TestCitationMetadata(t *testing.T) {
+	tests := []struct {
+		page any
+		want int
+	}{
+		{3, 3},
+		{int64(4), 4},
+		{5.0, 5},
+		{"6", 6},
+		{"six", 0},
+		{nil, 0},
+	}
+	for _, tt := range tests {
+		doc := ragkit.RetrievedDoc{Metadata: map[string]any{ragkit.MetaSource: "a.pdf", ragkit.MetaPage: tt.page}}
+		c := Citation{N: 1, Doc: &doc}
+		if c.Page() != tt.want || c.Source() != "a.pdf" {
+			t.Errorf("page %#v: got %d from %s, want %d", tt.page, c.Page(), c.Source(), tt.want)
+		}
+	}
+
+	var out Citation
+	if out.ID() != "" || out.Source() != "" || out.Page() != 0 {
+		t.Error("out-of-range citation has a document")
+	}
+}
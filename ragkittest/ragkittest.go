@@ -0,0 +1,531 @@
+// Package ragkittest provides a conformance test suite for ragkit.VectorStore implementations.
+//
+// Call RunVectorStoreSuite from a test of the store's package:
+//
+//	func TestConformance(t *testing.T) {
+//		ragkittest.RunVectorStoreSuite(t, func(t *testing.T) ragkit.VectorStore {
+//			return memory.New(fake.New(64))
+//		})
+//	}
+package ragkittest
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+// Factory returns a new, empty VectorStore with an embedder for a single test.
+// Stores needing teardown should register it with t.Cleanup.
+type Factory func(t *testing.T) ragkit.VectorStore
+
+// Corpus returns the documents indexed by the suite.
+// IDs are UUIDs so that stores requiring them, like Weaviate, can be tested.
+func Corpus() []ragkit.Document {
+	docs := []ragkit.Document{
+		{Text: "The quick brown fox jumps over the lazy dog.", Metadata: map[string]any{"source": "animals.txt", "page": 1, "tags": []any{"animals", "english"}}},
+		{Text: "Go is an open source programming language that makes it simple to build software.", Metadata: map[string]any{"source": "go.md", "page": 2, "tags": []any{"code", "english"}}},
+		{Text: "Seoul is the capital and largest city of South Korea.", Metadata: map[string]any{"source": "geo.txt", "page": 3, "tags": []any{"geo"}}},
+		{Text: "Photosynthesis converts light energy into chemical energy in plants.", Metadata: map[string]any{"source": "bio.txt", "page": 4}},
+		{Text: "The Pythagorean theorem relates the three sides of a right triangle.", Metadata: map[string]any{"source": "math.txt", "page": 5}},
+	}
+	for i := range docs {
+		docs[i].ID = ragkit.GenerateID(docs[i].Text, docs[i].Metadata)
+	}
+	return docs
+}
+
+// RunVectorStoreSuite checks that stores created by factory follow the semantics of the ragkit interfaces:
+//
+//   - Index returns the IDs of the documents in order, generating missing ones with ragkit.GenerateID
+//   - Index fails with ragkit.ErrDocumentExists for an ID already indexed and keeps the stored document
+//   - Delete removes a document; deleting a missing one is not an error
+//   - Retrieve and RetrieveText return at most topK (none for topK <= 0) documents, most similar first,
+//     with ID, Text, stored Vector, a Score in [0, 1] and the requested metadata fields
+//   - every operation fails with a canceled context
+//   - FilteredRetriever implementations apply metadata filters
+//   - Upserter implementations insert new documents and replace existing ones
+//   - HybridRetriever implementations rank keyword matches first at alpha 0 and vector matches at alpha 1
+func RunVectorStoreSuite(t *testing.T, factory Factory) {
+	t.Run("Index", func(t *testing.T) { testIndex(t, factory(t)) })
+	t.Run("IndexGeneratesID", func(t *testing.T) { testIndexGeneratesID(t, factory(t)) })
+	t.Run("IndexEmpty", func(t *testing.T) { testIndexEmpty(t, factory(t)) })
+	t.Run("IndexDuplicateID", func(t *testing.T) { testIndexDuplicateID(t, factory(t)) })
+	t.Run("IndexPartialFailure", func(t *testing.T) { testIndexPartialFailure(t, factory(t)) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, factory(t)) })
+	t.Run("RetrieveText", func(t *testing.T) { testRetrieveText(t, factory(t)) })
+	t.Run("RetrieveReturnsStoredVector", func(t *testing.T) { testRetrieveReturnsStoredVector(t, factory(t)) })
+	t.Run("RetrieveEmptyStore", func(t *testing.T) { testRetrieveEmptyStore(t, factory(t)) })
+	t.Run("TopK", func(t *testing.T) { testTopK(t, factory(t)) })
+	t.Run("Metadata", func(t *testing.T) { testMetadata(t, factory(t)) })
+	t.Run("ContextCanceled", func(t *testing.T) { testContextCanceled(t, factory(t)) })
+	t.Run("Filter", func(t *testing.T) { testFilter(t, factory(t)) })
+	t.Run("Upsert", func(t *testing.T) { testUpsert(t, factory(t)) })
+	t.Run("Hybrid", func(t *testing.T) { testHybrid(t, factory(t)) })
+}
+
+func testIndex(t *testing.T, store ragkit.VectorStore) {
+	ctx := context.Background()
+	docs := Corpus()
+
+	ids, err := store.Index(ctx, docs...)
+	if err != nil {
+		t.Fatalf("Index: %v", err)
+	}
+	if len(ids) != len(docs) {
+		t.Fatalf("Index returned %d IDs, want %d", len(ids), len(docs))
+	}
+	for i, doc := range docs {
+		if ids[i] != doc.ID {
+			t.Errorf("Index returned ID %q at %d, want %q", ids[i], i, doc.ID)
+		}
+		exists, err := store.Exists(ctx, doc.ID)
+		if err != nil {
+			t.Fatalf("Exists: %v", err)
+		}
+		if !exists {
+			t.Errorf("Exists(%q) = false after Index", doc.ID)
+		}
+	}
+
+	exists, err := store.Exists(ctx, ragkit.GenerateID("missing", nil))
+	if err != nil {
+		t.Fatalf("Exists: %v", err)
+	}
+	if exists {
+		t.Errorf("Exists = true for a document never indexed")
+	}
+}
+
+func testIndexGeneratesID(t *testing.T, store ragkit.VectorStore) {
+	ctx := context.Background()
+	doc := Corpus()[0]
+	doc.ID = ""
+
+	ids, err := store.Index(ctx, doc)
+	if err != nil {
+		t.Fatalf("Index: %v", err)
+	}
+	want := ragkit.GenerateID(doc.Text, doc.Metadata)
+	if len(ids) != 1 || ids[0] != want {
+		t.Fatalf("Index returned %v, want [%s]", ids, want)
+	}
+}
+
+func testIndexEmpty(t *testing.T, store ragkit.VectorStore) {
+	ids, err := store.Index(context.Background())
+	if err != nil {
+		t.Fatalf("Index without documents: %v", err)
+	}
+	if len(ids) != 0 {
+		t.Fatalf("Index without documents returned %v", ids)
+	}
+}
+
+func testIndexDuplicateID(t *testing.T, store ragkit.VectorStore) {
+	ctx := context.Background()
+	doc := Corpus()[0]
+	mustIndex(t, store, doc)
+
+	dup := doc
+	dup.Text = "A replacement text that must not be stored."
+	_, err := store.Index(ctx, dup)
+	if !errors.Is(err, ragkit.ErrDocumentExists) {
+		t.Fatalf("Index of an existing ID returned %v, want ragkit.ErrDocumentExists", err)
+	}
+
+	results, err := store.RetrieveText(ctx, doc.Text, 1)
+	if err != nil {
+		t.Fatalf("RetrieveText: %v", err)
+	}
+	if len(results) != 1 || results[0].Text != doc.Text {
+		t.Fatalf("stored document changed after a failed Index: %+v", results)
+	}
+}
+
+func testIndexPartialFailure(t *testing.T, store ragkit.VectorStore) {
+	ctx := context.Background()
+	docs := Corpus()
+	mustIndex(t, store, docs[1])
+
+	ids, err := store.Index(ctx, docs[0], docs[1], docs[2])
+	if !slices.Equal(ids, []string{docs[0].ID, docs[2].ID}) {
+		t.Errorf("Index returned %v, want [%s %s]", ids, docs[0].ID, docs[2].ID)
+	}
+	var indexErr *ragkit.IndexError
+	if !errors.As(err, &indexErr) {
+		t.Fatalf("Index returned %v, want a *ragkit.IndexError", err)
+	}
+	if len(indexErr.Failed) != 1 || indexErr.Failed[0].Index != 1 || indexErr.Failed[0].ID != docs[1].ID {
+		t.Errorf("Index reported failures %+v, want document 1 only", indexErr.Failed)
+	}
+	if !errors.Is(err, ragkit.ErrDocumentExists) {
+		t.Errorf("Index returned %v, want ragkit.ErrDocumentExists", err)
+	}
+
+	for _, doc := range []ragkit.Document{docs[0], docs[2]} {
+		exists, err := store.Exists(ctx, doc.ID)
+		if err != nil {
+			t.Fatalf("Exists: %v", err)
+		}
+		if !exists {
+			t.Errorf("Exists(%q) = false after a partially failed Index", doc.ID)
+		}
+	}
+}
+
+func testDelete(t *testing.T, store ragkit.VectorStore) {
+	ctx := context.Background()
+	docs := Corpus()
+	mustIndex(t, store, docs...)
+
+	if err := store.Delete(ctx, docs[0].ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	exists, err := store.Exists(ctx, docs[0].ID)
+	if err != nil {
+		t.Fatalf("Exists: %v", err)
+	}
+	if exists {
+		t.Errorf("Exists = true after Delete")
+	}
+
+	results, err := store.RetrieveText(ctx, docs[0].Text, len(docs))
+	if err != nil {
+		t.Fatalf("RetrieveText: %v", err)
+	}
+	if len(results) != len(docs)-1 {
+		t.Errorf("RetrieveText returned %d documents after Delete, want %d", len(results), len(docs)-1)
+	}
+	for _, r := range results {
+		if r.ID == docs[0].ID {
+			t.Errorf("RetrieveText returned deleted document %s", r.ID)
+		}
+	}
+
+	if err := store.Delete(ctx, docs[0].ID); err != nil {
+		t.Errorf("Delete of a missing document: %v", err)
+	}
+}
+
+func testRetrieveText(t *testing.T, store ragkit.VectorStore) {
+	ctx := context.Background()
+	docs := Corpus()
+	mustIndex(t, store, docs...)
+
+	for _, doc := range docs {
+		results, err := store.RetrieveText(ctx, doc.Text, len(docs))
+		if err != nil {
+			t.Fatalf("RetrieveText: %v", err)
+		}
+		if len(results) != len(docs) {
+			t.Fatalf("RetrieveText returned %d documents, want %d", len(results), len(docs))
+		}
+		if results[0].ID != doc.ID || results[0].Text != doc.Text {
+			t.Errorf("RetrieveText(%q) ranked %q first", doc.Text, results[0].Text)
+		}
+		checkResults(t, results)
+	}
+}
+
+func testRetrieveReturnsStoredVector(t *testing.T, store ragkit.VectorStore) {
+	ctx := context.Background()
+	docs := Corpus()
+	mustIndex(t, store, docs...)
+
+	results, err := store.RetrieveText(ctx, docs[0].Text, 1)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("RetrieveText: %v, %d results", err, len(results))
+	}
+	stored := results[0].Vector
+
+	// query with a perturbed vector; the result must carry the stored one, not the query
+	query := slices.Clone(stored)
+	query[0] += 0.01
+	results, err = store.Retrieve(ctx, query, 1)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("Retrieve: %v, %d results", err, len(results))
+	}
+	if results[0].ID != docs[0].ID {
+		t.Fatalf("Retrieve ranked %q first, want %q", results[0].Text, docs[0].Text)
+	}
+	if !vectorsEqual(results[0].Vector, stored) {
+		t.Errorf("Retrieve returned a vector other than the stored one")
+	}
+}
+
+func testRetrieveEmptyStore(t *testing.T, store ragkit.VectorStore) {
+	results, err := store.RetrieveText(context.Background(), "anything", 3)
+	if err != nil {
+		t.Fatalf("RetrieveText on an empty store: %v", err)
+	}
+	if len(results) != 0 {
+		t.Fatalf("RetrieveText on an empty store returned %d documents", len(results))
+	}
+}
+
+func testTopK(t *testing.T, store ragkit.VectorStore) {
+	ctx := context.Background()
+	docs := Corpus()
+	mustIndex(t, store, docs...)
+
+	for _, tc := range []struct {
+		topK int
+		want int
+	}{
+		{-1, 0},
+		{0, 0},
+		{1, 1},
+		{len(docs) - 1, len(docs) - 1},
+		{len(docs), len(docs)},
+		{len(docs) + 10, len(docs)},
+	} {
+		results, err := store.RetrieveText(ctx, docs[0].Text, tc.topK)
+		if err != nil {
+			t.Fatalf("RetrieveText with topK %d: %v", tc.topK, err)
+		}
+		if len(results) != tc.want {
+			t.Errorf("RetrieveText with topK %d returned %d documents, want %d", tc.topK, len(results), tc.want)
+		}
+	}
+}
+
+func testMetadata(t *testing.T, store ragkit.VectorStore) {
+	ctx := context.Background()
+	docs := Corpus()
+	mustIndex(t, store, docs...)
+
+	results, err := store.RetrieveText(ctx, docs[2].Text, 1, "source", "page")
+	if err != nil || len(results) != 1 {
+		t.Fatalf("RetrieveText: %v, %d results", err, len(results))
+	}
+	for _, key := range []string{"source", "page"} {
+		got, err1 := ragkit.NormalizeFilterValue(results[0].Metadata[key])
+		want, err2 := ragkit.NormalizeFilterValue(docs[2].Metadata[key])
+		if err1 != nil || err2 != nil || got != want {
+			t.Errorf("metadata %q = %v, want %v", key, results[0].Metadata[key], docs[2].Metadata[key])
+		}
+	}
+}
+
+func testContextCanceled(t *testing.T, store ragkit.VectorStore) {
+	docs := Corpus()
+	mustIndex(t, store, docs[0])
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := store.Index(ctx, docs[1]); err == nil {
+		t.Errorf("Index with a canceled context succeeded")
+	}
+	if _, err := store.Exists(ctx, docs[0].ID); err == nil {
+		t.Errorf("Exists with a canceled context succeeded")
+	}
+	if err := store.Delete(ctx, docs[0].ID); err == nil {
+		t.Errorf("Delete with a canceled context succeeded")
+	}
+	if _, err := store.RetrieveText(ctx, docs[0].Text, 1); err == nil {
+		t.Errorf("RetrieveText with a canceled context succeeded")
+	}
+
+	exists, err := store.Exists(context.Background(), docs[0].ID)
+	if err != nil || !exists {
+		t.Errorf("document missing after a canceled Delete: %v", err)
+	}
+}
+
+func testFilter(t *testing.T, store ragkit.VectorStore) {
+	fr, ok := store.(ragkit.FilteredRetriever)
+	if !ok {
+		t.Skip("store doesn't implement ragkit.FilteredRetriever")
+	}
+
+	ctx := context.Background()
+	docs := Corpus()
+	mustIndex(t, store, docs...)
+
+	for _, tc := range []struct {
+		name   string
+		filter *ragkit.Filter
+		want   []int // indexes into docs
+	}{
+		{"eq", ragkit.Eq("source", "go.md"), []int{1}},
+		{"neq", ragkit.Neq("source", "go.md"), []int{0, 2, 3, 4}},
+		{"in", ragkit.In("source", "go.md", "geo.txt"), []int{1, 2}},
+		{"range", ragkit.Range("page", 2, 3), []int{1, 2}},
+		{"open range", ragkit.Range("page", 4, nil), []int{3, 4}},
+		{"exists", ragkit.Exists("source"), []int{0, 1, 2, 3, 4}},
+		{"and", ragkit.And(ragkit.Range("page", 1, 3), ragkit.Neq("source", "animals.txt")), []int{1, 2}},
+		{"or", ragkit.Or(ragkit.Eq("page", 1), ragkit.Eq("page", 5)), []int{0, 4}},
+		{"not", ragkit.Not(ragkit.Range("page", 2, 5)), []int{0}},
+		// list values match by containing the operand
+		{"eq list", ragkit.Eq("tags", "english"), []int{0, 1}},
+		{"eq single element list", ragkit.Eq("tags", "geo"), []int{2}},
+		{"neq list", ragkit.Neq("tags", "english"), []int{2, 3, 4}},
+		{"in list", ragkit.In("tags", "code", "geo"), []int{1, 2}},
+	} {
+		results, err := fr.RetrieveTextFiltered(ctx, docs[0].Text, len(docs), tc.filter)
+		if err != nil {
+			t.Errorf("%s: RetrieveTextFiltered: %v", tc.name, err)
+			continue
+		}
+		var got []string
+		for _, r := range results {
+			got = append(got, r.ID)
+		}
+		var want []string
+		for _, i := range tc.want {
+			want = append(want, docs[i].ID)
+		}
+		slices.Sort(got)
+		slices.Sort(want)
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, want)
+		}
+	}
+
+	if _, err := fr.RetrieveTextFiltered(ctx, docs[0].Text, 1, &ragkit.Filter{Op: "bogus"}); err == nil {
+		t.Errorf("invalid filter accepted")
+	}
+}
+
+func testUpsert(t *testing.T, store ragkit.VectorStore) {
+	upserter, ok := store.(ragkit.Upserter)
+	if !ok {
+		t.Skip("store doesn't implement ragkit.Upserter")
+	}
+
+	ctx := context.Background()
+	docs := Corpus()
+	mustIndex(t, store, docs[0])
+
+	replaced := docs[0]
+	replaced.Text = "The lazy dog sleeps all day while the fox runs away."
+	replaced.Metadata = map[string]any{"source": "replaced.txt", "page": 9}
+
+	result, err := upserter.Upsert(ctx, replaced, docs[1])
+	if err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	if !slices.Equal(result.Inserted, []string{docs[1].ID}) {
+		t.Errorf("Upsert inserted %v, want [%s]", result.Inserted, docs[1].ID)
+	}
+	if !slices.Equal(result.Updated, []string{docs[0].ID}) {
+		t.Errorf("Upsert updated %v, want [%s]", result.Updated, docs[0].ID)
+	}
+
+	results, err := store.RetrieveText(ctx, replaced.Text, 1, "source")
+	if err != nil || len(results) != 1 {
+		t.Fatalf("RetrieveText: %v, %d results", err, len(results))
+	}
+	if results[0].ID != docs[0].ID || results[0].Text != replaced.Text || results[0].Metadata["source"] != "replaced.txt" {
+		t.Errorf("Upsert didn't replace the document: %+v", results[0])
+	}
+
+	results, err = store.RetrieveText(ctx, docs[0].Text, len(docs))
+	if err != nil {
+		t.Fatalf("RetrieveText: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("RetrieveText returned %d documents after Upsert, want 2", len(results))
+	}
+}
+
+func testHybrid(t *testing.T, store ragkit.VectorStore) {
+	hr, ok := store.(ragkit.HybridRetriever)
+	if !ok {
+		t.Skip("store doesn't implement ragkit.HybridRetriever")
+	}
+
+	ctx := context.Background()
+	docs := Corpus()
+	mustIndex(t, store, docs...)
+
+	for _, tc := range []struct {
+		name   string
+		text   string
+		alpha  float32
+		filter *ragkit.Filter
+		want   int // index into docs of the first result
+	}{
+		{"keyword", "Pythagorean", 0, nil, 4},
+		{"vector", docs[2].Text, 1, nil, 2},
+		{"fused", "Seoul capital city", 0.5, nil, 2},
+		{"filtered", "Seoul fox", 0, ragkit.Eq("source", "animals.txt"), 0},
+	} {
+		results, err := hr.RetrieveHybrid(ctx, tc.text, 3, tc.alpha, tc.filter)
+		if err != nil {
+			t.Errorf("%s: RetrieveHybrid: %v", tc.name, err)
+			continue
+		}
+		if len(results) == 0 {
+			t.Errorf("%s: RetrieveHybrid(%q) returned no documents", tc.name, tc.text)
+			continue
+		}
+		if results[0].ID != docs[tc.want].ID {
+			t.Errorf("%s: RetrieveHybrid(%q) ranked %q first, want %q", tc.name, tc.text, results[0].Text, docs[tc.want].Text)
+		}
+		if tc.filter != nil && len(results) != 1 {
+			t.Errorf("%s: RetrieveHybrid returned %d documents, want 1", tc.name, len(results))
+		}
+		for i, r := range results {
+			if r.Score < 0 || r.Score > 1 || (i > 0 && r.Score > results[i-1].Score) {
+				t.Errorf("%s: result %d has score %v out of order or of [0, 1]", tc.name, i, r.Score)
+			}
+		}
+	}
+
+	if results, err := hr.RetrieveHybrid(ctx, "fox", 0, 0.5, nil); err != nil || len(results) != 0 {
+		t.Errorf("RetrieveHybrid with topK 0 returned %d documents, %v", len(results), err)
+	}
+	if _, err := hr.RetrieveHybrid(ctx, "fox", 3, 1.5, nil); err == nil {
+		t.Errorf("RetrieveHybrid accepted alpha 1.5")
+	}
+}
+
+func mustIndex(t *testing.T, store ragkit.VectorStore, docs ...ragkit.Document) {
+	t.Helper()
+
+	if _, err := store.Index(context.Background(), docs...); err != nil {
+		t.Fatalf("Index: %v", err)
+	}
+}
+
+// checkResults verifies the fields every retrieved document must carry
+func checkResults(t *testing.T, results []ragkit.RetrievedDoc) {
+	t.Helper()
+
+	for i, r := range results {
+		if r.ID == "" {
+			t.Errorf("result %d has no ID", i)
+		}
+		if r.Metric == "" {
+			t.Errorf("result %d has no metric", i)
+		}
+		if len(r.Vector) == 0 {
+			t.Errorf("result %d has no vector", i)
+		}
+		if r.Score < 0 || r.Score > 1 || math.IsNaN(float64(r.Score)) {
+			t.Errorf("result %d has score %v out of [0, 1]", i, r.Score)
+		}
+		if i > 0 && r.Score > results[i-1].Score {
+			t.Errorf("result %d scores higher than result %d", i, i-1)
+		}
+	}
+}
+
+func vectorsEqual(a, b []float32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(float64(a[i]-b[i])) > 1e-5 {
+			return false
+		}
+	}
+	return true
+}
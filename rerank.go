@@ -0,0 +1,100 @@
+package ragkit
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+)
+
+var _ Retriever = &RerankingRetriever{}
+
+// DefaultRerankCandidates is the number of documents retrieved for reranking by default
+const DefaultRerankCandidates = 50
+
+// RerankingRetriever is a Retriever that retrieves a larger pool of candidates
+// and keeps the topK best of them according to a Reranker
+type RerankingRetriever struct {
+	retriever  Retriever
+	reranker   Reranker
+	candidates int
+}
+
+// RerankOption configures a RerankingRetriever
+type RerankOption func(*RerankingRetriever)
+
+// WithRerankCandidates sets the number of documents retrieved for reranking (default: DefaultRerankCandidates).
+// It is raised to topK when smaller.
+func WithRerankCandidates(n int) RerankOption {
+	return func(r *RerankingRetriever) {
+		r.candidates = n
+	}
+}
+
+// NewRerankingRetriever creates a RerankingRetriever reranking the results of retriever with reranker
+func NewRerankingRetriever(retriever Retriever, reranker Reranker, opts ...RerankOption) *RerankingRetriever {
+	r := &RerankingRetriever{
+		retriever:  retriever,
+		reranker:   reranker,
+		candidates: DefaultRerankCandidates,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Retrieve has no query text to rerank with, so it returns the results of the wrapped retriever
+func (r *RerankingRetriever) Retrieve(ctx context.Context, query []float32, topK int, metadataFieldNames ...string) ([]RetrievedDoc, error) {
+	return r.retriever.Retrieve(ctx, query, topK, metadataFieldNames...)
+}
+
+// RetrieveText retrieves the candidates for text and returns the topK most relevant by the reranker
+func (r *RerankingRetriever) RetrieveText(ctx context.Context, text string, topK int, metadataFieldNames ...string) ([]RetrievedDoc, error) {
+	if topK <= 0 {
+		return nil, ctx.Err()
+	}
+
+	candidates, err := r.retriever.RetrieveText(ctx, text, max(r.candidates, topK), metadataFieldNames...)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	return r.reranker.Rerank(ctx, text, candidates, topK)
+}
+
+func (r *RerankingRetriever) String() string {
+	return fmt.Sprintf("RerankingRetriever(reranker: %s, candidates: %d)", r.reranker, r.candidates)
+}
+
+// RankByRelevance returns copies of the topN docs with the highest relevance, most relevant first,
+// with Score set to their relevance clamped to [0, 1]. relevance[i] is the relevance of docs[i].
+// Ties keep the order of docs. It helps Reranker implementations.
+func RankByRelevance(docs []RetrievedDoc, relevance []float32, topN int) ([]RetrievedDoc, error) {
+	if len(relevance) != len(docs) {
+		return nil, fmt.Errorf("%d relevance scores for %d documents", len(relevance), len(docs))
+	}
+	if topN <= 0 {
+		return nil, nil
+	}
+
+	order := make([]int, len(docs))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(relevance[b], relevance[a])
+	})
+	if len(order) > topN {
+		order = order[:topN]
+	}
+
+	ranked := make([]RetrievedDoc, len(order))
+	for j, i := range order {
+		ranked[j] = docs[i]
+		ranked[j].Score = clamp01(relevance[i])
+	}
+	return ranked, nil
+}
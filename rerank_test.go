@@ -0,0 +1,147 @@
+package ragkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// stubRetriever returns results[text] for a text query, and records the queries
+type stubRetriever struct {
+	results map[string][]RetrievedDoc
+	err     error
+
+	mu      sync.Mutex
+	queries []string
+	topKs   []int
+}
+
+func (s *stubRetriever) Retrieve(ctx context.Context, query []float32, topK int, metadataFieldNames ...string) ([]RetrievedDoc, error) {
+	return s.RetrieveText(ctx, fmt.Sprint(query), topK, metadataFieldNames...)
+}
+
+func (s *stubRetriever) RetrieveText(ctx context.Context, text string, topK int, metadataFieldNames ...string) ([]RetrievedDoc, error) {
+	s.mu.Lock()
+	s.queries = append(s.queries, text)
+	s.topKs = append(s.topKs, topK)
+	s.mu.Unlock()
+
+	if s.err != nil {
+		return nil, s.err
+	}
+	results := s.results[text]
+	return slices.Clone(results[:min(topK, len(results))]), nil
+}
+
+// docIDs returns the IDs of docs joined by commas
+func docIDs(docs []RetrievedDoc) string {
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+// makeDocs returns documents of the given IDs, scored in decreasing order
+func makeDocs(ids ...string) []RetrievedDoc {
+	docs := make([]RetrievedDoc, len(ids))
+	for i, id := range ids {
+		docs[i] = RetrievedDoc{ID: id, Text: "text of " + id, Score: 1 - float32(i)/float32(len(ids))}
+	}
+	return docs
+}
+
+// reverseReranker ranks documents in reverse order
+type reverseReranker struct {
+	query string
+	n     int
+}
+
+func (r *reverseReranker) Rerank(ctx context.Context, query string, docs []RetrievedDoc, topN int) ([]RetrievedDoc, error) {
+	r.query, r.n = query, len(docs)
+	relevance := make([]float32, len(docs))
+	for i := range docs {
+		relevance[i] = float32(i) / float32(len(docs))
+	}
+	return RankByRelevance(docs, relevance, topN)
+}
+
+func (r *reverseReranker) String() string { return "reverse" }
+
+func TestRankByRelevance(t *testing.T) {
+	docs := makeDocs("a", "b", "c", "d")
+	tests := []struct {
+		name       string
+		relevance  []float32
+		topN       int
+		want       string
+		wantScores []float32
+	}{
+		{"sorted", []float32{0.1, 0.9, 0.5, 0.3}, 4, "b,c,d,a", []float32{0.9, 0.5, 0.3, 0.1}},
+		{"topN", []float32{0.1, 0.9, 0.5, 0.3}, 2, "b,c", []float32{0.9, 0.5}},
+		{"topN above len", []float32{0.1, 0.9, 0.5, 0.3}, 10, "b,c,d,a", nil},
+		{"ties keep order", []float32{0.5, 0.5, 0.7, 0.5}, 4, "c,a,b,d", nil},
+		{"clamped", []float32{1.5, -1, 0.5, 0}, 4, "a,c,d,b", []float32{1, 0.5, 0, 0}},
+		{"topN 0", []float32{0, 0, 0, 0}, 0, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranked, err := RankByRelevance(docs, tt.relevance, tt.topN)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := docIDs(ranked); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+			for i, score := range tt.wantScores {
+				if ranked[i].Score != score {
+					t.Errorf("ranked[%d].Score = %v, want %v", i, ranked[i].Score, score)
+				}
+			}
+		})
+	}
+
+	if _, err := RankByRelevance(docs, []float32{1}, 1); err == nil {
+		t.Error("relevance of another length: no error")
+	}
+	if docs[0].Score != 1 {
+		t.Error("RankByRelevance modified docs")
+	}
+}
+
+func TestRerankingRetriever(t *testing.T) {
+	ctx := context.Background()
+	stub := &stubRetriever{results: map[string][]RetrievedDoc{"q": makeDocs("a", "b", "c", "d", "e")}}
+	reranker := &reverseReranker{}
+	r := NewRerankingRetriever(stub, reranker, WithRerankCandidates(4))
+
+	got, err := r.RetrieveText(ctx, "q", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if docIDs(got) != "d,c" {
+		t.Errorf("got %s, want d,c", docIDs(got))
+	}
+	if reranker.query != "q" || reranker.n != 4 || stub.topKs[0] != 4 {
+		t.Errorf("reranked %d candidates for %q, retrieved %d, want 4 for q", reranker.n, reranker.query, stub.topKs[0])
+	}
+
+	// candidates are raised to topK
+	if _, err := r.RetrieveText(ctx, "q", 5); err != nil || stub.topKs[1] != 5 {
+		t.Errorf("retrieved %d candidates for topK 5 (err %v), want 5", stub.topKs[1], err)
+	}
+
+	reranker.n = -1
+	if got, err := r.RetrieveText(ctx, "nothing", 2); got != nil || err != nil || reranker.n != -1 {
+		t.Errorf("no candidates: got %v, %v, reranked: %v", got, err, reranker.n != -1)
+	}
+
+	stub.err = errors.New("down")
+	if _, err := r.RetrieveText(ctx, "q", 2); !errors.Is(err, stub.err) {
+		t.Errorf("got error %v, want %v", err, stub.err)
+	}
+}
@@ -0,0 +1,202 @@
+// Package endpoint provides a ragkit.Reranker calling an HTTP rerank endpoint,
+// like Cohere's /v2/rerank (also served by Jina and vLLM) or Hugging Face
+// Text Embeddings Inference's /rerank.
+package endpoint
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+var _ ragkit.Reranker = &Endpoint{}
+
+// Format is a type that selects the request and response bodies of the endpoint
+type Format string
+
+const (
+	// FormatCohere sends {"model", "query", "documents", "top_n"} and reads
+	// {"results": [{"index", "relevance_score"}]} (default)
+	FormatCohere Format = "cohere"
+	// FormatTEI sends {"query", "texts"} and reads [{"index", "score"}]
+	FormatTEI Format = "tei"
+)
+
+type Endpoint struct {
+	url    string
+	client *http.Client
+	format Format
+	model  string
+	apiKey string
+}
+
+// Option configures an Endpoint
+type Option func(*Endpoint)
+
+// WithHTTPClient sets the client sending the requests (default: http.DefaultClient)
+func WithHTTPClient(client *http.Client) Option {
+	return func(e *Endpoint) {
+		e.client = client
+	}
+}
+
+// WithFormat sets the format of the endpoint (default: FormatCohere)
+func WithFormat(format Format) Option {
+	return func(e *Endpoint) {
+		e.format = format
+	}
+}
+
+// WithModel sets the model named in the requests, required by Cohere
+func WithModel(model string) Option {
+	return func(e *Endpoint) {
+		e.model = model
+	}
+}
+
+// WithAPIKey sets the key sent as a bearer token
+func WithAPIKey(key string) Option {
+	return func(e *Endpoint) {
+		e.apiKey = key
+	}
+}
+
+// New creates a reranker posting to url, the full URL of the rerank endpoint
+func New(url string, opts ...Option) *Endpoint {
+	e := &Endpoint{
+		url:    url,
+		client: http.DefaultClient,
+		format: FormatCohere,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+func (e *Endpoint) Rerank(ctx context.Context, query string, docs []ragkit.RetrievedDoc, topN int) ([]ragkit.RetrievedDoc, error) {
+	if len(docs) == 0 || topN <= 0 {
+		return nil, ctx.Err()
+	}
+
+	texts := make([]string, len(docs))
+	for i, doc := range docs {
+		texts[i] = doc.Text
+	}
+
+	var body any
+	switch e.format {
+	case FormatCohere:
+		body = cohereRequest{Model: e.model, Query: query, Documents: texts, TopN: min(topN, len(docs))}
+	case FormatTEI:
+		body = teiRequest{Query: query, Texts: texts}
+	default:
+		return nil, fmt.Errorf("unknown rerank format: %q", e.format)
+	}
+
+	results, err := e.post(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// documents left out of a top_n response rank last
+	relevance := make([]float32, len(docs))
+	for i := range relevance {
+		relevance[i] = -1
+	}
+	for _, r := range results {
+		if r.Index < 0 || r.Index >= len(docs) {
+			return nil, fmt.Errorf("rerank result index %d out of range", r.Index)
+		}
+		relevance[r.Index] = r.score()
+	}
+	return ragkit.RankByRelevance(docs, relevance, topN)
+}
+
+func (e *Endpoint) String() string {
+	if e.model != "" {
+		return fmt.Sprintf("Endpoint(url: %s, model: %s)", e.url, e.model)
+	}
+	return fmt.Sprintf("Endpoint(url: %s)", e.url)
+}
+
+type cohereRequest struct {
+	Model     string   `json:"model,omitempty"`
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+	TopN      int      `json:"top_n,omitempty"`
+}
+
+type teiRequest struct {
+	Query string   `json:"query"`
+	Texts []string `json:"texts"`
+}
+
+// result is a reranked document in either format
+type result struct {
+	Index          int      `json:"index"`
+	RelevanceScore *float32 `json:"relevance_score"` // Cohere
+	Score          *float32 `json:"score"`           // TEI
+}
+
+func (r result) score() float32 {
+	if r.RelevanceScore != nil {
+		return *r.RelevanceScore
+	}
+	if r.Score != nil {
+		return *r.Score
+	}
+	return 0
+}
+
+// post sends body and decodes the results of either format
+func (e *Endpoint) post(ctx context.Context, body any) ([]result, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rerank: %s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+
+	data = bytes.TrimSpace(data)
+	var results []result
+	if len(data) > 0 && data[0] == '[' {
+		err = json.Unmarshal(data, &results)
+	} else {
+		var wrapped struct {
+			Results []result `json:"results"`
+		}
+		err = json.Unmarshal(data, &wrapped)
+		results = wrapped.Results
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode rerank response: %w", err)
+	}
+	return results, nil
+}
@@ -0,0 +1,143 @@
+package endpoint
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+var docs = []ragkit.RetrievedDoc{
+	{ID: "a", Text: "apples", Score: 0.9},
+	{ID: "b", Text: "bananas", Score: 0.8},
+	{ID: "c", Text: "cherries", Score: 0.7},
+}
+
+// serve returns the URL of a server recording the request body in got and replying with status and response
+func serve(t *testing.T, got *map[string]any, status int, response string) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if got != nil {
+			if err := json.Unmarshal(b, got); err != nil {
+				t.Errorf("request body: %v", err)
+			}
+			(*got)["authorization"] = r.Header.Get("Authorization")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, response)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func ids(docs []ragkit.RetrievedDoc) string {
+	var ids []string
+	for _, doc := range docs {
+		ids = append(ids, doc.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestRerankCohere(t *testing.T) {
+	var req map[string]any
+	url := serve(t, &req, http.StatusOK,
+		`{"id": "x", "results": [{"index": 2, "relevance_score": 0.95}, {"index": 0, "relevance_score": 0.5}]}`)
+	e := New(url, WithModel("rerank-v3.5"), WithAPIKey("secret"))
+
+	ranked, err := e.Rerank(context.Background(), "red fruit", docs, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids(ranked) != "c,a" || ranked[0].Score != 0.95 || ranked[1].Score != 0.5 {
+		t.Errorf("got %v, want c (0.95) then a (0.5)", ranked)
+	}
+
+	if req["model"] != "rerank-v3.5" || req["query"] != "red fruit" || req["top_n"] != 2.0 {
+		t.Errorf("request %v, want model, query and top_n 2", req)
+	}
+	if documents, _ := req["documents"].([]any); len(documents) != 3 || documents[1] != "bananas" {
+		t.Errorf("documents = %v, want the texts of docs", req["documents"])
+	}
+	if req["authorization"] != "Bearer secret" {
+		t.Errorf("Authorization = %q, want the bearer token", req["authorization"])
+	}
+}
+
+func TestRerankTEI(t *testing.T) {
+	var req map[string]any
+	url := serve(t, &req, http.StatusOK,
+		`[{"index": 1, "score": 0.8}, {"index": 2, "score": 0.3}, {"index": 0, "score": 0.1}]`)
+	e := New(url, WithFormat(FormatTEI))
+
+	ranked, err := e.Rerank(context.Background(), "yellow fruit", docs, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids(ranked) != "b,c" || ranked[0].Score != 0.8 {
+		t.Errorf("got %v, want b (0.8) then c", ranked)
+	}
+
+	if _, ok := req["top_n"]; ok {
+		t.Errorf("TEI request has top_n: %v", req)
+	}
+	if req["query"] != "yellow fruit" || len(req["texts"].([]any)) != 3 {
+		t.Errorf("request %v, want query and texts", req)
+	}
+	if req["authorization"] != "" {
+		t.Errorf("Authorization = %q, want none without a key", req["authorization"])
+	}
+}
+
+func TestRerankOmittedResultsRankLast(t *testing.T) {
+	// an endpoint ignoring top_n, or returning fewer results
+	url := serve(t, nil, http.StatusOK, `{"results": [{"index": 1, "relevance_score": 0.4}]}`)
+	ranked, err := New(url).Rerank(context.Background(), "q", docs, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids(ranked) != "b,a,c" || ranked[1].Score != 0 || ranked[2].Score != 0 {
+		t.Errorf("got %v, want b then a and c scored 0 in their order", ranked)
+	}
+}
+
+func TestRerankErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		opts     []Option
+		want     string
+	}{
+		{"index out of range", http.StatusOK, `{"results": [{"index": 3, "relevance_score": 0.4}]}`, nil, "out of range"},
+		{"negative index", http.StatusOK, `[{"index": -1, "score": 0.4}]`, []Option{WithFormat(FormatTEI)}, "out of range"},
+		{"status", http.StatusUnauthorized, `{"message": "invalid api token"}`, nil, "401"},
+		{"malformed", http.StatusOK, `{"results": "none"}`, nil, "decode"},
+		{"unknown format", http.StatusOK, `[]`, []Option{WithFormat("jina")}, "unknown rerank format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := New(serve(t, nil, tt.status, tt.response), tt.opts...)
+			_, err := e.Rerank(context.Background(), "q", docs, 2)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRerankNothing(t *testing.T) {
+	e := New("http://127.0.0.1:0") // never called
+	if ranked, err := e.Rerank(context.Background(), "q", nil, 3); ranked != nil || err != nil {
+		t.Errorf("no docs: got %v, %v", ranked, err)
+	}
+	if ranked, err := e.Rerank(context.Background(), "q", docs, 0); ranked != nil || err != nil {
+		t.Errorf("topN 0: got %v, %v", ranked, err)
+	}
+}
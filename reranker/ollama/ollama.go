@@ -0,0 +1,139 @@
+// Package ollama provides a ragkit.Reranker prompting a model hosted by Ollama
+// to grade the relevance of every document to the query.
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	ollama_api "github.com/ollama/ollama/api"
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+var _ ragkit.Reranker = &Ollama{}
+
+// DefaultPrompt asks for the relevance of a document as an integer from 0 to 10.
+// It is formatted with the query and the document text.
+const DefaultPrompt = `Rate how relevant the document is to the query, from 0 (unrelated) to 10 (answers it fully).
+
+Query: %s
+
+Document: %s
+
+Reply with JSON like {"score": 7}.`
+
+// format constrains the reply to {"score": <integer>} with Ollama's structured outputs
+var format = json.RawMessage(`{"type":"object","properties":{"score":{"type":"integer","minimum":0,"maximum":10}},"required":["score"]}`)
+
+type Ollama struct {
+	client      *ollama_api.Client
+	model       string
+	prompt      string
+	concurrency int
+}
+
+// Option configures an Ollama reranker
+type Option func(*Ollama)
+
+// WithPrompt sets the prompt, a format string taking the query and the document text (default: DefaultPrompt).
+// The reply must be JSON like {"score": 7} with a score from 0 to 10.
+func WithPrompt(prompt string) Option {
+	return func(o *Ollama) {
+		o.prompt = prompt
+	}
+}
+
+// WithConcurrency sets the number of documents graded at once (default: 4)
+func WithConcurrency(n int) Option {
+	return func(o *Ollama) {
+		o.concurrency = n
+	}
+}
+
+func New(client *ollama_api.Client, model string, opts ...Option) *Ollama {
+	o := &Ollama{
+		client:      client,
+		model:       model,
+		prompt:      DefaultPrompt,
+		concurrency: 4,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Rerank grades every document with a separate request
+func (o *Ollama) Rerank(ctx context.Context, query string, docs []ragkit.RetrievedDoc, topN int) ([]ragkit.RetrievedDoc, error) {
+	if len(docs) == 0 || topN <= 0 {
+		return nil, ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	relevance := make([]float32, len(docs))
+	var firstErr error
+	var once sync.Once
+	sem := make(chan struct{}, max(o.concurrency, 1))
+	var wg sync.WaitGroup
+	for i, doc := range docs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			score, err := o.grade(ctx, query, doc.Text)
+			if err != nil {
+				once.Do(func() {
+					firstErr = fmt.Errorf("document %s: %w", doc.ID, err)
+					cancel() // fail fast
+				})
+				return
+			}
+			relevance[i] = float32(score) / 10
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return ragkit.RankByRelevance(docs, relevance, topN)
+}
+
+// grade returns the relevance of text to query from 0 to 10
+func (o *Ollama) grade(ctx context.Context, query, text string) (int, error) {
+	stream := false
+	req := &ollama_api.GenerateRequest{
+		Model:   o.model,
+		Prompt:  fmt.Sprintf(o.prompt, query, text),
+		Stream:  &stream,
+		Format:  format,
+		Options: map[string]any{"temperature": 0},
+	}
+
+	var reply string
+	err := o.client.Generate(ctx, req, func(resp ollama_api.GenerateResponse) error {
+		reply += resp.Response
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var grade struct {
+		Score *int `json:"score"`
+	}
+	if err := json.Unmarshal([]byte(reply), &grade); err != nil || grade.Score == nil {
+		return 0, fmt.Errorf("unexpected reply: %q", reply)
+	}
+	return min(max(*grade.Score, 0), 10), nil
+}
+
+func (o *Ollama) String() string {
+	return fmt.Sprintf("Ollama(model: %s)", o.model)
+}
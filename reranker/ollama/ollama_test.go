@@ -0,0 +1,74 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	ollama_api "github.com/ollama/ollama/api"
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+// newTestReranker returns a reranker whose model replies replies[text] to the prompt for a document text
+func newTestReranker(t *testing.T, replies map[string]string, opts ...Option) *Ollama {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollama_api.GenerateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		var reply string
+		for text, r := range replies {
+			if strings.Contains(req.Prompt, "Document: "+text) {
+				reply = r
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ollama_api.GenerateResponse{Model: req.Model, Response: reply, Done: true})
+	}))
+	t.Cleanup(srv.Close)
+
+	u, _ := url.Parse(srv.URL)
+	return New(ollama_api.NewClient(u, srv.Client()), "test", opts...)
+}
+
+func TestRerank(t *testing.T) {
+	docs := []ragkit.RetrievedDoc{{ID: "a", Text: "apples"}, {ID: "b", Text: "bananas"}, {ID: "c", Text: "cherries"}, {ID: "d", Text: "dates"}}
+	o := newTestReranker(t, map[string]string{
+		"apples":   `{"score": 3}`,
+		"bananas":  `{"score": 15}`, // clamped to 10
+		"cherries": ` {"score": 7} `,
+		"dates":    `{"score": -2}`, // clamped to 0
+	}, WithConcurrency(2))
+
+	ranked, err := o.Rerank(context.Background(), "q", docs, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		id    string
+		score float32
+	}{{"b", 1}, {"c", 0.7}, {"a", 0.3}}
+	if len(ranked) != len(want) {
+		t.Fatalf("got %v, want %v", ranked, want)
+	}
+	for i, w := range want {
+		if ranked[i].ID != w.id || ranked[i].Score != w.score {
+			t.Errorf("ranked[%d] = %s (%v), want %s (%v)", i, ranked[i].ID, ranked[i].Score, w.id, w.score)
+		}
+	}
+}
+
+func TestRerankBadReply(t *testing.T) {
+	for _, reply := range []string{`7`, `{"relevance": 7}`, `{"score": "high"}`, ``} {
+		o := newTestReranker(t, map[string]string{"apples": `{"score": 3}`, "bananas": reply})
+		docs := []ragkit.RetrievedDoc{{ID: "a", Text: "apples"}, {ID: "b", Text: "bananas"}}
+		if _, err := o.Rerank(context.Background(), "q", docs, 2); err == nil || !strings.Contains(err.Error(), "document b") {
+			t.Errorf("reply %q: got error %v, want one for document b", reply, err)
+		}
+	}
+}
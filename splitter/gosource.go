@@ -0,0 +1,116 @@
+package splitter
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"maps"
+	"strings"
+)
+
+var _ Splitter = &GoSource{}
+
+// Metadata keys of Go source chunks
+const (
+	MetaPackage  = "package"  // Package name
+	MetaKind     = "kind"     // "package" (clause and imports), "func", "method", "type", "var" or "const"
+	MetaName     = "name"     // Declared names, joined by ", " for grouped declarations
+	MetaReceiver = "receiver" // Receiver type of a method, like "*Memory"
+)
+
+// GoSource splits Go source files into one chunk per top-level declaration, with its doc comment,
+// plus a chunk of the package clause and imports. Declarations longer than the chunk size
+// are split at blank lines, then lines, keeping their metadata.
+// Sources that fail to parse are split like Recursive at blank lines and lines.
+type GoSource struct {
+	size int
+	config
+}
+
+// NewGoSource creates a GoSource splitter making chunks of at most size,
+// measured by the length function (see WithLengthFunc)
+func NewGoSource(size int, opts ...Option) *GoSource {
+	g := &GoSource{config: newConfig(opts)}
+	g.size, _ = sizes(size, 0)
+	return g
+}
+
+func (g *GoSource) Split(text string) []Chunk {
+	lines := &Recursive{size: g.size, config: g.config}
+	lines.separators = []string{"\n\n", "\n", ""}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", text, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return lines.Split(text)
+	}
+	offset := func(pos token.Pos) int {
+		return fset.Position(pos).Offset
+	}
+	pkg := file.Name.Name
+
+	var chunks []Chunk
+	emit := func(start, end int, metadata map[string]any) {
+		metadata[MetaPackage] = pkg
+		for _, c := range merge(text, lines.pieces(text, start, end, lines.separators, nil), g.size, 0) {
+			c.Metadata = maps.Clone(metadata)
+			chunks = append(chunks, c)
+		}
+	}
+
+	// package clause and imports
+	start := offset(file.Package)
+	if file.Doc != nil {
+		start = offset(file.Doc.Pos())
+	}
+	end := offset(file.Name.End())
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			end = offset(gen.End())
+		}
+	}
+	emit(start, end, map[string]any{MetaKind: "package", MetaName: pkg})
+
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			metadata := map[string]any{MetaKind: "func", MetaName: decl.Name.Name}
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				metadata[MetaKind] = "method"
+				metadata[MetaReceiver] = exprString(text, offset, decl.Recv.List[0].Type)
+			}
+			emit(declStart(offset, decl.Doc, decl.Pos()), offset(decl.End()), metadata)
+		case *ast.GenDecl:
+			if decl.Tok == token.IMPORT {
+				continue
+			}
+			var names []string
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					names = append(names, spec.Name.Name)
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						names = append(names, name.Name)
+					}
+				}
+			}
+			metadata := map[string]any{MetaKind: decl.Tok.String(), MetaName: strings.Join(names, ", ")}
+			emit(declStart(offset, decl.Doc, decl.Pos()), offset(decl.End()), metadata)
+		}
+	}
+	return chunks
+}
+
+// declStart returns the offset of a declaration, including its doc comment
+func declStart(offset func(token.Pos) int, doc *ast.CommentGroup, pos token.Pos) int {
+	if doc != nil {
+		return offset(doc.Pos())
+	}
+	return offset(pos)
+}
+
+// exprString returns the source of expr
+func exprString(text string, offset func(token.Pos) int, expr ast.Expr) string {
+	return text[offset(expr.Pos()):offset(expr.End())]
+}
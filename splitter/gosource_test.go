@@ -0,0 +1,112 @@
+package splitter
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+const goSource = `// Package store keeps things.
+package store
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrNotFound is returned for missing keys
+var ErrNotFound = errors.New("not found")
+
+const (
+	a = iota
+	b
+)
+
+// Memory is a store in memory
+type Memory struct {
+	mu sync.Mutex
+	m  map[string]string
+}
+
+// Get returns the value of key
+func (s *Memory) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.m[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return v, nil
+}
+
+func New() *Memory { return &Memory{m: map[string]string{}} }
+`
+
+func TestGoSource(t *testing.T) {
+	chunks := NewGoSource(1000).Split(goSource)
+	checkChunks(t, goSource, chunks, 1000, utf8.RuneCountInString)
+
+	want := []struct {
+		kind, name, receiver, prefix string
+	}{
+		{"package", "store", "", "// Package store keeps things."},
+		{"var", "ErrNotFound", "", "// ErrNotFound is returned"},
+		{"const", "a, b", "", "const ("},
+		{"type", "Memory", "", "// Memory is a store"},
+		{"method", "Get", "*Memory", "// Get returns"},
+		{"func", "New", "", "func New()"},
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("got %d chunks, want %d", len(chunks), len(want))
+	}
+	for i, w := range want {
+		c := chunks[i]
+		if c.Metadata[MetaPackage] != "store" || c.Metadata[MetaKind] != w.kind || c.Metadata[MetaName] != w.name {
+			t.Errorf("chunk %d: metadata %v, want kind %s, name %s", i, c.Metadata, w.kind, w.name)
+		}
+		if r, _ := c.Metadata[MetaReceiver].(string); r != w.receiver {
+			t.Errorf("chunk %d: receiver %q, want %q", i, r, w.receiver)
+		}
+		if !strings.HasPrefix(c.Text, w.prefix) {
+			t.Errorf("chunk %d: %q doesn't start with %q", i, c.Text, w.prefix)
+		}
+	}
+	if !strings.HasSuffix(chunks[0].Text, `"sync"
+)`) {
+		t.Errorf("package chunk doesn't include the imports: %q", chunks[0].Text)
+	}
+}
+
+func TestGoSourceLongDeclaration(t *testing.T) {
+	chunks := NewGoSource(60).Split(goSource)
+	checkChunks(t, goSource, chunks, 60, utf8.RuneCountInString)
+
+	var get []Chunk
+	for _, c := range chunks {
+		if c.Metadata[MetaName] == "Get" {
+			get = append(get, c)
+		}
+	}
+	if len(get) < 2 {
+		t.Fatalf("Get split into %d chunks, want several", len(get))
+	}
+	for _, c := range get {
+		if c.Metadata[MetaKind] != "method" || c.Metadata[MetaReceiver] != "*Memory" {
+			t.Errorf("chunk %q lost its metadata: %v", c.Text, c.Metadata)
+		}
+	}
+	// the parts of a declaration don't share metadata maps
+	get[0].Metadata["extra"] = true
+	if get[1].Metadata["extra"] != nil {
+		t.Error("chunks share their metadata")
+	}
+}
+
+func TestGoSourceInvalid(t *testing.T) {
+	text := "this is not Go\n\nfunc broken( {\n"
+	chunks := NewGoSource(1000).Split(text)
+	checkChunks(t, text, chunks, 1000, utf8.RuneCountInString)
+	if len(chunks) != 1 || chunks[0].Metadata != nil {
+		t.Errorf("got %v, want the text without metadata", chunks)
+	}
+}
@@ -0,0 +1,133 @@
+package splitter
+
+import (
+	"slices"
+	"strings"
+)
+
+var _ Splitter = &Markdown{}
+
+// MetaHeadings is the metadata key of the heading path of a Markdown chunk, like ["Guide", "Install"]
+const MetaHeadings = "headings"
+
+// Markdown splits Markdown texts into sections at ATX headings ("#" to "######"),
+// then sections longer than the chunk size like Recursive does, keeping fenced code blocks
+// whole unless a block alone exceeds the size. Chunks never span two sections and carry
+// the path of headings of their section in MetaHeadings. Headings in code blocks are ignored.
+type Markdown struct {
+	size, overlap int
+	config
+}
+
+// NewMarkdown creates a Markdown splitter making chunks of at most size,
+// overlapping by up to overlap within a section, both measured by the length function (see WithLengthFunc)
+func NewMarkdown(size, overlap int, opts ...Option) *Markdown {
+	m := &Markdown{config: newConfig(opts)}
+	m.size, m.overlap = sizes(size, overlap)
+	return m
+}
+
+func (m *Markdown) Split(text string) []Chunk {
+	prose := &Recursive{size: m.size, config: m.config}
+	code := &Recursive{size: m.size, config: m.config}
+	code.separators = []string{"\n", ""}
+
+	var chunks []Chunk
+	for _, sec := range markdownSections(text) {
+		var spans []span
+		for _, b := range sec.blocks {
+			if b.code {
+				spans = code.pieces(text, b.start, b.end, code.separators, spans)
+			} else {
+				spans = prose.pieces(text, b.start, b.end, prose.separators, spans)
+			}
+		}
+		for _, c := range merge(text, spans, m.size, m.overlap) {
+			if len(sec.headings) > 0 {
+				c.Metadata = map[string]any{MetaHeadings: slices.Clone(sec.headings)}
+			}
+			chunks = append(chunks, c)
+		}
+	}
+	return chunks
+}
+
+// section is the text under a heading, up to the next one
+type section struct {
+	headings []string
+	blocks   []block
+}
+
+// block is a run of lines of a section, either prose or a fenced code block
+type block struct {
+	start, end int
+	code       bool
+}
+
+// markdownSections splits text at its headings
+func markdownSections(text string) []section {
+	var sections []section
+	var headings []string
+	cur := section{}
+	fence := "" // marker of the open code fence, "" outside code
+
+	addLine := func(start, end int, code bool) {
+		if n := len(cur.blocks); n > 0 && cur.blocks[n-1].code == code && cur.blocks[n-1].end == start {
+			cur.blocks[n-1].end = end
+			return
+		}
+		cur.blocks = append(cur.blocks, block{start, end, code})
+	}
+
+	for start := 0; start < len(text); {
+		end := len(text)
+		if i := strings.IndexByte(text[start:], '\n'); i >= 0 {
+			end = start + i + 1
+		}
+		raw := strings.TrimRight(text[start:end], "\r\n")
+		line := strings.TrimSpace(raw)
+		indented := strings.HasPrefix(raw, "    ") || strings.HasPrefix(raw, "\t") // indented code
+
+		switch {
+		case fence != "":
+			// a closing fence is at least as long as the opening one
+			if strings.HasPrefix(line, fence) && strings.Trim(line, fence[:1]) == "" {
+				fence = ""
+			}
+			addLine(start, end, true)
+		case !indented && (strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~")):
+			fence = line[:len(line)-len(strings.TrimLeft(line, line[:1]))]
+			// a code block starts a new block even right after another one
+			cur.blocks = append(cur.blocks, block{start, start, true})
+			addLine(start, end, true)
+		default:
+			if level, title, ok := atxHeading(line); ok && !indented {
+				if len(cur.blocks) > 0 {
+					sections = append(sections, cur)
+				}
+				headings = append(headings[:min(len(headings), level-1)], title)
+				cur = section{headings: slices.Clone(headings)}
+			}
+			addLine(start, end, false)
+		}
+		start = end
+	}
+	if len(cur.blocks) > 0 {
+		sections = append(sections, cur)
+	}
+	return sections
+}
+
+// atxHeading parses a heading line like "## Title ##"
+func atxHeading(line string) (level int, title string, ok bool) {
+	level = len(line) - len(strings.TrimLeft(line, "#"))
+	if level < 1 || level > 6 {
+		return 0, "", false
+	}
+	rest := line[level:]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return 0, "", false // like "#hashtag"
+	}
+	title = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(rest), "#"))
+	return level, title, true
+}
@@ -0,0 +1,155 @@
+package splitter
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+const guide = "Intro before any heading.\n" +
+	"\n" +
+	"# Guide\n" +
+	"\n" +
+	"Welcome to the guide.\n" +
+	"\n" +
+	"## Install\n" +
+	"\n" +
+	"Run the command below.\n" +
+	"\n" +
+	"```sh\n" +
+	"# not a heading\n" +
+	"go get example.com/mod\n" +
+	"```\n" +
+	"\n" +
+	"### Linux ###\n" +
+	"\n" +
+	"Use a package manager.\n" +
+	"\n" +
+	"## Usage\n" +
+	"\n" +
+	"Import the package.\n" +
+	"\n" +
+	"    # indented code, not a heading\n" +
+	"\n" +
+	"#hashtag is not a heading either.\n" +
+	"\n" +
+	"# Appendix\n" +
+	"\n" +
+	"~~~~\n" +
+	"~~~\n" +
+	"still code\n" +
+	"~~~~\n"
+
+func headings(c Chunk) []string {
+	h, _ := c.Metadata[MetaHeadings].([]string)
+	return h
+}
+
+func TestMarkdownHeadings(t *testing.T) {
+	chunks := NewMarkdown(1000, 0).Split(guide)
+	checkChunks(t, guide, chunks, 1000, utf8.RuneCountInString)
+
+	want := []struct {
+		headings []string
+		prefix   string
+	}{
+		{nil, "Intro before any heading."},
+		{[]string{"Guide"}, "# Guide"},
+		{[]string{"Guide", "Install"}, "## Install"},
+		{[]string{"Guide", "Install", "Linux"}, "### Linux ###"},
+		{[]string{"Guide", "Usage"}, "## Usage"},
+		{[]string{"Appendix"}, "# Appendix"},
+	}
+	if len(chunks) != len(want) {
+		for _, c := range chunks {
+			t.Logf("%v %q", headings(c), c.Text)
+		}
+		t.Fatalf("got %d chunks, want %d", len(chunks), len(want))
+	}
+	for i, w := range want {
+		c := chunks[i]
+		if !slices.Equal(headings(c), w.headings) {
+			t.Errorf("chunk %d: headings %q, want %q", i, headings(c), w.headings)
+		}
+		if !strings.HasPrefix(c.Text, w.prefix) {
+			t.Errorf("chunk %d: %q doesn't start with %q", i, c.Text, w.prefix)
+		}
+	}
+	if chunks[0].Metadata != nil {
+		t.Errorf("chunk before any heading has metadata %v", chunks[0].Metadata)
+	}
+	if !strings.Contains(chunks[2].Text, "# not a heading") {
+		t.Error("the code block of Install is split off its section")
+	}
+	if !strings.Contains(chunks[4].Text, "#hashtag") || !strings.Contains(chunks[4].Text, "# indented code") {
+		t.Error("Usage is split at a hashtag or indented code")
+	}
+	if !strings.HasSuffix(chunks[5].Text, "still code\n~~~~") {
+		t.Errorf("a shorter fence closes the code block: %q", chunks[5].Text)
+	}
+}
+
+func TestMarkdownCodeFences(t *testing.T) {
+	code := "```go\n" + strings.Repeat("fmt.Println(\"a line of code\")\n", 3) + "```"
+	text := "# Code\n\nSome prose before the code block, long enough to fill a chunk.\n\n" + code + "\n\nProse after.\n"
+
+	// the code block fits a chunk, so it stays whole
+	chunks := NewMarkdown(len(code)+5, 0).Split(text)
+	checkChunks(t, text, chunks, len(code)+5, utf8.RuneCountInString)
+	found := false
+	for _, c := range chunks {
+		if strings.Contains(c.Text, "```go") {
+			found = true
+			if !strings.Contains(c.Text, code) {
+				t.Errorf("code block split: %q", c.Text)
+			}
+		}
+		if !slices.Equal(headings(c), []string{"Code"}) {
+			t.Errorf("chunk %q: headings %q", c.Text, headings(c))
+		}
+	}
+	if !found {
+		t.Fatal("code block not found")
+	}
+
+	// a code block longer than the size is split at lines
+	chunks = NewMarkdown(40, 0).Split(code)
+	checkChunks(t, code, chunks, 40, utf8.RuneCountInString)
+	for _, c := range chunks[:len(chunks)-1] {
+		if c.End < len(code) && code[c.End] != '\n' {
+			t.Errorf("code chunk %q doesn't end at a line", c.Text)
+		}
+	}
+}
+
+func TestMarkdownSectionsDontMerge(t *testing.T) {
+	text := "# A\n\none\n\n# B\n\ntwo\n"
+	chunks := NewMarkdown(100, 50).Split(text)
+	if len(chunks) != 2 || chunks[0].Text != "# A\n\none" || chunks[1].Text != "# B\n\ntwo" {
+		t.Errorf("got %v, want one chunk per section", chunks)
+	}
+}
+
+func TestATXHeading(t *testing.T) {
+	tests := []struct {
+		line  string
+		level int
+		title string
+		ok    bool
+	}{
+		{"# Title", 1, "Title", true},
+		{"###### Six", 6, "Six", true},
+		{"####### Seven", 0, "", false},
+		{"## Closed ##", 2, "Closed", true},
+		{"#", 1, "", true},
+		{"#tag", 0, "", false},
+		{"Title", 0, "", false},
+	}
+	for _, tt := range tests {
+		level, title, ok := atxHeading(tt.line)
+		if level != tt.level || title != tt.title || ok != tt.ok {
+			t.Errorf("atxHeading(%q) = %d, %q, %v, want %d, %q, %v", tt.line, level, title, ok, tt.level, tt.title, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package splitter
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+var _ Splitter = &Recursive{}
+
+// Recursive splits texts at the first separator that makes pieces fit the chunk size,
+// retrying the next separators on pieces still too long, then merges the pieces into chunks.
+// Separators stay at the end of the piece they terminate.
+type Recursive struct {
+	size, overlap int
+	config
+}
+
+// NewRecursive creates a Recursive splitter making chunks of at most size,
+// overlapping by up to overlap, both measured by the length function (see WithLengthFunc)
+func NewRecursive(size, overlap int, opts ...Option) *Recursive {
+	r := &Recursive{config: newConfig(opts)}
+	r.size, r.overlap = sizes(size, overlap)
+	return r
+}
+
+func (r *Recursive) Split(text string) []Chunk {
+	return merge(text, r.pieces(text, 0, len(text), r.separators, nil), r.size, r.overlap)
+}
+
+// pieces appends the spans of text[start:end] split until they fit the chunk size
+func (r *Recursive) pieces(text string, start, end int, separators []string, out []span) []span {
+	n := r.length(text[start:end])
+	if n <= r.size {
+		return append(out, span{start, end, n})
+	}
+
+	for i, sep := range separators {
+		if sep == "" {
+			// split into characters
+			for pos := start; pos < end; {
+				_, w := utf8.DecodeRuneInString(text[pos:end])
+				out = append(out, span{pos, pos + w, r.length(text[pos : pos+w])})
+				pos += w
+			}
+			return out
+		}
+		if !strings.Contains(text[start:end], sep) {
+			continue
+		}
+		for pos := start; pos < end; {
+			pieceEnd := end
+			if idx := strings.Index(text[pos:end], sep); idx >= 0 {
+				pieceEnd = pos + idx + len(sep)
+			}
+			out = r.pieces(text, pos, pieceEnd, separators[i+1:], out)
+			pos = pieceEnd
+		}
+		return out
+	}
+
+	// no separator left; keep it whole
+	return append(out, span{start, end, n})
+}
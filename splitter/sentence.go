@@ -0,0 +1,111 @@
+package splitter
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var _ Splitter = &Sentence{}
+
+// Sentence splits texts into sentences and merges them into chunks, so chunks only break between sentences.
+// A sentence longer than the chunk size is split like Recursive does.
+//
+// A sentence ends at a paragraph break, or after '.', '!', '?' or '…' followed by white space,
+// or right after the CJK full stops '。', '！', '？' and '．', which need no space.
+// Closing quotes and brackets after the punctuation stay in the sentence.
+// Korean sentences, ending like "합니다." with a space, are split by the Latin rules.
+type Sentence struct {
+	size, overlap int
+	config
+}
+
+// NewSentence creates a Sentence splitter making chunks of at most size,
+// overlapping by up to overlap, both measured by the length function (see WithLengthFunc)
+func NewSentence(size, overlap int, opts ...Option) *Sentence {
+	s := &Sentence{config: newConfig(opts)}
+	s.size, s.overlap = sizes(size, overlap)
+	return s
+}
+
+func (s *Sentence) Split(text string) []Chunk {
+	// long sentences fall back to words, then characters
+	fallback := &Recursive{size: s.size, config: s.config}
+	fallback.separators = []string{" ", ""}
+
+	var spans []span
+	for _, sentence := range Sentences(text) {
+		spans = fallback.pieces(text, sentence.Start, sentence.End, fallback.separators, spans)
+	}
+	return merge(text, spans, s.size, s.overlap)
+}
+
+// Sentences returns the sentences of text, following the rules of Sentence.
+// Together they cover text; white space after a sentence belongs to it.
+func Sentences(text string) []Chunk {
+	var sentences []Chunk
+	start := 0
+	for pos := 0; pos < len(text); {
+		r, w := utf8.DecodeRuneInString(text[pos:])
+		pos += w
+
+		end := -1
+		switch {
+		case isCJKStop(r):
+			end = skipClosers(text, skipStops(text, pos))
+		case isStop(r):
+			next := skipClosers(text, skipStops(text, pos))
+			if next == len(text) {
+				end = next
+			} else if r, _ := utf8.DecodeRuneInString(text[next:]); unicode.IsSpace(r) {
+				end = next
+			}
+		case r == '\n' && strings.HasPrefix(text[pos:], "\n"):
+			end = pos // paragraph break
+		}
+		if end < 0 {
+			continue
+		}
+
+		// white space goes with the sentence it follows
+		end += len(text[end:]) - len(strings.TrimLeftFunc(text[end:], unicode.IsSpace))
+		sentences = append(sentences, Chunk{Text: text[start:end], Start: start, End: end})
+		start, pos = end, end
+	}
+	if start < len(text) {
+		sentences = append(sentences, Chunk{Text: text[start:], Start: start, End: len(text)})
+	}
+	return sentences
+}
+
+func isStop(r rune) bool {
+	return r == '.' || r == '!' || r == '?' || r == '…'
+}
+
+func isCJKStop(r rune) bool {
+	return r == '。' || r == '！' || r == '？' || r == '．'
+}
+
+// skipStops returns the offset after the sentence-ending punctuation at pos, like in "?!" or "..."
+func skipStops(text string, pos int) int {
+	for pos < len(text) {
+		r, w := utf8.DecodeRuneInString(text[pos:])
+		if !isStop(r) && !isCJKStop(r) {
+			break
+		}
+		pos += w
+	}
+	return pos
+}
+
+// skipClosers returns the offset after the closing quotes and brackets at pos
+func skipClosers(text string, pos int) int {
+	for pos < len(text) {
+		r, w := utf8.DecodeRuneInString(text[pos:])
+		if !strings.ContainsRune(`"')]}”’」』）》〉`, r) {
+			break
+		}
+		pos += w
+	}
+	return pos
+}
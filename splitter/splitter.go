@@ -0,0 +1,159 @@
+// Package splitter splits long texts into chunks sized for embedding and retrieval,
+// turning a document into the ragkit.Documents of its chunks.
+package splitter
+
+import (
+	"maps"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+// Metadata keys set on the documents of chunks by SplitDocuments
+const (
+	MetaChunkIndex = "chunk_index" // Position of the chunk in its parent
+	MetaStart      = "chunk_start" // Byte offset of the chunk in the parent text
+	MetaEnd        = "chunk_end"   // Byte offset of the end of the chunk in the parent text
+	MetaParentID   = "parent_id"   // ID of the parent document
+)
+
+// Splitter is a type that can split a text into chunks
+type Splitter interface {
+	// Split: Split text into chunks, in order
+	Split(text string) []Chunk
+}
+
+// Chunk is a type that represents a piece of a text
+type Chunk struct {
+	Text     string         // Text of the chunk, trimmed of surrounding white space
+	Start    int            // Byte offset of Text in the original text
+	End      int            // Byte offset of the end of Text in the original text
+	Metadata map[string]any // Optional: Structure of the text around the chunk, like headings
+}
+
+// Option configures a splitter
+type Option func(*config)
+
+type config struct {
+	separators []string
+	length     func(string) int
+	tokenizer  func(string) []Chunk
+}
+
+// WithSeparators sets the separators tried in order by Recursive
+// (default: paragraphs, lines, sentences, words, then characters)
+func WithSeparators(separators ...string) Option {
+	return func(c *config) {
+		c.separators = separators
+	}
+}
+
+// WithLengthFunc sets how Recursive and Sentence measure the size of chunks
+// (default: the number of runes). ragkit.EstimateTokens sizes chunks in tokens.
+func WithLengthFunc(length func(string) int) Option {
+	return func(c *config) {
+		c.length = length
+	}
+}
+
+// WithTokenizer sets the tokenizer of Token, returning the tokens of a text with their offsets
+// (default: Tokenize)
+func WithTokenizer(tokenizer func(string) []Chunk) Option {
+	return func(c *config) {
+		c.tokenizer = tokenizer
+	}
+}
+
+func newConfig(opts []Option) config {
+	c := config{
+		separators: []string{"\n\n", "\n", ". ", "? ", "! ", "。", " ", ""},
+		length:     utf8.RuneCountInString,
+		tokenizer:  Tokenize,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// sizes returns size and overlap made consistent: size at least 1, overlap in [0, size)
+func sizes(size, overlap int) (int, int) {
+	size = max(size, 1)
+	return size, min(max(overlap, 0), size-1)
+}
+
+// SplitDocuments splits the text of every doc with s into documents of its chunks.
+// Chunks inherit the metadata of their parent, overridden by the metadata of the chunk,
+// plus MetaChunkIndex, MetaStart, MetaEnd and MetaParentID.
+// A parent without ID gets one by ragkit.GenerateID. Chunk IDs are generated from their text and metadata.
+func SplitDocuments(s Splitter, docs ...ragkit.Document) []ragkit.Document {
+	var chunks []ragkit.Document
+	for _, doc := range docs {
+		parentID := doc.ID
+		if parentID == "" {
+			parentID = ragkit.GenerateID(doc.Text, doc.Metadata)
+		}
+		for i, chunk := range s.Split(doc.Text) {
+			metadata := maps.Clone(doc.Metadata)
+			if metadata == nil {
+				metadata = make(map[string]any)
+			}
+			maps.Copy(metadata, chunk.Metadata)
+			metadata[MetaChunkIndex] = i
+			metadata[MetaStart] = chunk.Start
+			metadata[MetaEnd] = chunk.End
+			metadata[MetaParentID] = parentID
+			chunks = append(chunks, ragkit.Document{
+				ID:       ragkit.GenerateID(chunk.Text, metadata),
+				Text:     chunk.Text,
+				Metadata: metadata,
+			})
+		}
+	}
+	return chunks
+}
+
+// span is a range of a text measured by a length function
+type span struct {
+	start, end int
+	n          int // length
+}
+
+// merge joins consecutive spans of text into chunks of at most size,
+// starting each chunk with up to overlap of the end of the previous one.
+// A span longer than size makes a chunk on its own.
+func merge(text string, spans []span, size, overlap int) []Chunk {
+	var chunks []Chunk
+	var window []span
+	total := 0
+	for _, s := range spans {
+		if len(window) > 0 && total+s.n > size {
+			chunks = appendChunk(chunks, text, window[0].start, window[len(window)-1].end)
+			// keep the tail fitting in overlap, leaving room for s
+			for len(window) > 0 && (total > overlap || total+s.n > size) {
+				total -= window[0].n
+				window = window[1:]
+			}
+		}
+		window = append(window, s)
+		total += s.n
+	}
+	if len(window) > 0 {
+		chunks = appendChunk(chunks, text, window[0].start, window[len(window)-1].end)
+	}
+	return chunks
+}
+
+// appendChunk appends text[start:end] trimmed of white space, unless empty
+func appendChunk(chunks []Chunk, text string, start, end int) []Chunk {
+	s := text[start:end]
+	trimmed := strings.TrimLeftFunc(s, unicode.IsSpace)
+	start += len(s) - len(trimmed)
+	trimmed = strings.TrimRightFunc(trimmed, unicode.IsSpace)
+	if trimmed == "" {
+		return chunks
+	}
+	return append(chunks, Chunk{Text: trimmed, Start: start, End: start + len(trimmed)})
+}
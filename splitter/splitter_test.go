@@ -0,0 +1,320 @@
+package splitter
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+const english = `Retrieval augmented generation answers questions from documents. The documents are split into chunks, embedded and stored.
+
+At query time, the question is embedded too. The nearest chunks are retrieved! Are they relevant? A reranker can tell.
+
+A very long sentence follows, made of many words and no punctuation at all so that it cannot fit in a single chunk of the configured size and must be split at words`
+
+const cjk = "検索拡張生成は文書から質問に答える。文書はチャンクに分割される！本当か？はい。「引用」も文になる。」最後"
+
+const korean = "문서를 청크로 나눕니다. 청크를 임베딩합니다! 질문도 임베딩할까요? 네."
+
+// checkChunks checks that chunks are in order, trimmed, at most size long, and found at their offsets in text
+func checkChunks(t *testing.T, text string, chunks []Chunk, size int, length func(string) int) {
+	t.Helper()
+	if len(chunks) == 0 {
+		t.Fatal("no chunk")
+	}
+	prev := -1
+	for i, c := range chunks {
+		if c.Start < 0 || c.End > len(text) || c.Start > c.End {
+			t.Fatalf("chunk %d: invalid offsets [%d:%d]", i, c.Start, c.End)
+		}
+		if text[c.Start:c.End] != c.Text {
+			t.Errorf("chunk %d: text[%d:%d] = %q, want %q", i, c.Start, c.End, text[c.Start:c.End], c.Text)
+		}
+		if c.Text == "" || strings.TrimSpace(c.Text) != c.Text {
+			t.Errorf("chunk %d: %q isn't trimmed", i, c.Text)
+		}
+		if !utf8.ValidString(c.Text) {
+			t.Errorf("chunk %d: %q splits a rune", i, c.Text)
+		}
+		if n := length(c.Text); size > 0 && n > size {
+			t.Errorf("chunk %d: length %d > %d: %q", i, n, size, c.Text)
+		}
+		if c.Start <= prev {
+			t.Errorf("chunk %d: starts at %d, not after the previous chunk at %d", i, c.Start, prev)
+		}
+		prev = c.Start
+	}
+}
+
+// covered reports whether every non-space byte of text is in a chunk
+func covered(text string, chunks []Chunk) bool {
+	in := make([]bool, len(text))
+	for _, c := range chunks {
+		for i := c.Start; i < c.End; i++ {
+			in[i] = true
+		}
+	}
+	for i, b := range []byte(text) {
+		if !in[i] && !strings.ContainsRune(" \t\r\n", rune(b)) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestOffsets(t *testing.T) {
+	splitters := map[string]Splitter{
+		"recursive":        NewRecursive(40, 10),
+		"recursive tokens": NewRecursive(12, 4, WithLengthFunc(ragkit.EstimateTokens)),
+		"sentence":         NewSentence(60, 20),
+		"sentence small":   NewSentence(5, 0),
+		"token":            NewToken(8, 2),
+		"markdown":         NewMarkdown(40, 10),
+		"gosource":         NewGoSource(40),
+	}
+	for name, s := range splitters {
+		for _, text := range []string{english, cjk, korean, "  padded  \n\n", "x"} {
+			chunks := s.Split(text)
+			checkChunks(t, text, chunks, 0, utf8.RuneCountInString)
+			if !covered(text, chunks) {
+				t.Errorf("%s: chunks of %q don't cover it", name, text)
+			}
+		}
+		if got := s.Split(" \n\t "); len(got) != 0 {
+			t.Errorf("%s: white space split into %v", name, got)
+		}
+	}
+}
+
+func TestRecursive(t *testing.T) {
+	chunks := NewRecursive(70, 0).Split(english)
+	checkChunks(t, english, chunks, 70, utf8.RuneCountInString)
+	if !covered(english, chunks) {
+		t.Error("chunks don't cover the text")
+	}
+	// the first sentence fits, so it isn't split at words
+	if chunks[0].Text != "Retrieval augmented generation answers questions from documents." {
+		t.Errorf("first chunk = %q", chunks[0].Text)
+	}
+
+	// a text fitting the size is one chunk
+	if got := NewRecursive(1000, 0).Split(english); len(got) != 1 || got[0].Text != strings.TrimSpace(english) {
+		t.Errorf("got %d chunks, want the whole text", len(got))
+	}
+
+	// runes are never split
+	if got := NewRecursive(1, 0, WithSeparators("")).Split("한글"); len(got) != 2 {
+		t.Errorf("got %d chunks, want 2", len(got))
+	}
+	for _, c := range NewRecursive(1, 0, WithSeparators("")).Split("한글") {
+		if utf8.RuneCountInString(c.Text) != 1 {
+			t.Errorf("chunk %q isn't one rune", c.Text)
+		}
+	}
+}
+
+func TestOverlap(t *testing.T) {
+	text := strings.Repeat("alpha beta gamma delta epsilon ", 10)
+	const size, overlap = 30, 12
+	chunks := NewRecursive(size, overlap).Split(text)
+	checkChunks(t, text, chunks, size, utf8.RuneCountInString)
+	if len(chunks) < 3 {
+		t.Fatalf("got %d chunks, want several", len(chunks))
+	}
+	for i := 1; i < len(chunks); i++ {
+		prev, c := chunks[i-1], chunks[i]
+		if c.Start >= prev.End {
+			t.Errorf("chunk %d [%d:%d] doesn't overlap the previous one [%d:%d]", i, c.Start, c.End, prev.Start, prev.End)
+			continue
+		}
+		if n := utf8.RuneCountInString(text[c.Start:prev.End]); n > overlap {
+			t.Errorf("chunk %d overlaps by %d > %d", i, n, overlap)
+		}
+	}
+
+	// without overlap, chunks are disjoint
+	chunks = NewRecursive(size, 0).Split(text)
+	for i := 1; i < len(chunks); i++ {
+		if chunks[i].Start < chunks[i-1].End {
+			t.Errorf("chunk %d overlaps the previous one", i)
+		}
+	}
+
+	// an overlap of size or more is reduced below size
+	chunks = NewRecursive(10, 50).Split(text)
+	checkChunks(t, text, chunks, 10, utf8.RuneCountInString)
+}
+
+func TestSentences(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"latin", "One. Two! Three? Four", []string{"One. ", "Two! ", "Three? ", "Four"}},
+		{"ellipsis and repeats", "Wait... What?! Yes.", []string{"Wait... ", "What?! ", "Yes."}},
+		{"no space after stop", "v1.2 is out. Go", []string{"v1.2 is out. ", "Go"}},
+		{"closing quotes", `He said "stop." Then left.`, []string{`He said "stop." `, "Then left."}},
+		{"paragraphs", "no stop\n\nnext para", []string{"no stop\n\n", "next para"}},
+		{"cjk", cjk, []string{"検索拡張生成は文書から質問に答える。", "文書はチャンクに分割される！", "本当か？", "はい。", "「引用」も文になる。」", "最後"}},
+		{"cjk full stops", "一．二。。三", []string{"一．", "二。。", "三"}},
+		{"korean", korean, []string{"문서를 청크로 나눕니다. ", "청크를 임베딩합니다! ", "질문도 임베딩할까요? ", "네."}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sentences := Sentences(tt.text)
+			var got []string
+			end := 0
+			for _, s := range sentences {
+				got = append(got, s.Text)
+				if s.Start != end || tt.text[s.Start:s.End] != s.Text {
+					t.Errorf("sentence %q at [%d:%d] doesn't follow the previous one at %d", s.Text, s.Start, s.End, end)
+				}
+				end = s.End
+			}
+			if end != len(tt.text) {
+				t.Errorf("sentences end at %d, want %d", end, len(tt.text))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSentence(t *testing.T) {
+	chunks := NewSentence(30, 0).Split(cjk)
+	checkChunks(t, cjk, chunks, 30, utf8.RuneCountInString)
+	// chunks break between sentences only
+	ends := make(map[int]bool)
+	for _, s := range Sentences(cjk) {
+		ends[s.End] = true
+	}
+	for _, c := range chunks {
+		if !ends[c.End] {
+			t.Errorf("chunk %q ends inside a sentence", c.Text)
+		}
+	}
+
+	// a sentence longer than the size is split at words
+	chunks = NewSentence(40, 0).Split(english)
+	checkChunks(t, english, chunks, 40, utf8.RuneCountInString)
+	for _, c := range chunks {
+		if c.End < len(english) && english[c.End] != ' ' && english[c.End] != '\n' {
+			t.Errorf("chunk %q ends inside a word", c.Text)
+		}
+	}
+	if last := chunks[len(chunks)-1]; !strings.HasSuffix(last.Text, "words") {
+		t.Errorf("last chunk = %q", last.Text)
+	}
+}
+
+func TestTokenBudget(t *testing.T) {
+	// Recursive and Sentence sized in estimated tokens
+	for _, s := range []Splitter{
+		NewRecursive(20, 5, WithLengthFunc(ragkit.EstimateTokens)),
+		NewSentence(20, 5, WithLengthFunc(ragkit.EstimateTokens)),
+	} {
+		for _, text := range []string{english, cjk, korean} {
+			checkChunks(t, text, s.Split(text), 20, ragkit.EstimateTokens)
+		}
+	}
+
+	// Token makes chunks of exactly size tokens, overlapping by overlap tokens
+	text := strings.Repeat("word ", 25)
+	chunks := NewToken(10, 3).Split(text)
+	checkChunks(t, text, chunks, 0, utf8.RuneCountInString)
+	var counts []int
+	for _, c := range chunks {
+		counts = append(counts, len(Tokenize(c.Text)))
+	}
+	if want := []int{10, 10, 10, 4}; !slices.Equal(counts, want) {
+		t.Errorf("tokens per chunk = %v, want %v", counts, want)
+	}
+	if tokens := Tokenize(text[chunks[1].Start:chunks[0].End]); len(tokens) != 3 {
+		t.Errorf("chunks overlap by %d tokens, want 3", len(tokens))
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"hello, world", []string{"hell", "o", ",", "worl", "d"}},
+		{"go1.24", []string{"go1", ".", "24"}},
+		{"한국어 문서", []string{"한", "국", "어", "문", "서"}},
+		{"日本語テキスト", []string{"日", "本", "語", "テ", "キ", "ス", "ト"}},
+		{"  ", nil},
+	}
+	for _, tt := range tests {
+		tokens := Tokenize(tt.text)
+		var got []string
+		for _, tok := range tokens {
+			got = append(got, tok.Text)
+			if tt.text[tok.Start:tok.End] != tok.Text {
+				t.Errorf("%q: token %q at wrong offsets [%d:%d]", tt.text, tok.Text, tok.Start, tok.End)
+			}
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+
+	custom := NewToken(2, 0, WithTokenizer(func(text string) []Chunk {
+		return []Chunk{{Text: text[:1], Start: 0, End: 1}, {Text: text[1:2], Start: 1, End: 2}, {Text: text[2:], Start: 2, End: len(text)}}
+	}))
+	if got := custom.Split("abcdef"); len(got) != 2 || got[0].Text != "ab" || got[1].Text != "cdef" {
+		t.Errorf("custom tokenizer: got %v", got)
+	}
+}
+
+func TestSplitDocuments(t *testing.T) {
+	docs := []ragkit.Document{
+		{ID: "doc", Text: "First sentence. Second sentence.", Metadata: map[string]any{"source": "a.txt"}},
+		{Text: "No ID here."},
+	}
+	chunks := SplitDocuments(NewSentence(16, 0), docs...)
+	if len(chunks) != 3 {
+		t.Fatalf("got %d chunks, want 3", len(chunks))
+	}
+
+	for i, want := range []struct {
+		text   string
+		index  int
+		parent string
+	}{
+		{"First sentence.", 0, "doc"},
+		{"Second sentence.", 1, "doc"},
+		{"No ID here.", 0, ragkit.GenerateID(docs[1].Text, nil)},
+	} {
+		c := chunks[i]
+		if c.Text != want.text || c.Metadata[MetaChunkIndex] != want.index || c.Metadata[MetaParentID] != want.parent {
+			t.Errorf("chunk %d = %q, index %v, parent %v, want %q, %d, %s",
+				i, c.Text, c.Metadata[MetaChunkIndex], c.Metadata[MetaParentID], want.text, want.index, want.parent)
+		}
+		parent := docs[0].Text
+		if i == 2 {
+			parent = docs[1].Text
+		}
+		start, end := c.Metadata[MetaStart].(int), c.Metadata[MetaEnd].(int)
+		if parent[start:end] != c.Text {
+			t.Errorf("chunk %d: parent[%d:%d] = %q", i, start, end, parent[start:end])
+		}
+		if c.ID == "" || c.ID == want.parent {
+			t.Errorf("chunk %d: ID %q", i, c.ID)
+		}
+	}
+	if chunks[0].Metadata["source"] != "a.txt" || chunks[2].Metadata["source"] != nil {
+		t.Error("chunks don't inherit the metadata of their parent only")
+	}
+	if _, ok := docs[0].Metadata[MetaChunkIndex]; ok {
+		t.Error("SplitDocuments modified the metadata of the parent")
+	}
+	if chunks[0].ID == chunks[1].ID {
+		t.Error("chunks have the same ID")
+	}
+}
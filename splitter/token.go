@@ -0,0 +1,76 @@
+package splitter
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+var _ Splitter = &Token{}
+
+// Token splits texts into chunks of a fixed number of tokens, overlapping by a fixed number of tokens
+type Token struct {
+	size, overlap int
+	config
+}
+
+// NewToken creates a Token splitter making chunks of size tokens, overlapping by overlap tokens.
+// Tokens come from the tokenizer (see WithTokenizer).
+func NewToken(size, overlap int, opts ...Option) *Token {
+	t := &Token{config: newConfig(opts)}
+	t.size, t.overlap = sizes(size, overlap)
+	return t
+}
+
+func (t *Token) Split(text string) []Chunk {
+	tokens := t.tokenizer(text)
+
+	var chunks []Chunk
+	for i := 0; i < len(tokens); i += t.size - t.overlap {
+		j := min(i+t.size, len(tokens))
+		chunks = appendChunk(chunks, text, tokens[i].Start, tokens[j-1].End)
+		if j == len(tokens) {
+			break
+		}
+	}
+	return chunks
+}
+
+// Tokenize is the default tokenizer of Token, approximating the tokens of language models
+// without a vocabulary: a token is a run of letters or digits up to 4 runes long,
+// a single Han, Hiragana or Katakana character, a Hangul syllable, or any other non-space rune.
+func Tokenize(text string) []Chunk {
+	const maxRun = 4
+
+	var tokens []Chunk
+	for pos := 0; pos < len(text); {
+		r, w := utf8.DecodeRuneInString(text[pos:])
+		switch {
+		case unicode.IsSpace(r):
+			pos += w
+			continue
+		case isWordRune(r):
+			end := pos + w
+			for n := 1; n < maxRun && end < len(text); n++ {
+				r, w := utf8.DecodeRuneInString(text[end:])
+				if !isWordRune(r) {
+					break
+				}
+				end += w
+			}
+			tokens = append(tokens, Chunk{Text: text[pos:end], Start: pos, End: end})
+			pos = end
+		default:
+			tokens = append(tokens, Chunk{Text: text[pos : pos+w], Start: pos, End: pos + w})
+			pos += w
+		}
+	}
+	return tokens
+}
+
+// isWordRune reports whether r joins a run of letters or digits; CJK characters are tokens on their own
+func isWordRune(r rune) bool {
+	if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+		return false
+	}
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
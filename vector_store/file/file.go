@@ -0,0 +1,524 @@
+// Package file provides a ragkit.VectorStore persisted to a single local file.
+//
+// Documents are kept in memory for search and every change is appended to the file
+// as a checksummed record, so a crash can at worst lose the record being written.
+// Open replays the file and drops a torn record at its tail.
+// A file must be opened by one process at a time.
+package file
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	ragkit "github.com/suapapa/go_ragkit"
+	"github.com/suapapa/go_ragkit/index/hnsw"
+	"github.com/suapapa/go_ragkit/vector_store/memory"
+)
+
+var (
+	_ ragkit.VectorStore       = &File{}
+	_ ragkit.FilteredRetriever = &File{}
+	_ ragkit.Upserter          = &File{}
+	_ ragkit.HybridRetriever   = &File{}
+)
+
+// magic identifies ragkit vector store files
+const magic = "RAGKITV1"
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+type File struct {
+	path      string
+	f         *os.File
+	embedder  ragkit.Embedder
+	mem       *memory.Memory
+	noSync    bool
+	batchSize int
+	torn      error // set when a failed append couldn't be truncated away
+
+	mu sync.Mutex // serializes writes to f
+}
+
+// Option configures a File store
+type Option func(*config)
+
+type config struct {
+	memOpts   []memory.Option
+	noSync    bool
+	batchSize int
+}
+
+// WithMetric sets the distance metric used for search (default: cosine)
+func WithMetric(metric ragkit.DistanceMetric) Option {
+	return func(c *config) {
+		c.memOpts = append(c.memOpts, memory.WithMetric(metric))
+	}
+}
+
+// WithDimension fixes the dimension of vectors (default: the dimension of the first indexed vector)
+func WithDimension(dimension int) Option {
+	return func(c *config) {
+		c.memOpts = append(c.memOpts, memory.WithDimension(dimension))
+	}
+}
+
+// WithBatchSize sets the number of documents embedded at once (default: ragkit.DefaultBatchSize)
+func WithBatchSize(n int) Option {
+	return func(c *config) {
+		c.batchSize = n
+	}
+}
+
+// WithHNSW searches with an HNSW approximate nearest neighbour index, rebuilt from the file on Open
+func WithHNSW(cfg hnsw.Config) Option {
+	return func(c *config) {
+		c.memOpts = append(c.memOpts, memory.WithHNSW(cfg))
+	}
+}
+
+// WithoutSync skips fsync after each write.
+// Writes get faster, but recent changes may be lost on power failure.
+func WithoutSync() Option {
+	return func(c *config) {
+		c.noSync = true
+	}
+}
+
+// record is a single change appended to the file
+type record struct {
+	Op  string           `json:"op"` // "put" or "del"
+	Doc *ragkit.Document `json:"doc,omitempty"`
+	ID  string           `json:"id,omitempty"`
+}
+
+// Open opens the store at path, creating the file if it doesn't exist, and loads its documents.
+// embedder may be nil if every document is indexed with a Vector and only Retrieve is used.
+func Open(path string, embedder ragkit.Embedder, opts ...Option) (*File, error) {
+	cfg := config{batchSize: ragkit.DefaultBatchSize}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &File{
+		path:      path,
+		f:         f,
+		embedder:  embedder,
+		mem:       memory.New(embedder, cfg.memOpts...),
+		noSync:    cfg.noSync,
+		batchSize: cfg.batchSize,
+	}
+	if err := ret.load(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to load %s: %w", path, err)
+	}
+	return ret, nil
+}
+
+func (s *File) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.f.Close()
+}
+
+// Index indexes docs, skipping the ones failing to embed, of a wrong dimension or with an existing ID.
+// Those are reported by a *ragkit.IndexError.
+func (s *File) Index(ctx context.Context, docs ...ragkit.Document) ([]string, error) {
+	docs, failed := ragkit.EmbedDocs(ctx, s.embedder, s.batchLimits(), docs...)
+
+	var (
+		encoded []ragkit.Document
+		recs    [][]byte
+		pos     []int // position in docs of encoded[i]
+	)
+	for i, doc := range docs {
+		if doc.Vector == nil {
+			continue // failed to embed
+		}
+		doc, rec, err := encode(doc)
+		if err != nil {
+			failed.Add(i, doc.ID, err)
+			continue
+		}
+		encoded, recs, pos = append(encoded, doc), append(recs, rec), append(pos, i)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids, err := s.mem.Index(ctx, encoded...)
+	rejected := make(map[int]bool)
+	if memFailed, ok := err.(*ragkit.IndexError); ok {
+		for _, de := range memFailed.Failed {
+			failed.Add(pos[de.Index], de.ID, de.Err)
+			rejected[de.Index] = true
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	var accepted [][]byte
+	for i, rec := range recs {
+		if !rejected[i] {
+			accepted = append(accepted, rec)
+		}
+	}
+	if err := s.append(accepted...); err != nil {
+		// keep memory in line with the file
+		for _, id := range ids {
+			s.mem.Delete(context.Background(), id)
+		}
+		return nil, err
+	}
+	return ids, failed.Err()
+}
+
+// Upsert indexes docs, replacing the documents of existing IDs.
+// Either all documents are upserted or, on error, none.
+func (s *File) Upsert(ctx context.Context, docs ...ragkit.Document) (ragkit.UpsertResult, error) {
+	docs, err := ragkit.PrepareDocs(ctx, s.embedder, s.batchLimits(), docs...)
+	if err != nil {
+		return ragkit.UpsertResult{}, err
+	}
+	recs := make([][]byte, len(docs))
+	for i := range docs {
+		if docs[i], recs[i], err = encode(docs[i]); err != nil {
+			return ragkit.UpsertResult{}, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return ragkit.UpsertResult{}, err
+	}
+	// write ahead, so a failed write leaves memory untouched
+	if err := s.append(recs...); err != nil {
+		return ragkit.UpsertResult{}, err
+	}
+	result, upsertErr := s.mem.Upsert(context.WithoutCancel(ctx), docs...)
+	if upsertErr != nil {
+		// invalid documents are in the file already; make it match memory again
+		if err := s.rewrite(); err != nil {
+			return ragkit.UpsertResult{}, err
+		}
+		return ragkit.UpsertResult{}, upsertErr
+	}
+	return result, nil
+}
+
+func (s *File) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exists, err := s.mem.Exists(ctx, id)
+	if err != nil || !exists {
+		return err
+	}
+
+	rec, err := json.Marshal(record{Op: "del", ID: id})
+	if err != nil {
+		return err
+	}
+	if err := s.append(rec); err != nil {
+		return err
+	}
+	return s.mem.Delete(ctx, id)
+}
+
+func (s *File) Exists(ctx context.Context, id string) (bool, error) {
+	return s.mem.Exists(ctx, id)
+}
+
+func (s *File) Retrieve(ctx context.Context, query []float32, topK int, metadataFieldNames ...string) ([]ragkit.RetrievedDoc, error) {
+	return s.mem.Retrieve(ctx, query, topK, metadataFieldNames...)
+}
+
+func (s *File) RetrieveFiltered(ctx context.Context, query []float32, topK int, filter *ragkit.Filter, metadataFieldNames ...string) ([]ragkit.RetrievedDoc, error) {
+	return s.mem.RetrieveFiltered(ctx, query, topK, filter, metadataFieldNames...)
+}
+
+func (s *File) RetrieveText(ctx context.Context, text string, topK int, metadataFieldNames ...string) ([]ragkit.RetrievedDoc, error) {
+	return s.mem.RetrieveText(ctx, text, topK, metadataFieldNames...)
+}
+
+func (s *File) RetrieveTextFiltered(ctx context.Context, text string, topK int, filter *ragkit.Filter, metadataFieldNames ...string) ([]ragkit.RetrievedDoc, error) {
+	return s.mem.RetrieveTextFiltered(ctx, text, topK, filter, metadataFieldNames...)
+}
+
+// RetrieveHybrid fuses vector and BM25 keyword search, as memory.Memory does
+func (s *File) RetrieveHybrid(ctx context.Context, text string, topK int, alpha float32, filter *ragkit.Filter, metadataFieldNames ...string) ([]ragkit.RetrievedDoc, error) {
+	return s.mem.RetrieveHybrid(ctx, text, topK, alpha, filter, metadataFieldNames...)
+}
+
+// Compact rewrites the file with only the live documents, dropping deleted and overwritten records.
+// The new file replaces the old one atomically.
+func (s *File) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.rewrite()
+}
+
+// rewrite replaces the file with the documents in memory. The caller must hold the lock.
+func (s *File) rewrite() error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".compact-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	if _, err := w.WriteString(magic); err != nil {
+		tmp.Close()
+		return err
+	}
+	for _, doc := range s.mem.Documents() {
+		rec, err := json.Marshal(record{Op: "put", Doc: &doc})
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		if _, err := w.Write(frame(rec)); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(s.path))
+
+	f, err := os.OpenFile(s.path, os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		f.Close()
+		return err
+	}
+	s.f.Close()
+	s.f = f
+	s.torn = nil
+	return nil
+}
+
+func (s *File) String() string {
+	return fmt.Sprintf("File(path: %s, embedder: %v)", s.path, s.embedder)
+}
+
+func (s *File) batchLimits() ragkit.BatchLimits {
+	return ragkit.BatchLimits{MaxTexts: s.batchSize}
+}
+
+// encode encodes doc as a put record.
+// The returned doc is decoded back from the record so that stored metadata
+// looks the same before and after a reload.
+func encode(doc ragkit.Document) (ragkit.Document, []byte, error) {
+	b, err := json.Marshal(record{Op: "put", Doc: &doc})
+	if err != nil {
+		return doc, nil, err
+	}
+	var rec record
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return doc, nil, err
+	}
+	return *rec.Doc, b, nil
+}
+
+// load replays the records of the file into memory and positions it for appending.
+// A torn or corrupted record at the tail, left by a crash, is truncated away;
+// a corrupted record before the tail is an error.
+func (s *File) load() error {
+	info, err := s.f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 {
+		if _, err := s.f.WriteString(magic); err != nil {
+			return err
+		}
+		return s.sync()
+	}
+
+	r := bufio.NewReader(s.f)
+	head := make([]byte, len(magic))
+	if _, err := io.ReadFull(r, head); err != nil || string(head) != magic {
+		return fmt.Errorf("not a ragkit vector store file")
+	}
+
+	ctx := context.Background()
+	offset := int64(len(magic))
+	for {
+		rec, n, err := readRecord(r, info.Size()-offset)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			// a bad record followed by others is not a torn write but damage
+			// that truncating would turn into silent data loss
+			if n > 0 && offset+n < info.Size() {
+				return fmt.Errorf("corrupted record at offset %d: %w", offset, err)
+			}
+			// torn write at the tail: drop it
+			if err := s.f.Truncate(offset); err != nil {
+				return err
+			}
+			break
+		}
+		offset += n
+
+		switch rec.Op {
+		case "put":
+			if rec.Doc == nil {
+				return fmt.Errorf("put record without document at offset %d", offset-n)
+			}
+			// later records win, as after Compact the same ID may appear again
+			if err := s.mem.Delete(ctx, rec.Doc.ID); err != nil {
+				return err
+			}
+			if _, err := s.mem.Index(ctx, *rec.Doc); err != nil {
+				return err
+			}
+		case "del":
+			if err := s.mem.Delete(ctx, rec.ID); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown record op %q at offset %d", rec.Op, offset-n)
+		}
+	}
+
+	_, err = s.f.Seek(offset, io.SeekStart)
+	return err
+}
+
+// writeFile writes to the file; tests replace it to fail writes
+var writeFile = (*os.File).Write
+
+// append writes records at the end of the file and syncs it.
+// A failed write is truncated away, so that the records appended after it
+// aren't dropped with it by the next Open.
+func (s *File) append(recs ...[]byte) error {
+	if s.torn != nil {
+		return s.torn
+	}
+	if len(recs) == 0 {
+		return nil
+	}
+
+	var buf []byte
+	for _, rec := range recs {
+		buf = append(buf, frame(rec)...)
+	}
+	end, err := s.f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := writeFile(s.f, buf); err != nil {
+		return s.undo(end, err)
+	}
+	if err := s.sync(); err != nil {
+		return s.undo(end, err)
+	}
+	return nil
+}
+
+// undo truncates the file back to end after an append failed with err, and returns err.
+// If that fails too, further writes are refused until Compact rewrites the file.
+func (s *File) undo(end int64, err error) error {
+	if terr := s.f.Truncate(end); terr != nil {
+		s.torn = fmt.Errorf("file left with a torn record: %w", terr)
+		return errors.Join(err, s.torn)
+	}
+	if _, serr := s.f.Seek(end, io.SeekStart); serr != nil {
+		s.torn = fmt.Errorf("file left with a torn record: %w", serr)
+		return errors.Join(err, s.torn)
+	}
+	return err
+}
+
+func (s *File) sync() error {
+	if s.noSync {
+		return nil
+	}
+	return s.f.Sync()
+}
+
+// frame prefixes a record with its length and CRC-32C checksum
+func frame(rec []byte) []byte {
+	buf := make([]byte, 8, 8+len(rec))
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(rec)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.Checksum(rec, crcTable))
+	return append(buf, rec...)
+}
+
+// readRecord reads a framed record of at most limit bytes, returning the number of bytes consumed.
+// It returns io.EOF only at a clean record boundary. A record that was read whole but
+// fails its checksum or decoding still reports its length, while a short read reports 0.
+func readRecord(r io.Reader, limit int64) (record, int64, error) {
+	var rec record
+
+	var head [8]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		if err == io.EOF {
+			return rec, 0, io.EOF
+		}
+		return rec, 0, fmt.Errorf("truncated record header: %w", err)
+	}
+	size := binary.LittleEndian.Uint32(head[0:4])
+	sum := binary.LittleEndian.Uint32(head[4:8])
+	if int64(size) > limit-int64(len(head)) {
+		return rec, 0, fmt.Errorf("truncated record")
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return rec, 0, fmt.Errorf("truncated record: %w", err)
+	}
+	n := int64(len(head)) + int64(size)
+	if crc32.Checksum(payload, crcTable) != sum {
+		return rec, n, fmt.Errorf("record checksum mismatch")
+	}
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return rec, n, err
+	}
+	return rec, n, nil
+}
+
+// syncDir makes a rename in dir durable. Errors are ignored as not every platform supports it.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
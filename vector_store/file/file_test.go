@@ -0,0 +1,271 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	ragkit "github.com/suapapa/go_ragkit"
+	"github.com/suapapa/go_ragkit/embedder/fake"
+	"github.com/suapapa/go_ragkit/index/hnsw"
+	"github.com/suapapa/go_ragkit/ragkittest"
+)
+
+func TestConformance(t *testing.T) {
+	ragkittest.RunVectorStoreSuite(t, func(t *testing.T) ragkit.VectorStore {
+		return open(t, filepath.Join(t.TempDir(), "store"))
+	})
+}
+
+func TestConformanceHNSW(t *testing.T) {
+	ragkittest.RunVectorStoreSuite(t, func(t *testing.T) ragkit.VectorStore {
+		s, err := Open(filepath.Join(t.TempDir(), "store"), fake.New(64), WithHNSW(hnsw.Config{Seed: 1}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Close() })
+		return s
+	})
+}
+
+func open(t *testing.T, path string) *File {
+	t.Helper()
+	s, err := Open(path, fake.New(64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// ids returns the IDs of the documents in s
+func ids(s *File) []string {
+	var ids []string
+	for _, doc := range s.mem.Documents() {
+		ids = append(ids, doc.ID)
+	}
+	return ids
+}
+
+func docs(ids ...string) []ragkit.Document {
+	docs := make([]ragkit.Document, len(ids))
+	for i, id := range ids {
+		docs[i] = ragkit.Document{ID: id, Text: "text of " + id, Metadata: map[string]any{"n": i}}
+	}
+	return docs
+}
+
+func TestReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "store")
+
+	s := open(t, path)
+	if _, err := s.Index(ctx, docs("a", "b", "c")...); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Upsert(ctx, ragkit.Document{ID: "b", Text: "new text of b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, "c"); err != nil {
+		t.Fatal(err)
+	}
+	want := s.mem.Documents()
+	s.Close()
+
+	s = open(t, path)
+	got := s.mem.Documents()
+	if len(got) != len(want) {
+		t.Fatalf("reopened with %v, want %v", ids(s), []string{"a", "b"})
+	}
+	for i := range want {
+		if got[i].ID != want[i].ID || got[i].Text != want[i].Text || !slices.Equal(got[i].Vector, want[i].Vector) {
+			t.Errorf("reopened %+v, want %+v", got[i], want[i])
+		}
+	}
+	if n, ok := got[0].Metadata["n"].(float64); !ok || n != 0 {
+		t.Errorf("metadata = %v, want n: 0", got[0].Metadata)
+	}
+
+	if err := s.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if s = open(t, path); !slices.Equal(ids(s), []string{"a", "b"}) {
+		t.Errorf("reopened after Compact with %v, want [a b]", ids(s))
+	}
+}
+
+func TestTornTail(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "store")
+
+	s := open(t, path)
+	if _, err := s.Index(ctx, docs("a", "b")...); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// a crash in the middle of writing c
+	rec := frame([]byte(`{"op":"put","doc":{"ID":"c","Text":"c","Vector":[1]}}`))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write(rec[:len(rec)/2])
+	f.Close()
+
+	s = open(t, path)
+	if !slices.Equal(ids(s), []string{"a", "b"}) {
+		t.Fatalf("reopened with %v, want [a b]", ids(s))
+	}
+	if _, err := s.Index(ctx, docs("d")...); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	if s = open(t, path); !slices.Equal(ids(s), []string{"a", "b", "d"}) {
+		t.Errorf("reopened with %v, want [a b d]", ids(s))
+	}
+}
+
+func TestCorruptedMiddleRecord(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "store")
+
+	s := open(t, path)
+	if _, err := s.Index(ctx, docs("a")...); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Index(ctx, docs("b", "c")...); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// flip a payload byte of b, which is followed by c
+	offset := info.Size()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[offset+8] ^= 0xff
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Open(path, fake.New(64))
+	if err == nil {
+		t.Fatal("Open succeeded on a corrupted middle record")
+	}
+	if want := fmt.Sprintf("offset %d", offset); !strings.Contains(err.Error(), want) {
+		t.Errorf("error = %v, want it to mention %s", err, want)
+	}
+	// nothing is truncated away
+	if after, err := os.ReadFile(path); err != nil || len(after) != len(b) {
+		t.Errorf("file is %d bytes after Open, want %d", len(after), len(b))
+	}
+}
+
+func TestUpsertPersists(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "store")
+
+	s := open(t, path)
+	if _, err := s.Index(ctx, docs("a")...); err != nil {
+		t.Fatal(err)
+	}
+	result, err := s.Upsert(ctx, ragkit.Document{ID: "a", Text: "new a"}, ragkit.Document{ID: "b", Text: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(result.Inserted, []string{"b"}) || !slices.Equal(result.Updated, []string{"a"}) {
+		t.Errorf("got %+v, want inserted [b] and updated [a]", result)
+	}
+	s.Close()
+
+	s = open(t, path)
+	got := s.mem.Documents()
+	if len(got) != 2 || got[0].Text != "new a" || got[1].Text != "b" {
+		t.Errorf("reopened with %+v, want new a and b", got)
+	}
+}
+
+func TestInvalidUpsertIsUndone(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "store")
+
+	s := open(t, path)
+	if _, err := s.Index(ctx, docs("a")...); err != nil {
+		t.Fatal(err)
+	}
+	// the records are written before memory rejects the wrong dimension
+	_, err := s.Upsert(ctx,
+		ragkit.Document{ID: "a", Text: "new a"},
+		ragkit.Document{ID: "b", Text: "b", Vector: []float32{1, 0}},
+	)
+	if err == nil {
+		t.Fatal("Upsert succeeded with a wrong dimension")
+	}
+	if got := s.mem.Documents(); len(got) != 1 || got[0].Text != "text of a" {
+		t.Errorf("stored %+v after a failed Upsert, want a unchanged", got)
+	}
+	s.Close()
+
+	s = open(t, path)
+	if got := s.mem.Documents(); len(got) != 1 || got[0].Text != "text of a" {
+		t.Errorf("reopened with %+v, want a unchanged", got)
+	}
+}
+
+func TestFailedAppendIsUndone(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "store")
+	s := open(t, path)
+	if _, err := s.Index(ctx, docs("a")...); err != nil {
+		t.Fatal(err)
+	}
+
+	// write half of the records, as a full disk would
+	errFull := errors.New("no space left on device")
+	writeFile = func(f *os.File, b []byte) (int, error) {
+		n, _ := f.Write(b[:len(b)/2])
+		return n, errFull
+	}
+	_, err := s.Index(ctx, docs("b")...)
+	_, upsertErr := s.Upsert(ctx, docs("c")...)
+	deleteErr := s.Delete(ctx, "a")
+	writeFile = (*os.File).Write
+
+	for _, err := range []error{err, upsertErr, deleteErr} {
+		if !errors.Is(err, errFull) {
+			t.Errorf("got error %v, want %v", err, errFull)
+		}
+	}
+	if !slices.Equal(ids(s), []string{"a"}) {
+		t.Errorf("after failed writes: %v, want [a]", ids(s))
+	}
+
+	if _, err := s.Index(ctx, docs("d")...); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if s = open(t, path); !slices.Equal(ids(s), []string{"a", "d"}) {
+		t.Errorf("reopened with %v, want [a d]", ids(s))
+	}
+}
+
+func TestNotAStoreFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store")
+	os.WriteFile(path, []byte("hello, world"), 0o644)
+	if _, err := Open(path, nil); err == nil {
+		t.Error("opening a foreign file: no error")
+	}
+}
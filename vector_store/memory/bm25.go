@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// BM25 parameters, the usual defaults of Lucene and Elasticsearch
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// termIndex keeps the term statistics of the stored documents for BM25 keyword search
+type termIndex struct {
+	docs     map[string]docTerms
+	df       map[string]int // number of documents containing each term
+	totalLen int
+}
+
+type docTerms struct {
+	freq   map[string]int
+	length int
+}
+
+func newTermIndex() *termIndex {
+	return &termIndex{
+		docs: make(map[string]docTerms),
+		df:   make(map[string]int),
+	}
+}
+
+// add indexes the terms of text under id, replacing the previous text of id
+func (ti *termIndex) add(id, text string) {
+	ti.remove(id)
+
+	terms := tokenize(text)
+	dt := docTerms{freq: make(map[string]int), length: len(terms)}
+	for _, term := range terms {
+		if dt.freq[term] == 0 {
+			ti.df[term]++
+		}
+		dt.freq[term]++
+	}
+	ti.docs[id] = dt
+	ti.totalLen += dt.length
+}
+
+func (ti *termIndex) remove(id string) {
+	dt, ok := ti.docs[id]
+	if !ok {
+		return
+	}
+	for term := range dt.freq {
+		if ti.df[term]--; ti.df[term] == 0 {
+			delete(ti.df, term)
+		}
+	}
+	ti.totalLen -= dt.length
+	delete(ti.docs, id)
+}
+
+// score returns the BM25 score of the document id for the query terms, 0 if none matches
+func (ti *termIndex) score(id string, query []string) float64 {
+	dt, ok := ti.docs[id]
+	if !ok || dt.length == 0 {
+		return 0
+	}
+
+	n := float64(len(ti.docs))
+	avgLen := float64(ti.totalLen) / n
+	var score float64
+	for _, term := range query {
+		tf := float64(dt.freq[term])
+		if tf == 0 {
+			continue
+		}
+		df := float64(ti.df[term])
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*float64(dt.length)/avgLen))
+	}
+	return score
+}
+
+// tokenize splits text into lowercased runs of letters and digits
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// queryTerms returns the distinct terms of a query
+func queryTerms(text string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, term := range tokenize(text) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
@@ -0,0 +1,439 @@
+// Package memory provides an in-memory ragkit.VectorStore with exact (brute-force) search.
+// It needs no external service, so it fits unit tests and small corpora.
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	ragkit "github.com/suapapa/go_ragkit"
+	"github.com/suapapa/go_ragkit/index/hnsw"
+)
+
+var (
+	_ ragkit.VectorStore       = &Memory{}
+	_ ragkit.FilteredRetriever = &Memory{}
+	_ ragkit.Upserter          = &Memory{}
+	_ ragkit.HybridRetriever   = &Memory{}
+)
+
+// minCompaction is the number of tombstones below which the HNSW graph is never rebuilt
+const minCompaction = 64
+
+type Memory struct {
+	embedder  ragkit.Embedder
+	metric    ragkit.DistanceMetric
+	dimension int
+	batchSize int
+
+	hnswConfig *hnsw.Config
+	ann        *hnsw.Index // approximate index, nil for exact search
+
+	docs  map[string]ragkit.Document
+	terms *termIndex // for keyword search
+	mu    sync.RWMutex
+}
+
+// Option configures a Memory store
+type Option func(*Memory)
+
+// WithMetric sets the distance metric used for search (default: cosine)
+func WithMetric(metric ragkit.DistanceMetric) Option {
+	return func(m *Memory) {
+		m.metric = metric
+	}
+}
+
+// WithDimension fixes the dimension of vectors (default: the dimension of the first indexed vector)
+func WithDimension(dimension int) Option {
+	return func(m *Memory) {
+		m.dimension = dimension
+	}
+}
+
+// WithBatchSize sets the number of documents embedded at once (default: ragkit.DefaultBatchSize)
+func WithBatchSize(n int) Option {
+	return func(m *Memory) {
+		m.batchSize = n
+	}
+}
+
+// WithHNSW searches with an HNSW approximate nearest neighbour index instead of scanning every vector.
+// The metric of cfg is overridden by the metric of the store.
+// Searches that find fewer than topK matches in the graph fall back to an exact scan.
+// The graph is rebuilt once deleted and replaced documents outnumber the live ones.
+func WithHNSW(cfg hnsw.Config) Option {
+	return func(m *Memory) {
+		m.hnswConfig = &cfg
+	}
+}
+
+// New creates an empty in-memory vector store.
+// embedder may be nil if every document is indexed with a Vector and only Retrieve is used.
+func New(embedder ragkit.Embedder, opts ...Option) *Memory {
+	m := &Memory{
+		embedder:  embedder,
+		metric:    ragkit.DistanceCosine,
+		batchSize: ragkit.DefaultBatchSize,
+		docs:      make(map[string]ragkit.Document),
+		terms:     newTermIndex(),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.hnswConfig != nil {
+		cfg := *m.hnswConfig
+		cfg.Metric = m.metric
+		m.ann = hnsw.New(cfg)
+	}
+	return m
+}
+
+// Index indexes docs, skipping the ones failing to embed, of a wrong dimension or with an existing ID.
+// Those are reported by a *ragkit.IndexError.
+func (m *Memory) Index(ctx context.Context, docs ...ragkit.Document) ([]string, error) {
+	docs, failed := ragkit.EmbedDocs(ctx, m.embedder, m.batchLimits(), docs...)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ids []string
+	for i, doc := range docs {
+		if doc.Vector == nil {
+			continue // failed to embed
+		}
+		if err := ctx.Err(); err != nil {
+			failed.Add(i, doc.ID, err)
+			continue
+		}
+		if err := m.checkDimension(doc.Vector); err != nil {
+			failed.Add(i, doc.ID, err)
+			continue
+		}
+		if _, ok := m.docs[doc.ID]; ok {
+			failed.Add(i, doc.ID, ragkit.ErrDocumentExists)
+			continue
+		}
+		m.put(doc)
+		ids = append(ids, doc.ID)
+	}
+	return ids, failed.Err()
+}
+
+// Upsert indexes docs, replacing the documents of existing IDs.
+// Either all documents are upserted or, on error, none.
+func (m *Memory) Upsert(ctx context.Context, docs ...ragkit.Document) (ragkit.UpsertResult, error) {
+	var result ragkit.UpsertResult
+
+	docs, err := ragkit.PrepareDocs(ctx, m.embedder, m.batchLimits(), docs...)
+	if err != nil {
+		return result, err
+	}
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// validate everything first so that nothing is stored on error
+	dimension := m.dimension
+	for _, doc := range docs {
+		if len(doc.Vector) == 0 {
+			return result, fmt.Errorf("empty vector")
+		}
+		if dimension != 0 && len(doc.Vector) != dimension {
+			return result, fmt.Errorf("expected %d dimensions, not %d", dimension, len(doc.Vector))
+		}
+		dimension = len(doc.Vector)
+	}
+
+	seen := make(map[string]bool)
+	for _, doc := range docs {
+		if _, ok := m.docs[doc.ID]; ok || seen[doc.ID] {
+			result.Updated = append(result.Updated, doc.ID)
+		} else {
+			result.Inserted = append(result.Inserted, doc.ID)
+		}
+		seen[doc.ID] = true
+		m.put(doc)
+	}
+	return result, nil
+}
+
+func (m *Memory) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.docs, id)
+	m.terms.remove(id)
+	if m.ann != nil && m.ann.Delete(id) {
+		m.compact()
+	}
+	return nil
+}
+
+func (m *Memory) Exists(ctx context.Context, id string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, ok := m.docs[id]
+	return ok, nil
+}
+
+// Len returns the number of stored documents
+func (m *Memory) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.docs)
+}
+
+// Documents returns copies of all stored documents ordered by ID
+func (m *Memory) Documents() []ragkit.Document {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	docs := make([]ragkit.Document, 0, len(m.docs))
+	for _, doc := range m.docs {
+		doc.Vector = slices.Clone(doc.Vector)
+		doc.Metadata = maps.Clone(doc.Metadata)
+		docs = append(docs, doc)
+	}
+	slices.SortFunc(docs, func(a, b ragkit.Document) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return docs
+}
+
+func (m *Memory) Retrieve(ctx context.Context, query []float32, topK int, metadataFieldNames ...string) ([]ragkit.RetrievedDoc, error) {
+	return m.RetrieveFiltered(ctx, query, topK, nil, metadataFieldNames...)
+}
+
+func (m *Memory) RetrieveFiltered(ctx context.Context, query []float32, topK int, filter *ragkit.Filter, metadataFieldNames ...string) ([]ragkit.RetrievedDoc, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if err := m.checkDimension(query); err != nil {
+		return nil, err
+	}
+	if topK <= 0 {
+		return nil, nil
+	}
+
+	if m.ann != nil {
+		var accept func(id string) bool
+		if filter != nil {
+			accept = func(id string) bool { return filter.Match(m.docs[id].Metadata) }
+		}
+		found := m.ann.Search(query, topK, 0, accept)
+		if len(found) == topK || (filter == nil && len(found) == len(m.docs)) {
+			results := make([]ragkit.RetrievedDoc, len(found))
+			for i, r := range found {
+				results[i] = m.retrieved(m.docs[r.ID], r.Distance, metadataFieldNames)
+			}
+			return results, nil
+		}
+	}
+
+	var results []ragkit.RetrievedDoc
+	for _, doc := range m.docs {
+		if !filter.Match(doc.Metadata) {
+			continue
+		}
+		results = append(results, m.retrieved(doc, ragkit.Distance(m.metric, query, doc.Vector), metadataFieldNames))
+	}
+	sortResults(results)
+
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	return results, nil
+}
+
+func (m *Memory) RetrieveText(ctx context.Context, text string, topK int, metadataFieldNames ...string) ([]ragkit.RetrievedDoc, error) {
+	return m.RetrieveTextFiltered(ctx, text, topK, nil, metadataFieldNames...)
+}
+
+func (m *Memory) RetrieveTextFiltered(ctx context.Context, text string, topK int, filter *ragkit.Filter, metadataFieldNames ...string) ([]ragkit.RetrievedDoc, error) {
+	if m.embedder == nil {
+		return nil, fmt.Errorf("no embedder to embed the query")
+	}
+	vectors, err := m.embedder.EmbedTexts(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	return m.RetrieveFiltered(ctx, vectors[0], topK, filter, metadataFieldNames...)
+}
+
+// RetrieveHybrid fuses the vector search of text with a BM25 keyword search over the document texts.
+// Each search returns topK candidates, merged by ragkit.FuseRelativeScores.
+// At alpha 0 no query vector is embedded, so results carry no Distance.
+func (m *Memory) RetrieveHybrid(ctx context.Context, text string, topK int, alpha float32, filter *ragkit.Filter, metadataFieldNames ...string) ([]ragkit.RetrievedDoc, error) {
+	if err := ragkit.ValidateAlpha(alpha); err != nil {
+		return nil, err
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if topK <= 0 {
+		return nil, nil
+	}
+
+	var query []float32
+	var vectorResults []ragkit.RetrievedDoc
+	if alpha > 0 {
+		if m.embedder == nil {
+			return nil, fmt.Errorf("no embedder to embed the query")
+		}
+		vectors, err := m.embedder.EmbedTexts(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		query = vectors[0]
+		vectorResults, err = m.RetrieveFiltered(ctx, query, topK, filter, metadataFieldNames...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var keywordResults []ragkit.RetrievedDoc
+	if alpha < 1 {
+		keywordResults = m.retrieveKeywords(text, query, topK, filter, metadataFieldNames)
+	}
+	return ragkit.FuseRelativeScores(vectorResults, keywordResults, alpha, topK), nil
+}
+
+// retrieveKeywords returns the topK documents best matching the words of text by BM25, scored by it.
+// Their distance to query is set if query isn't nil.
+func (m *Memory) retrieveKeywords(text string, query []float32, topK int, filter *ragkit.Filter, metadataFieldNames []string) []ragkit.RetrievedDoc {
+	terms := queryTerms(text)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var results []ragkit.RetrievedDoc
+	for id, doc := range m.docs {
+		score := m.terms.score(id, terms)
+		if score == 0 || !filter.Match(doc.Metadata) {
+			continue
+		}
+		var distance float32
+		if query != nil {
+			distance = ragkit.Distance(m.metric, query, doc.Vector)
+		}
+		r := m.retrieved(doc, distance, metadataFieldNames)
+		r.Score = float32(score)
+		results = append(results, r)
+	}
+	slices.SortFunc(results, func(a, b ragkit.RetrievedDoc) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	return results
+}
+
+func (m *Memory) String() string {
+	if m.ann != nil {
+		return fmt.Sprintf("Memory(metric: %s, index: hnsw, embedder: %v)", m.metric, m.embedder)
+	}
+	return fmt.Sprintf("Memory(metric: %s, embedder: %v)", m.metric, m.embedder)
+}
+
+func (m *Memory) batchLimits() ragkit.BatchLimits {
+	return ragkit.BatchLimits{MaxTexts: m.batchSize}
+}
+
+// checkDimension validates vec against the dimension of the store. The caller must hold the lock.
+func (m *Memory) checkDimension(vec []float32) error {
+	if len(vec) == 0 {
+		return fmt.Errorf("empty vector")
+	}
+	if m.dimension != 0 && len(vec) != m.dimension {
+		return fmt.Errorf("expected %d dimensions, not %d", m.dimension, len(vec))
+	}
+	return nil
+}
+
+// put stores a copy of doc so callers can't mutate it afterwards. The caller must hold the write lock.
+func (m *Memory) put(doc ragkit.Document) {
+	doc.Vector = slices.Clone(doc.Vector)
+	doc.Metadata = maps.Clone(doc.Metadata)
+	if m.dimension == 0 {
+		m.dimension = len(doc.Vector)
+	}
+	m.docs[doc.ID] = doc
+	m.terms.add(doc.ID, doc.Text)
+	if m.ann != nil {
+		m.ann.Insert(doc.ID, doc.Vector) // only fails on dimension mismatch, which is checked beforehand
+		m.compact()
+	}
+}
+
+// compact rebuilds the HNSW graph once its tombstones outnumber the live vectors and minCompaction,
+// as they slow searches down. The caller must hold the write lock.
+func (m *Memory) compact() {
+	if t := m.ann.Tombstones(); t >= minCompaction && t > m.ann.Len() {
+		m.ann.Compact()
+	}
+}
+
+func (m *Memory) retrieved(doc ragkit.Document, distance float32, metadataFieldNames []string) ragkit.RetrievedDoc {
+	metadata := maps.Clone(doc.Metadata)
+	if len(metadataFieldNames) > 0 && metadata != nil {
+		metadata = make(map[string]any, len(metadataFieldNames))
+		for _, name := range metadataFieldNames {
+			if v, ok := doc.Metadata[name]; ok {
+				metadata[name] = v
+			}
+		}
+	}
+
+	return ragkit.RetrievedDoc{
+		ID:       doc.ID,
+		Score:    ragkit.ScoreFromDistance(m.metric, distance),
+		Distance: distance,
+		Metric:   m.metric,
+		Vector:   slices.Clone(doc.Vector),
+		Text:     doc.Text,
+		Metadata: metadata,
+	}
+}
+
+// sortResults orders results by distance, breaking ties by ID for stable output
+func sortResults(results []ragkit.RetrievedDoc) {
+	slices.SortFunc(results, func(a, b ragkit.RetrievedDoc) int {
+		if c := cmp.Compare(a.Distance, b.Distance); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+}
@@ -0,0 +1,315 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"testing"
+
+	ragkit "github.com/suapapa/go_ragkit"
+	"github.com/suapapa/go_ragkit/embedder/fake"
+	"github.com/suapapa/go_ragkit/index/hnsw"
+	"github.com/suapapa/go_ragkit/ragkittest"
+)
+
+func TestConformance(t *testing.T) {
+	ragkittest.RunVectorStoreSuite(t, func(t *testing.T) ragkit.VectorStore {
+		return New(fake.New(64))
+	})
+}
+
+func TestConformanceHNSW(t *testing.T) {
+	ragkittest.RunVectorStoreSuite(t, func(t *testing.T) ragkit.VectorStore {
+		return New(fake.New(64), WithHNSW(hnsw.Config{Seed: 1}))
+	})
+}
+
+func TestHNSWUpsertKeepsTopK(t *testing.T) {
+	ctx := context.Background()
+	m := New(fake.New(32), WithHNSW(hnsw.Config{Seed: 1}))
+
+	docs := make([]ragkit.Document, 500)
+	for i := range docs {
+		docs[i] = ragkit.Document{ID: fmt.Sprint(i), Text: fmt.Sprintf("document %d about topic %d", i, i%7)}
+	}
+	if _, err := m.Index(ctx, docs...); err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		if _, err := m.Upsert(ctx, docs...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := m.RetrieveText(ctx, "topic 3", 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 50 {
+		t.Errorf("got %d results, want 50", len(results))
+	}
+	if n := m.ann.Tombstones(); n > m.ann.Len() {
+		t.Errorf("%d tombstones for %d documents, want the graph compacted", n, m.ann.Len())
+	}
+
+	for _, doc := range docs[:400] {
+		if err := m.Delete(ctx, doc.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if results, _ := m.RetrieveText(ctx, "topic 3", 150); len(results) != 100 {
+		t.Errorf("after deletes: got %d results, want 100", len(results))
+	}
+	if n := m.ann.Tombstones(); n > m.ann.Len() && n >= minCompaction {
+		t.Errorf("%d tombstones for %d documents, want the graph compacted", n, m.ann.Len())
+	}
+}
+
+func TestHybridKeywordOnly(t *testing.T) {
+	ctx := context.Background()
+	m := New(fake.New(32), WithMetric(ragkit.DistanceL2))
+	m.Index(ctx, ragkit.MakeDocsFromTexts([]string{"red apples", "green pears", "red cars"}, nil)...)
+
+	results, err := m.RetrieveHybrid(ctx, "red", 5, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	for _, r := range results {
+		if r.Metric != ragkit.DistanceL2 || r.Score < 0 || r.Score > 1 {
+			t.Errorf("%q: Metric %q, Score %v, want l2 and a score in [0, 1]", r.Text, r.Metric, r.Score)
+		}
+	}
+}
+
+func TestExactSearchPerMetric(t *testing.T) {
+	ctx := context.Background()
+	query := []float32{1, 0}
+	docs := []ragkit.Document{
+		{ID: "a", Text: "a", Vector: []float32{1, 0}},     // cosine 1, dot 1, l2 0
+		{ID: "b", Text: "b", Vector: []float32{0.6, 0.8}}, // cosine 0.6, dot 0.6, l2 0.89
+		{ID: "c", Text: "c", Vector: []float32{2, 1}},     // cosine 0.89, dot 2, l2 1.41
+	}
+
+	for metric, want := range map[ragkit.DistanceMetric][]string{
+		ragkit.DistanceCosine: {"a", "c", "b"},
+		ragkit.DistanceDot:    {"c", "a", "b"},
+		ragkit.DistanceL2:     {"a", "b", "c"},
+	} {
+		t.Run(string(metric), func(t *testing.T) {
+			m := New(nil, WithMetric(metric))
+			if _, err := m.Index(ctx, docs...); err != nil {
+				t.Fatal(err)
+			}
+			results, err := m.Retrieve(ctx, query, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != len(want) {
+				t.Fatalf("got %d results, want %d", len(results), len(want))
+			}
+			for i, r := range results {
+				if r.ID != want[i] {
+					t.Errorf("result %d is %s, want %s", i, r.ID, want[i])
+				}
+				vec := docs[r.ID[0]-'a'].Vector
+				if d := ragkit.Distance(metric, query, vec); math.Abs(float64(r.Distance-d)) > 1e-6 {
+					t.Errorf("%s: Distance %v, want %v", r.ID, r.Distance, d)
+				}
+				if r.Metric != metric || r.Score < 0 || r.Score > 1 {
+					t.Errorf("%s: Metric %q, Score %v, want %s and a score in [0, 1]", r.ID, r.Metric, r.Score, metric)
+				}
+				if i > 0 && r.Score > results[i-1].Score {
+					t.Errorf("%s scores %v, above the previous %v", r.ID, r.Score, results[i-1].Score)
+				}
+			}
+		})
+	}
+}
+
+func TestDimension(t *testing.T) {
+	ctx := context.Background()
+	m := New(nil, WithDimension(2))
+
+	ids, err := m.Index(ctx,
+		ragkit.Document{ID: "a", Vector: []float32{1, 0}},
+		ragkit.Document{ID: "b", Vector: []float32{1, 0, 0}},
+		ragkit.Document{ID: "c", Vector: []float32{}},
+	)
+	var indexErr *ragkit.IndexError
+	if !errors.As(err, &indexErr) || !indexErr.Has(1) || !indexErr.Has(2) || indexErr.Has(0) {
+		t.Fatalf("err = %v, want b and c to fail", err)
+	}
+	if len(ids) != 1 || ids[0] != "a" {
+		t.Errorf("indexed %v, want [a]", ids)
+	}
+	if _, err := m.Retrieve(ctx, []float32{1, 0, 0}, 1); err == nil {
+		t.Error("query of a wrong dimension accepted")
+	}
+}
+
+func TestStoresCopies(t *testing.T) {
+	ctx := context.Background()
+	m := New(nil)
+
+	doc := ragkit.Document{ID: "a", Vector: []float32{1, 0}, Metadata: map[string]any{"k": "v"}}
+	if _, err := m.Index(ctx, doc); err != nil {
+		t.Fatal(err)
+	}
+	doc.Vector[0] = -1
+	doc.Metadata["k"] = "changed"
+
+	results, err := m.Retrieve(ctx, []float32{1, 0}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results[0].Vector[1] = 5
+	results[0].Metadata["k"] = "changed again"
+
+	stored := m.Documents()[0]
+	if stored.Vector[0] != 1 || stored.Vector[1] != 0 || stored.Metadata["k"] != "v" {
+		t.Errorf("stored %+v, want it unaffected by callers", stored)
+	}
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"exact", nil},
+		{"hnsw", []Option{WithHNSW(hnsw.Config{Seed: 1})}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := New(fake.New(32), tc.opts...)
+
+			const workers, perWorker = 8, 20
+			var wg sync.WaitGroup
+			errs := make(chan error, workers)
+			for w := range workers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := range perWorker {
+						id := fmt.Sprintf("%d-%d", w, i)
+						doc := ragkit.Document{ID: id, Text: fmt.Sprintf("worker %d document %d", w, i)}
+						if _, err := m.Index(ctx, doc); err != nil {
+							errs <- err
+							return
+						}
+						if _, err := m.Upsert(ctx, doc); err != nil {
+							errs <- err
+							return
+						}
+						if _, err := m.RetrieveText(ctx, "document", 5); err != nil {
+							errs <- err
+							return
+						}
+						if i%2 == 1 {
+							if err := m.Delete(ctx, id); err != nil {
+								errs <- err
+								return
+							}
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				t.Error(err)
+			}
+
+			if n := m.Len(); n != workers*perWorker/2 {
+				t.Errorf("Len() = %d, want %d", n, workers*perWorker/2)
+			}
+			if ok, _ := m.Exists(ctx, "0-1"); ok {
+				t.Error("deleted document exists")
+			}
+			if ok, _ := m.Exists(ctx, "7-18"); !ok {
+				t.Error("indexed document is missing")
+			}
+		})
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	ctx := context.Background()
+	m := New(fake.New(16))
+	if _, err := m.Index(ctx, ragkit.Document{ID: "a", Text: "old a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := m.Upsert(ctx,
+		ragkit.Document{ID: "a", Text: "new a"},
+		ragkit.Document{ID: "b", Text: "first b"},
+		ragkit.Document{ID: "b", Text: "second b"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a repeated ID is inserted once, then updated by its later occurrence
+	if !slices.Equal(result.Inserted, []string{"b"}) || !slices.Equal(result.Updated, []string{"a", "b"}) {
+		t.Errorf("got %+v, want inserted [b] and updated [a b]", result)
+	}
+	docs := m.Documents()
+	if len(docs) != 2 || docs[0].Text != "new a" || docs[1].Text != "second b" {
+		t.Errorf("stored %+v, want the last text of a and b", docs)
+	}
+}
+
+func TestUpsertIsAtomic(t *testing.T) {
+	ctx := context.Background()
+	errEmbed := errors.New("embedding failed")
+
+	tests := []struct {
+		name     string
+		embedder ragkit.Embedder
+		docs     []ragkit.Document
+	}{
+		{"wrong dimension", fake.New(16), []ragkit.Document{
+			{ID: "a", Text: "new a"},
+			{ID: "c", Text: "c", Vector: []float32{1, 0}},
+		}},
+		{"empty vector", fake.New(16), []ragkit.Document{
+			{ID: "c", Text: "c"},
+			{ID: "d", Text: "d", Vector: []float32{}},
+		}},
+		{"embedding failure", fake.New(16, fake.WithErrorEvery(2, errEmbed)), []ragkit.Document{
+			{ID: "a", Text: "new a"},
+			{ID: "c", Text: "c"},
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m := New(tc.embedder)
+			if _, err := m.Index(ctx, ragkit.Document{ID: "a", Text: "old a"}, ragkit.Document{ID: "b", Text: "b"}); err != nil {
+				t.Fatal(err)
+			}
+			before := m.Documents()
+
+			result, err := m.Upsert(ctx, tc.docs...)
+			if err == nil {
+				t.Fatal("Upsert succeeded")
+			}
+			if len(result.Inserted)+len(result.Updated) != 0 {
+				t.Errorf("failed Upsert reported %+v", result)
+			}
+			after := m.Documents()
+			if len(after) != len(before) {
+				t.Fatalf("stored %d documents after a failed Upsert, want %d", len(after), len(before))
+			}
+			for i := range before {
+				if after[i].ID != before[i].ID || after[i].Text != before[i].Text {
+					t.Errorf("stored %+v after a failed Upsert, want %+v", after[i], before[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package pgvector
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+// compileFilter translates filter into a SQL predicate over the metadata JSONB column.
+// Operands are appended to args and referenced as positional parameters.
+func compileFilter(f *ragkit.Filter, args *[]any) (string, error) {
+	param := func(v any) string {
+		*args = append(*args, v)
+		return fmt.Sprintf("$%d", len(*args))
+	}
+
+	switch f.Op {
+	case ragkit.FilterEq, ragkit.FilterNeq:
+		v, err := ragkit.NormalizeFilterValue(f.Value)
+		if err != nil {
+			return "", err
+		}
+		// @> matches a scalar nested under a key only as itself, so lists containing it
+		// are matched by a second containment of the scalar wrapped in an array
+		scalar, err := json.Marshal(map[string]any{f.Key: v})
+		if err != nil {
+			return "", err
+		}
+		list, err := json.Marshal(map[string]any{f.Key: []any{v}})
+		if err != nil {
+			return "", err
+		}
+		pred := fmt.Sprintf("COALESCE(metadata @> %s::jsonb OR metadata @> %s::jsonb, false)", param(string(scalar)), param(string(list)))
+		if f.Op == ragkit.FilterNeq {
+			pred = "NOT " + pred
+		}
+		return pred, nil
+
+	case ragkit.FilterIn:
+		var preds []string
+		for _, v := range f.Values {
+			pred, err := compileFilter(ragkit.Eq(f.Key, v), args)
+			if err != nil {
+				return "", err
+			}
+			preds = append(preds, pred)
+		}
+		return "(" + strings.Join(preds, " OR ") + ")", nil
+
+	case ragkit.FilterRange:
+		var preds []string
+		for _, b := range []struct {
+			op    string
+			bound any
+		}{{">=", f.Min}, {"<=", f.Max}} {
+			if b.bound == nil {
+				continue
+			}
+			v, err := ragkit.NormalizeFilterValue(b.bound)
+			if err != nil {
+				return "", err
+			}
+			key := param(f.Key) + "::text"
+			switch v := v.(type) {
+			case float64:
+				// guard the cast so non-numeric values don't fail the whole query
+				preds = append(preds, fmt.Sprintf(
+					"CASE WHEN jsonb_typeof(metadata->%s) = 'number' THEN (metadata->>%s)::numeric %s %s ELSE false END",
+					key, key, b.op, param(v)))
+			case string:
+				preds = append(preds, fmt.Sprintf(
+					"CASE WHEN jsonb_typeof(metadata->%s) = 'string' THEN (metadata->>%s) COLLATE \"C\" %s %s ELSE false END",
+					key, key, b.op, param(v)))
+			default:
+				return "", fmt.Errorf("filter range %q: unsupported bound type %T", f.Key, v)
+			}
+		}
+		return "(" + strings.Join(preds, " AND ") + ")", nil
+
+	case ragkit.FilterExists:
+		return fmt.Sprintf("COALESCE(metadata ? %s::text, false)", param(f.Key)), nil
+
+	case ragkit.FilterAnd, ragkit.FilterOr:
+		var preds []string
+		for _, sub := range f.Filters {
+			pred, err := compileFilter(sub, args)
+			if err != nil {
+				return "", err
+			}
+			preds = append(preds, pred)
+		}
+		return "(" + strings.Join(preds, " "+strings.ToUpper(string(f.Op))+" ") + ")", nil
+
+	case ragkit.FilterNot:
+		pred, err := compileFilter(f.Filters[0], args)
+		if err != nil {
+			return "", err
+		}
+		return "NOT " + pred, nil
+	}
+	return "", fmt.Errorf("unknown filter op: %q", f.Op)
+}
@@ -0,0 +1,69 @@
+package pgvector
+
+import (
+	"reflect"
+	"testing"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+func TestCompileFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   *ragkit.Filter
+		want     string
+		wantArgs []any
+	}{
+		{"eq", ragkit.Eq("source", "a.txt"),
+			`COALESCE(metadata @> $1::jsonb OR metadata @> $2::jsonb, false)`,
+			[]any{`{"source":"a.txt"}`, `{"source":["a.txt"]}`}},
+		{"neq", ragkit.Neq("page", 3),
+			`NOT COALESCE(metadata @> $1::jsonb OR metadata @> $2::jsonb, false)`,
+			[]any{`{"page":3}`, `{"page":[3]}`}},
+		{"in", ragkit.In("lang", "ko", "en"),
+			`(COALESCE(metadata @> $1::jsonb OR metadata @> $2::jsonb, false) OR COALESCE(metadata @> $3::jsonb OR metadata @> $4::jsonb, false))`,
+			[]any{`{"lang":"ko"}`, `{"lang":["ko"]}`, `{"lang":"en"}`, `{"lang":["en"]}`}},
+		{"numeric range", ragkit.Range("year", 2000, nil),
+			`(CASE WHEN jsonb_typeof(metadata->$1::text) = 'number' THEN (metadata->>$1::text)::numeric >= $2 ELSE false END)`,
+			[]any{"year", 2000.0}},
+		{"string range", ragkit.Range("date", "2024-01-01", "2024-12-31"),
+			`(CASE WHEN jsonb_typeof(metadata->$1::text) = 'string' THEN (metadata->>$1::text) COLLATE "C" >= $2 ELSE false END` +
+				` AND CASE WHEN jsonb_typeof(metadata->$3::text) = 'string' THEN (metadata->>$3::text) COLLATE "C" <= $4 ELSE false END)`,
+			[]any{"date", "2024-01-01", "date", "2024-12-31"}},
+		{"exists", ragkit.Exists("author"),
+			`COALESCE(metadata ? $1::text, false)`,
+			[]any{"author"}},
+		{"and not", ragkit.And(ragkit.Eq("draft", false), ragkit.Not(ragkit.Exists("deleted"))),
+			`(COALESCE(metadata @> $1::jsonb OR metadata @> $2::jsonb, false) AND NOT COALESCE(metadata ? $3::text, false))`,
+			[]any{`{"draft":false}`, `{"draft":[false]}`, "deleted"}},
+		{"or", ragkit.Or(ragkit.Eq("a", 1), ragkit.Eq("b", 2)),
+			`(COALESCE(metadata @> $1::jsonb OR metadata @> $2::jsonb, false) OR COALESCE(metadata @> $3::jsonb OR metadata @> $4::jsonb, false))`,
+			[]any{`{"a":1}`, `{"a":[1]}`, `{"b":2}`, `{"b":[2]}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args []any
+			got, err := compileFilter(tt.filter, &args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestCompileFilterContinuesArgs(t *testing.T) {
+	args := []any{"query vector", 10}
+	got, err := compileFilter(ragkit.Eq("k", "v"), &args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `COALESCE(metadata @> $3::jsonb OR metadata @> $4::jsonb, false)`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
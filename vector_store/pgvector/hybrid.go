@@ -0,0 +1,156 @@
+package pgvector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pgvector/pgvector-go"
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+// WithTextSearch enables keyword search for RetrieveHybrid with the text search configuration
+// config, like "simple" (no stemming, fits codes and names) or "english".
+// It adds a generated tsvector column "text_search" with a GIN index to the table,
+// which rewrites an existing table once.
+func WithTextSearch(config string) Option {
+	return func(p *PGVector) {
+		p.textSearch = config
+	}
+}
+
+// validTextSearchConfig reports whether config can be written into SQL as is
+func validTextSearchConfig(config string) bool {
+	for _, r := range config {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// ensureTextSearch adds the keyword column and its index if they don't exist
+func (p *PGVector) ensureTextSearch(ctx context.Context) error {
+	_, err := p.pool.Exec(ctx, fmt.Sprintf(`
+		ALTER TABLE %s ADD COLUMN IF NOT EXISTS text_search tsvector
+		GENERATED ALWAYS AS (to_tsvector('%s'::regconfig, text)) STORED
+	`, p.className, p.textSearch))
+	if err != nil {
+		return fmt.Errorf("failed to add text search column: %w", err)
+	}
+
+	_, err = p.pool.Exec(ctx, fmt.Sprintf(`
+		CREATE INDEX IF NOT EXISTS %s_text_search_idx ON %s USING gin (text_search)
+	`, p.className, p.className))
+	if err != nil {
+		return fmt.Errorf("failed to create text search index: %w", err)
+	}
+	return nil
+}
+
+// RetrieveHybrid fuses the vector search of text with a keyword search ranked by ts_rank.
+// Each search returns topK candidates, merged by ragkit.FuseRelativeScores.
+// Keyword search needs WithTextSearch; a document matches if it contains any word of text.
+// At alpha 0 no query vector is embedded, so results carry no Distance.
+func (p *PGVector) RetrieveHybrid(ctx context.Context, text string, topK int, alpha float32, filter *ragkit.Filter, metadataFieldNames ...string) ([]ragkit.RetrievedDoc, error) {
+	if err := ragkit.ValidateAlpha(alpha); err != nil {
+		return nil, err
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	if alpha < 1 && p.textSearch == "" {
+		return nil, fmt.Errorf("keyword search isn't enabled, see WithTextSearch")
+	}
+	if topK <= 0 {
+		return nil, ctx.Err()
+	}
+
+	var query []float32
+	var vectorResults []ragkit.RetrievedDoc
+	if alpha > 0 {
+		vectors, err := p.embedder.EmbedTexts(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		query = vectors[0]
+		vectorResults, err = p.RetrieveFiltered(ctx, query, topK, filter, metadataFieldNames...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var keywordResults []ragkit.RetrievedDoc
+	if alpha < 1 {
+		var err error
+		keywordResults, err = p.retrieveKeywords(ctx, text, query, topK, filter)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ragkit.FuseRelativeScores(vectorResults, keywordResults, alpha, topK), nil
+}
+
+// retrieveKeywords returns the topK documents best matching the words of text, scored by ts_rank
+// normalized to [0, 1) as rank / (rank + 1). Their distance to query is set if query isn't nil.
+func (p *PGVector) retrieveKeywords(ctx context.Context, text string, query []float32, topK int, filter *ragkit.Filter) ([]ragkit.RetrievedDoc, error) {
+	keywords := keywordQuery(text)
+	if keywords == "" {
+		return nil, nil
+	}
+
+	args := []any{keywords, topK}
+	where := "WHERE text_search @@ q"
+	if filter != nil {
+		pred, err := compileFilter(filter, &args)
+		if err != nil {
+			return nil, err
+		}
+		where += " AND " + pred
+	}
+
+	rows, err := p.pool.Query(ctx, fmt.Sprintf(`
+		SELECT id, text, metadata, embedding, ts_rank(text_search, q, 32) AS rank
+		FROM %s, websearch_to_tsquery('%s'::regconfig, $1) q
+		%s
+		ORDER BY rank DESC, id
+		LIMIT $2
+	`, p.className, p.textSearch, where), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []ragkit.RetrievedDoc
+	for rows.Next() {
+		var doc ragkit.RetrievedDoc
+		var embedding pgvector.Vector
+		var rank float32
+		err := rows.Scan(&doc.ID, &doc.Text, &doc.Metadata, &embedding, &rank)
+		if err != nil {
+			return nil, err
+		}
+		doc.Vector = embedding.Slice()
+		doc.Score = rank
+		doc.Metric = p.metric
+		if query != nil {
+			doc.Distance = ragkit.Distance(p.metric, query, doc.Vector)
+		}
+		results = append(results, doc)
+	}
+	return results, rows.Err()
+}
+
+// keywordQuery returns a websearch_to_tsquery query matching any word of text
+func keywordQuery(text string) string {
+	var words []string
+	for _, word := range strings.Fields(text) {
+		// quotes and a leading minus are operators of the websearch syntax
+		word = strings.TrimLeft(strings.ReplaceAll(word, `"`, ""), "-")
+		if word == "" || strings.EqualFold(word, "or") {
+			continue
+		}
+		words = append(words, word)
+	}
+	return strings.Join(words, " or ")
+}
@@ -0,0 +1,162 @@
+package pgvector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+// IndexType is a type that selects the vector index created on the embedding column
+type IndexType string
+
+const (
+	IndexIVFFlat IndexType = "ivfflat" // Inverted lists, fast to build (default)
+	IndexHNSW    IndexType = "hnsw"    // Graph, better speed-recall tradeoff but slower to build
+	IndexNone    IndexType = "none"    // No index, exact search
+)
+
+// The index is created only with the table, so changing the metric or the index
+// of an existing table needs the index "<table>_embedding_idx" to be dropped first.
+
+// WithMetric sets the distance metric used for the index and search (default: cosine)
+func WithMetric(metric ragkit.DistanceMetric) Option {
+	return func(p *PGVector) {
+		p.metric = metric
+	}
+}
+
+// WithIVFFlat indexes vectors with ivfflat split into lists. lists of 0 keeps the default of 100.
+func WithIVFFlat(lists int) Option {
+	return func(p *PGVector) {
+		p.indexType = IndexIVFFlat
+		p.lists = lists
+	}
+}
+
+// WithHNSW indexes vectors with hnsw. m and efConstruction of 0 keep the pgvector defaults (16 and 64).
+func WithHNSW(m, efConstruction int) Option {
+	return func(p *PGVector) {
+		p.indexType = IndexHNSW
+		p.m = m
+		p.efConstruction = efConstruction
+	}
+}
+
+// WithoutIndex searches every row exactly instead of using an approximate index
+func WithoutIndex() Option {
+	return func(p *PGVector) {
+		p.indexType = IndexNone
+	}
+}
+
+// WithSearchParams sets the default search parameters of every query (see SearchParams)
+func WithSearchParams(params SearchParams) Option {
+	return func(p *PGVector) {
+		p.searchParams = params
+	}
+}
+
+// SearchParams tunes the recall and latency of approximate searches.
+// Zero fields keep the server settings.
+type SearchParams struct {
+	Probes   int // ivfflat.probes: number of lists to search
+	EfSearch int // hnsw.ef_search: size of the candidate list
+}
+
+type searchParamsKey struct{}
+
+// ContextWithSearchParams returns a copy of ctx carrying params for the queries made with it.
+// Non-zero fields override the defaults of the store.
+func ContextWithSearchParams(ctx context.Context, params SearchParams) context.Context {
+	return context.WithValue(ctx, searchParamsKey{}, params)
+}
+
+// paramsFor returns the search parameters of a query made with ctx
+func (p *PGVector) paramsFor(ctx context.Context) SearchParams {
+	params := p.searchParams
+	if override, ok := ctx.Value(searchParamsKey{}).(SearchParams); ok {
+		if override.Probes > 0 {
+			params.Probes = override.Probes
+		}
+		if override.EfSearch > 0 {
+			params.EfSearch = override.EfSearch
+		}
+	}
+	return params
+}
+
+// setSearchParams applies params to the transaction tx
+func setSearchParams(ctx context.Context, tx pgx.Tx, params SearchParams) error {
+	// SET doesn't take bind parameters; the values are integers
+	if params.Probes > 0 {
+		if _, err := tx.Exec(ctx, fmt.Sprintf("SET LOCAL ivfflat.probes = %d", params.Probes)); err != nil {
+			return err
+		}
+	}
+	if params.EfSearch > 0 {
+		if _, err := tx.Exec(ctx, fmt.Sprintf("SET LOCAL hnsw.ef_search = %d", params.EfSearch)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// operator returns the distance operator of the metric
+func (p *PGVector) operator() (string, error) {
+	switch p.metric {
+	case ragkit.DistanceCosine:
+		return "<=>", nil
+	case ragkit.DistanceDot:
+		return "<#>", nil // negative inner product
+	case ragkit.DistanceL2:
+		return "<->", nil
+	}
+	return "", fmt.Errorf("unsupported distance metric: %q", p.metric)
+}
+
+// indexSQL returns the statement creating the vector index, or "" for IndexNone
+func (p *PGVector) indexSQL() (string, error) {
+	var ops string
+	switch p.metric {
+	case ragkit.DistanceCosine:
+		ops = "vector_cosine_ops"
+	case ragkit.DistanceDot:
+		ops = "vector_ip_ops"
+	case ragkit.DistanceL2:
+		ops = "vector_l2_ops"
+	default:
+		return "", fmt.Errorf("unsupported distance metric: %q", p.metric)
+	}
+
+	var with string
+	switch p.indexType {
+	case IndexNone:
+		return "", nil
+	case IndexIVFFlat:
+		lists := p.lists
+		if lists <= 0 {
+			lists = 100
+		}
+		with = fmt.Sprintf("WITH (lists = %d)", lists)
+	case IndexHNSW:
+		m := p.m
+		if m <= 0 {
+			m = 16
+		}
+		efConstruction := p.efConstruction
+		if efConstruction <= 0 {
+			efConstruction = 64
+		}
+		with = fmt.Sprintf("WITH (m = %d, ef_construction = %d)", m, efConstruction)
+	default:
+		return "", fmt.Errorf("unsupported index type: %q", p.indexType)
+	}
+
+	return fmt.Sprintf(`
+		CREATE INDEX IF NOT EXISTS %s_embedding_idx ON %s
+		USING %s (embedding %s)
+		%s
+	`, p.className, p.className, p.indexType, ops, with), nil
+}
@@ -0,0 +1,159 @@
+package pgvector
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+// store returns a store configured by opts without connecting it
+func store(opts ...Option) *PGVector {
+	p := &PGVector{
+		className: "docs",
+		metric:    ragkit.DistanceCosine,
+		indexType: IndexIVFFlat,
+		lists:     100,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func TestIndexSQL(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"default", nil,
+			"CREATE INDEX IF NOT EXISTS docs_embedding_idx ON docs USING ivfflat (embedding vector_cosine_ops) WITH (lists = 100)"},
+		{"ivfflat dot", []Option{WithMetric(ragkit.DistanceDot), WithIVFFlat(50)},
+			"CREATE INDEX IF NOT EXISTS docs_embedding_idx ON docs USING ivfflat (embedding vector_ip_ops) WITH (lists = 50)"},
+		{"ivfflat l2 default lists", []Option{WithMetric(ragkit.DistanceL2), WithIVFFlat(0)},
+			"CREATE INDEX IF NOT EXISTS docs_embedding_idx ON docs USING ivfflat (embedding vector_l2_ops) WITH (lists = 100)"},
+		{"ivfflat negative lists", []Option{WithIVFFlat(-1)},
+			"CREATE INDEX IF NOT EXISTS docs_embedding_idx ON docs USING ivfflat (embedding vector_cosine_ops) WITH (lists = 100)"},
+		{"hnsw", []Option{WithHNSW(32, 128)},
+			"CREATE INDEX IF NOT EXISTS docs_embedding_idx ON docs USING hnsw (embedding vector_cosine_ops) WITH (m = 32, ef_construction = 128)"},
+		{"hnsw l2 defaults", []Option{WithMetric(ragkit.DistanceL2), WithHNSW(0, 0)},
+			"CREATE INDEX IF NOT EXISTS docs_embedding_idx ON docs USING hnsw (embedding vector_l2_ops) WITH (m = 16, ef_construction = 64)"},
+		{"hnsw dot negative", []Option{WithMetric(ragkit.DistanceDot), WithHNSW(-4, -1)},
+			"CREATE INDEX IF NOT EXISTS docs_embedding_idx ON docs USING hnsw (embedding vector_ip_ops) WITH (m = 16, ef_construction = 64)"},
+		{"none", []Option{WithoutIndex()}, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := store(tc.opts...).indexSQL()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got = strings.Join(strings.Fields(got), " "); got != tc.want {
+				t.Errorf("got  %s\nwant %s", got, tc.want)
+			}
+		})
+	}
+
+	for _, p := range []*PGVector{
+		store(WithMetric("manhattan")),
+		store(WithMetric("manhattan"), WithoutIndex()),
+		{className: "docs", metric: ragkit.DistanceCosine, indexType: "btree"},
+	} {
+		if got, err := p.indexSQL(); err == nil {
+			t.Errorf("metric %q, index %q: got %q, want an error", p.metric, p.indexType, got)
+		}
+	}
+}
+
+func TestOperator(t *testing.T) {
+	for metric, want := range map[ragkit.DistanceMetric]string{
+		ragkit.DistanceCosine: "<=>",
+		ragkit.DistanceDot:    "<#>",
+		ragkit.DistanceL2:     "<->",
+	} {
+		got, err := store(WithMetric(metric)).operator()
+		if err != nil || got != want {
+			t.Errorf("%s: got %q, %v, want %q", metric, got, err, want)
+		}
+	}
+	if _, err := store(WithMetric("manhattan")).operator(); err == nil {
+		t.Error("unsupported metric accepted")
+	}
+}
+
+func TestParamsFor(t *testing.T) {
+	p := store(WithSearchParams(SearchParams{Probes: 10, EfSearch: 40}))
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want SearchParams
+	}{
+		{"defaults", context.Background(), SearchParams{Probes: 10, EfSearch: 40}},
+		{"override both", ContextWithSearchParams(context.Background(), SearchParams{Probes: 1, EfSearch: 200}),
+			SearchParams{Probes: 1, EfSearch: 200}},
+		{"override one", ContextWithSearchParams(context.Background(), SearchParams{EfSearch: 100}),
+			SearchParams{Probes: 10, EfSearch: 100}},
+		{"non-positive keeps defaults", ContextWithSearchParams(context.Background(), SearchParams{Probes: -1}),
+			SearchParams{Probes: 10, EfSearch: 40}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := p.paramsFor(tc.ctx); got != tc.want {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+// recordingTx records the statements executed on it
+type recordingTx struct {
+	pgx.Tx
+	stmts []string
+	err   error
+}
+
+func (tx *recordingTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	tx.stmts = append(tx.stmts, sql)
+	return pgconn.CommandTag{}, tx.err
+}
+
+func TestSetSearchParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		params SearchParams
+		want   []string
+	}{
+		{"none", SearchParams{}, nil},
+		{"probes", SearchParams{Probes: 5}, []string{"SET LOCAL ivfflat.probes = 5"}},
+		{"ef_search", SearchParams{EfSearch: 80}, []string{"SET LOCAL hnsw.ef_search = 80"}},
+		{"both", SearchParams{Probes: 5, EfSearch: 80},
+			[]string{"SET LOCAL ivfflat.probes = 5", "SET LOCAL hnsw.ef_search = 80"}},
+		{"negative", SearchParams{Probes: -1, EfSearch: -1}, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tx := &recordingTx{}
+			if err := setSearchParams(context.Background(), tx, tc.params); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(tx.stmts, tc.want) {
+				t.Errorf("got %q, want %q", tx.stmts, tc.want)
+			}
+		})
+	}
+
+	errExec := errors.New("exec failed")
+	tx := &recordingTx{err: errExec}
+	if err := setSearchParams(context.Background(), tx, SearchParams{Probes: 5, EfSearch: 80}); !errors.Is(err, errExec) {
+		t.Errorf("err = %v, want %v", err, errExec)
+	}
+	if len(tx.stmts) != 1 {
+		t.Errorf("executed %q after a failure", tx.stmts)
+	}
+}
@@ -2,65 +2,160 @@ package pgvector
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"sync"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/pgvector/pgvector-go"
+	pgxvec "github.com/pgvector/pgvector-go/pgx"
 	ragkit "github.com/suapapa/go_ragkit"
 )
 
-var _ ragkit.VectorStore = &PGVector{}
+var (
+	_ ragkit.VectorStore       = &PGVector{}
+	_ ragkit.FilteredRetriever = &PGVector{}
+	_ ragkit.Upserter          = &PGVector{}
+	_ ragkit.HybridRetriever   = &PGVector{}
+)
 
 type PGVector struct {
 	className string
-	conn      *pgx.Conn
+	pool      *pgxpool.Pool
+	ownsPool  bool
 	embedder  ragkit.Embedder
 	dimension int
+	batchSize int
+
+	metric         ragkit.DistanceMetric
+	indexType      IndexType
+	lists          int // ivfflat
+	m              int // hnsw
+	efConstruction int // hnsw
+	searchParams   SearchParams
+	textSearch     string // text search configuration of the keyword column, "" if disabled
+}
+
+// Option configures a PGVector store
+type Option func(*PGVector)
 
-	mu sync.Mutex
+// WithBatchSize sets the number of documents embedded and copied into the table at once
+// (default: ragkit.DefaultBatchSize)
+func WithBatchSize(n int) Option {
+	return func(p *PGVector) {
+		p.batchSize = n
+	}
 }
 
-func New(connStr string, dimension int, className string, embedder ragkit.Embedder) *PGVector {
-	conn, err := pgx.Connect(context.Background(), connStr)
+// New connects to connStr and creates the table if it doesn't exist, exiting the process on error.
+//
+// Deprecated: use Connect or NewWithPool, which return errors.
+func New(connStr string, dimension int, className string, embedder ragkit.Embedder, opts ...Option) *PGVector {
+	p, err := Connect(context.Background(), connStr, dimension, className, embedder, opts...)
 	if err != nil {
-		log.Fatalf("Unable to connect to database: %v", err)
+		log.Fatalf("Failed to create pgvector store: %v", err)
+	}
+	return p
+}
+
+// Connect creates a connection pool for connStr and a store on it, creating the table if it doesn't exist.
+// Pool settings such as pool_max_conns can be given in connStr. Close closes the pool.
+func Connect(ctx context.Context, connStr string, dimension int, className string, embedder ragkit.Embedder, opts ...Option) (*PGVector, error) {
+	cfg, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse connection string: %w", err)
+	}
+
+	// the vector type has to exist before the connections of the pool register it
+	conn, err := pgx.ConnectConfig(ctx, cfg.ConnConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to database: %w", err)
+	}
+	err = createExtension(ctx, conn)
+	conn.Close(ctx)
+	if err != nil {
+		return nil, err
 	}
-	// defer conn.Close(context.Background())
 
-	ret := &PGVector{
+	afterConnect := cfg.AfterConnect
+	cfg.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		if afterConnect != nil {
+			if err := afterConnect(ctx, conn); err != nil {
+				return err
+			}
+		}
+		return pgxvec.RegisterTypes(ctx, conn)
+	}
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create connection pool: %w", err)
+	}
+
+	p, err := NewWithPool(ctx, pool, dimension, className, embedder, opts...)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+	p.ownsPool = true
+	return p, nil
+}
+
+// NewWithPool creates a store on pool, creating the table if it doesn't exist.
+// The vector extension must already exist in the database (CREATE EXTENSION vector), as the connections
+// of pool must register the pgvector types, by calling RegisterTypes of github.com/pgvector/pgvector-go/pgx
+// in the AfterConnect hook of its config.
+// Close leaves pool open.
+func NewWithPool(ctx context.Context, pool *pgxpool.Pool, dimension int, className string, embedder ragkit.Embedder, opts ...Option) (*PGVector, error) {
+	p := &PGVector{
 		className: className,
-		conn:      conn,
+		pool:      pool,
 		embedder:  embedder,
 		dimension: dimension,
+		batchSize: ragkit.DefaultBatchSize,
+		metric:    ragkit.DistanceCosine,
+		indexType: IndexIVFFlat,
+		lists:     100,
 	}
-
-	err = ret.ensureTable(context.Background())
-	if err != nil {
-		log.Fatalf("Failed to ensure table: %v", err)
+	for _, opt := range opts {
+		opt(p)
 	}
 
-	return ret
+	if _, err := p.operator(); err != nil {
+		return nil, err
+	}
+	if !validTextSearchConfig(p.textSearch) {
+		return nil, fmt.Errorf("invalid text search configuration: %q", p.textSearch)
+	}
+	if err := p.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	return p, nil
 }
 
+// Close closes the connection pool if the store created it
 func (p *PGVector) Close() error {
-	return p.conn.Close(context.Background())
+	if p.ownsPool {
+		p.pool.Close()
+	}
+	return nil
 }
 
-// ensureTable creates the table if it doesn't exist
-func (p *PGVector) ensureTable(ctx context.Context) error {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	// Create the pgvector extension if it doesn't exist
-	_, err := p.conn.Exec(ctx, `CREATE EXTENSION IF NOT EXISTS vector SCHEMA public;`)
+// createExtension creates the pgvector extension if it doesn't exist
+func createExtension(ctx context.Context, conn *pgx.Conn) error {
+	_, err := conn.Exec(ctx, `CREATE EXTENSION IF NOT EXISTS vector SCHEMA public;`)
 	if err != nil {
 		return fmt.Errorf("failed to create pgvector extension: %w", err)
 	}
+	return nil
+}
 
+// ensureTable creates the table if it doesn't exist
+func (p *PGVector) ensureTable(ctx context.Context) error {
 	// Create the table if it doesn't exist
-	_, err = p.conn.Exec(ctx, fmt.Sprintf(`
+	_, err := p.pool.Exec(ctx, fmt.Sprintf(`
 		CREATE TABLE IF NOT EXISTS %s (
 			id TEXT PRIMARY KEY,
 			text TEXT NOT NULL,
@@ -78,114 +173,248 @@ func (p *PGVector) ensureTable(ctx context.Context) error {
 	}
 
 	// Create index for vector similarity search
-	_, err = p.conn.Exec(ctx, fmt.Sprintf(`
-		CREATE INDEX IF NOT EXISTS %s_embedding_idx ON %s 
-		USING ivfflat (embedding vector_cosine_ops)
-		WITH (lists = 100)
-	`, p.className, p.className))
+	indexSQL, err := p.indexSQL()
 	if err != nil {
-		return fmt.Errorf("failed to create index: %w", err)
+		return err
+	}
+	if indexSQL != "" {
+		_, err = p.pool.Exec(ctx, indexSQL)
+		if err != nil {
+			return fmt.Errorf("failed to create index: %w", err)
+		}
 	}
 
+	if p.textSearch != "" {
+		return p.ensureTextSearch(ctx)
+	}
 	return nil
 }
 
+// Index embeds and writes docs in batches, copying each batch into the table at once.
+// When a batch fails to copy, its documents are inserted one by one so that only the failing ones,
+// like documents with an existing ID, are skipped. Skipped documents are reported by a *ragkit.IndexError.
 func (p *PGVector) Index(ctx context.Context, docs ...ragkit.Document) ([]string, error) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+	docs, failed := ragkit.EmbedDocs(ctx, p.embedder, p.batchLimits(), docs...)
+
+	var pos []int // positions of the embedded documents
+	for i, doc := range docs {
+		if doc.Vector != nil {
+			pos = append(pos, i)
+		}
+	}
 
 	var ids []string
-	for _, doc := range docs {
-		var embedding []float32
-		var err error
+	for len(pos) > 0 {
+		batch := pos[:min(len(pos), max(p.batchSize, 1))]
+		pos = pos[len(batch):]
 
-		if doc.ID == "" {
-			doc.ID = ragkit.GenerateID(doc.Text, doc.Metadata)
+		rows := make([][]any, len(batch))
+		for j, i := range batch {
+			rows[j] = []any{docs[i].ID, docs[i].Text, docs[i].Metadata, pgvector.NewVector(docs[i].Vector)}
+		}
+		_, err := p.pool.CopyFrom(ctx, p.tableIdentifier(),
+			[]string{"id", "text", "metadata", "embedding"}, pgx.CopyFromRows(rows))
+		if err == nil {
+			for _, i := range batch {
+				ids = append(ids, docs[i].ID)
+			}
+			continue
 		}
 
-		// Use provided vector if available, otherwise generate one
-		if doc.Vector != nil {
-			embedding = doc.Vector
-		} else {
-			vectors, err := p.embedder.EmbedTexts(ctx, doc.Text)
-			if err != nil {
-				return nil, err
+		for _, i := range batch {
+			if err := ctx.Err(); err != nil {
+				failed.Add(i, docs[i].ID, err)
+				continue
 			}
-			embedding = vectors[0]
+			if err := p.insert(ctx, docs[i]); err != nil {
+				failed.Add(i, docs[i].ID, err)
+				continue
+			}
+			ids = append(ids, docs[i].ID)
 		}
+	}
+	return ids, failed.Err()
+}
 
-		// Insert document into database
-		_, err = p.conn.Exec(ctx, fmt.Sprintf(`
+// insert writes a single document
+func (p *PGVector) insert(ctx context.Context, doc ragkit.Document) error {
+	_, err := p.pool.Exec(ctx, fmt.Sprintf(`
+		INSERT INTO %s (id, text, metadata, embedding) 
+		VALUES ($1, $2, $3, $4)
+	`, p.className), doc.ID, doc.Text, doc.Metadata, pgvector.NewVector(doc.Vector))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+			return ragkit.ErrDocumentExists
+		}
+		return err
+	}
+	return nil
+}
+
+// Upsert indexes docs in a single transaction, replacing the documents of existing IDs
+func (p *PGVector) Upsert(ctx context.Context, docs ...ragkit.Document) (ragkit.UpsertResult, error) {
+	var result ragkit.UpsertResult
+
+	docs, err := ragkit.PrepareDocs(ctx, p.embedder, p.batchLimits(), docs...)
+	if err != nil {
+		return result, err
+	}
+
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, doc := range docs {
+		// xmax is 0 only for freshly inserted rows
+		var inserted bool
+		err := tx.QueryRow(ctx, fmt.Sprintf(`
 			INSERT INTO %s (id, text, metadata, embedding) 
 			VALUES ($1, $2, $3, $4)
-		`, p.className), doc.ID, doc.Text, doc.Metadata, pgvector.NewVector(embedding))
+			ON CONFLICT (id) DO UPDATE
+			SET text = EXCLUDED.text, metadata = EXCLUDED.metadata, embedding = EXCLUDED.embedding
+			RETURNING (xmax = 0)
+		`, p.className), doc.ID, doc.Text, doc.Metadata, pgvector.NewVector(doc.Vector)).Scan(&inserted)
 		if err != nil {
-			return nil, err
+			return ragkit.UpsertResult{}, err
 		}
-		ids = append(ids, doc.ID)
+		if inserted {
+			result.Inserted = append(result.Inserted, doc.ID)
+		} else {
+			result.Updated = append(result.Updated, doc.ID)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return ragkit.UpsertResult{}, err
 	}
-	return ids, nil
+	return result, nil
 }
 
 func (p *PGVector) Delete(ctx context.Context, id string) error {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	_, err := p.conn.Exec(ctx, fmt.Sprintf(`
+	_, err := p.pool.Exec(ctx, fmt.Sprintf(`
 		DELETE FROM %s WHERE id = $1
 	`, p.className), id)
 	return err
 }
 
 func (p *PGVector) Exists(ctx context.Context, id string) (bool, error) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
 	var exists bool
-	err := p.conn.QueryRow(ctx, fmt.Sprintf(`
+	err := p.pool.QueryRow(ctx, fmt.Sprintf(`
 		SELECT EXISTS(SELECT 1 FROM %s WHERE id = $1)
 	`, p.className), id).Scan(&exists)
 	return exists, err
 }
 
 func (p *PGVector) Retrieve(ctx context.Context, query []float32, topK int, metadataFieldNames ...string) ([]ragkit.RetrievedDoc, error) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+	return p.RetrieveFiltered(ctx, query, topK, nil, metadataFieldNames...)
+}
 
-	// Perform vector similarity search
-	rows, err := p.conn.Query(ctx, fmt.Sprintf(`
-		SELECT text, metadata, embedding 
+func (p *PGVector) RetrieveFiltered(ctx context.Context, query []float32, topK int, filter *ragkit.Filter, metadataFieldNames ...string) ([]ragkit.RetrievedDoc, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	if topK <= 0 {
+		return nil, ctx.Err()
+	}
+
+	args := []any{pgvector.NewVector(query), topK}
+	where := ""
+	if filter != nil {
+		pred, err := compileFilter(filter, &args)
+		if err != nil {
+			return nil, err
+		}
+		where = "WHERE " + pred
+	}
+
+	operator, err := p.operator()
+	if err != nil {
+		return nil, err
+	}
+	sql := fmt.Sprintf(`
+		SELECT id, text, metadata, embedding, embedding %s $1 AS distance
 		FROM %s 
-		ORDER BY embedding <=> $1 
+		%s
+		ORDER BY distance 
 		LIMIT $2
-	`, p.className), pgvector.NewVector(query), topK)
+	`, operator, p.className, where)
+
+	// search parameters are set for the transaction only, so they don't leak through the pool
+	params := p.paramsFor(ctx)
+	if params == (SearchParams{}) {
+		rows, err := p.pool.Query(ctx, sql, args...)
+		if err != nil {
+			return nil, err
+		}
+		return p.scanResults(rows)
+	}
+
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := setSearchParams(ctx, tx, params); err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(ctx, sql, args...)
 	if err != nil {
 		return nil, err
 	}
+	results, err := p.scanResults(rows)
+	if err != nil {
+		return nil, err
+	}
+	return results, tx.Commit(ctx)
+}
+
+// scanResults reads the rows of a similarity search and closes them
+func (p *PGVector) scanResults(rows pgx.Rows) ([]ragkit.RetrievedDoc, error) {
 	defer rows.Close()
 
 	var results []ragkit.RetrievedDoc
 	for rows.Next() {
 		var doc ragkit.RetrievedDoc
 		var embedding pgvector.Vector
-		err := rows.Scan(&doc.Text, &doc.Metadata, &embedding)
+		var distance float64
+		err := rows.Scan(&doc.ID, &doc.Text, &doc.Metadata, &embedding, &distance)
 		if err != nil {
 			return nil, err
 		}
 		doc.Vector = embedding.Slice()
+		doc.Distance = float32(distance)
+		doc.Metric = p.metric
+		doc.Score = ragkit.ScoreFromDistance(doc.Metric, doc.Distance)
 		results = append(results, doc)
 	}
 	return results, rows.Err()
 }
 
 func (p *PGVector) RetrieveText(ctx context.Context, text string, topK int, metadataFieldNames ...string) ([]ragkit.RetrievedDoc, error) {
+	return p.RetrieveTextFiltered(ctx, text, topK, nil, metadataFieldNames...)
+}
+
+func (p *PGVector) RetrieveTextFiltered(ctx context.Context, text string, topK int, filter *ragkit.Filter, metadataFieldNames ...string) ([]ragkit.RetrievedDoc, error) {
 	vectors, err := p.embedder.EmbedTexts(ctx, text)
 	if err != nil {
 		return nil, err
 	}
-	return p.Retrieve(ctx, vectors[0], topK, metadataFieldNames...)
+	return p.RetrieveFiltered(ctx, vectors[0], topK, filter, metadataFieldNames...)
+}
+
+func (p *PGVector) batchLimits() ragkit.BatchLimits {
+	return ragkit.BatchLimits{MaxTexts: p.batchSize}
+}
+
+// tableIdentifier returns the table name as CREATE TABLE folded it, for APIs quoting identifiers
+func (p *PGVector) tableIdentifier() pgx.Identifier {
+	return pgx.Identifier(strings.Split(strings.ToLower(p.className), "."))
 }
 
 func (p *PGVector) String() string {
-	return fmt.Sprintf("PGVector(table: %s, embedder: %s)", p.className, p.embedder)
+	return fmt.Sprintf("PGVector(table: %s, metric: %s, index: %s, embedder: %s)", p.className, p.metric, p.indexType, p.embedder)
 }
@@ -0,0 +1,146 @@
+package weviate
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	ragkit "github.com/suapapa/go_ragkit"
+	"github.com/weaviate/weaviate-go-client/v5/weaviate/filters"
+)
+
+// Weaviate can't filter on properties nested in an object, so Index also stores
+// every scalar (or list of scalars) metadata value as a top-level property
+// named by filterPropName. String equality follows the tokenization of those
+// properties; define them with `field` tokenization in the class schema for exact
+// matches. FilterExists and FilterNeq rely on IsNull, which needs `indexNullState` enabled.
+
+// filterPropName returns the top-level property name mirroring a metadata key
+func filterPropName(key string) string {
+	var sb strings.Builder
+	sb.WriteString("meta_")
+	for _, r := range key {
+		if r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteRune('_')
+		}
+	}
+	return sb.String()
+}
+
+// filterProps returns the filterable top-level properties for metadata
+func filterProps(metadata map[string]any) map[string]any {
+	props := make(map[string]any)
+	for k, v := range metadata {
+		if _, err := ragkit.NormalizeFilterValue(v); err == nil {
+			props[filterPropName(k)] = v
+			continue
+		}
+		// lists of scalars can be filtered with Equal/ContainsAny as well
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice || rv.Len() == 0 {
+			continue
+		}
+		if _, err := ragkit.NormalizeFilterValue(rv.Index(0).Interface()); err == nil {
+			props[filterPropName(k)] = v
+		}
+	}
+	return props
+}
+
+// compileFilter translates filter into a Weaviate where filter
+func compileFilter(f *ragkit.Filter) (*filters.WhereBuilder, error) {
+	path := []string{filterPropName(f.Key)}
+
+	switch f.Op {
+	case ragkit.FilterEq:
+		return withValues(filters.Where().WithPath(path).WithOperator(filters.Equal), f.Value)
+
+	case ragkit.FilterNeq:
+		// NotEqual skips objects without the property, which FilterNeq matches
+		notEqual, err := withValues(filters.Where().WithPath(path).WithOperator(filters.NotEqual), f.Value)
+		if err != nil {
+			return nil, err
+		}
+		missing := filters.Where().WithPath(path).WithOperator(filters.IsNull).WithValueBoolean(true)
+		return filters.Where().WithOperator(filters.Or).WithOperands([]*filters.WhereBuilder{missing, notEqual}), nil
+
+	case ragkit.FilterIn:
+		return withValues(filters.Where().WithPath(path).WithOperator(filters.ContainsAny), f.Values...)
+
+	case ragkit.FilterRange:
+		var operands []*filters.WhereBuilder
+		if f.Min != nil {
+			w, err := withValues(filters.Where().WithPath(path).WithOperator(filters.GreaterThanEqual), f.Min)
+			if err != nil {
+				return nil, err
+			}
+			operands = append(operands, w)
+		}
+		if f.Max != nil {
+			w, err := withValues(filters.Where().WithPath(path).WithOperator(filters.LessThanEqual), f.Max)
+			if err != nil {
+				return nil, err
+			}
+			operands = append(operands, w)
+		}
+		if len(operands) == 1 {
+			return operands[0], nil
+		}
+		return filters.Where().WithOperator(filters.And).WithOperands(operands), nil
+
+	case ragkit.FilterExists:
+		return filters.Where().WithPath(path).WithOperator(filters.IsNull).WithValueBoolean(false), nil
+
+	case ragkit.FilterAnd, ragkit.FilterOr, ragkit.FilterNot:
+		var operands []*filters.WhereBuilder
+		for _, sub := range f.Filters {
+			w, err := compileFilter(sub)
+			if err != nil {
+				return nil, err
+			}
+			operands = append(operands, w)
+		}
+		op := map[ragkit.FilterOp]filters.WhereOperator{
+			ragkit.FilterAnd: filters.And,
+			ragkit.FilterOr:  filters.Or,
+			ragkit.FilterNot: filters.Not,
+		}[f.Op]
+		return filters.Where().WithOperator(op).WithOperands(operands), nil
+	}
+	return nil, fmt.Errorf("unknown filter op: %q", f.Op)
+}
+
+// withValues sets the operands of w, which must share a type
+func withValues(w *filters.WhereBuilder, values ...any) (*filters.WhereBuilder, error) {
+	var (
+		texts   []string
+		numbers []float64
+		bools   []bool
+	)
+	for _, v := range values {
+		nv, err := ragkit.NormalizeFilterValue(v)
+		if err != nil {
+			return nil, err
+		}
+		switch nv := nv.(type) {
+		case string:
+			texts = append(texts, nv)
+		case float64:
+			numbers = append(numbers, nv)
+		case bool:
+			bools = append(bools, nv)
+		}
+	}
+
+	switch {
+	case len(texts) == len(values):
+		return w.WithValueText(texts...), nil
+	case len(numbers) == len(values):
+		return w.WithValueNumber(numbers...), nil
+	case len(bools) == len(values):
+		return w.WithValueBoolean(bools...), nil
+	}
+	return nil, fmt.Errorf("filter values of mixed types: %v", values)
+}
@@ -0,0 +1,64 @@
+package weviate
+
+import (
+	"reflect"
+	"testing"
+
+	ragkit "github.com/suapapa/go_ragkit"
+)
+
+func TestCompileFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter *ragkit.Filter
+		want   string
+	}{
+		{"eq", ragkit.Eq("source", "a.txt"),
+			`where:{operator: Equal path: ["meta_source"] valueText: "a.txt"}`},
+		{"neq matches missing", ragkit.Neq("lang", "ko"),
+			`where:{operator: Or operands:[{operator: IsNull path: ["meta_lang"] valueBoolean: true},{operator: NotEqual path: ["meta_lang"] valueText: "ko"}]}`},
+		{"in numbers", ragkit.In("page", 1, 2.5),
+			`where:{operator: ContainsAny path: ["meta_page"] valueNumber: [1,2.5]}`},
+		{"range", ragkit.Range("year", 2000, 2010),
+			`where:{operator: And operands:[{operator: GreaterThanEqual path: ["meta_year"] valueNumber: 2000},{operator: LessThanEqual path: ["meta_year"] valueNumber: 2010}]}`},
+		{"open range", ragkit.Range("date", "2024-01-01", nil),
+			`where:{operator: GreaterThanEqual path: ["meta_date"] valueText: "2024-01-01"}`},
+		{"exists", ragkit.Exists("a.b-c"),
+			`where:{operator: IsNull path: ["meta_a_b_c"] valueBoolean: false}`},
+		{"nested", ragkit.Not(ragkit.Or(ragkit.Eq("draft", true), ragkit.Eq("n", 3))),
+			`where:{operator: Not operands:[{operator: Or operands:[{operator: Equal path: ["meta_draft"] valueBoolean: true},{operator: Equal path: ["meta_n"] valueNumber: 3}]}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := compileFilter(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := w.String(); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := compileFilter(ragkit.In("k", "a", 1)); err == nil {
+		t.Error("mixed value types: no error")
+	}
+}
+
+func TestFilterProps(t *testing.T) {
+	got := filterProps(map[string]any{
+		"source": "a.txt",
+		"page":   3,
+		"tags":   []string{"x", "y"},
+		"nested": map[string]any{"a": 1},
+		"empty":  []string{},
+	})
+	want := map[string]any{
+		"meta_source": "a.txt",
+		"meta_page":   3,
+		"meta_tags":   []string{"x", "y"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
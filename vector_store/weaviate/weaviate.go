@@ -2,124 +2,293 @@ package weviate
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 
+	"github.com/go-openapi/strfmt"
 	ragkit "github.com/suapapa/go_ragkit"
 	"github.com/weaviate/weaviate-go-client/v5/weaviate"
+	"github.com/weaviate/weaviate-go-client/v5/weaviate/fault"
+	"github.com/weaviate/weaviate-go-client/v5/weaviate/filters"
 	"github.com/weaviate/weaviate-go-client/v5/weaviate/graphql"
+	"github.com/weaviate/weaviate/entities/models"
 )
 
-var _ ragkit.VectorStore = &Weaviate{}
+var (
+	_ ragkit.VectorStore       = &Weaviate{}
+	_ ragkit.FilteredRetriever = &Weaviate{}
+	_ ragkit.Upserter          = &Weaviate{}
+	_ ragkit.HybridRetriever   = &Weaviate{}
+)
 
 type Weaviate struct {
 	className string
 	client    *weaviate.Client
 	embedder  ragkit.Embedder
+	batchSize int
+
+	metric   ragkit.DistanceMetric // distance of the class, read from its schema once it exists
+	metricMu sync.Mutex
+}
 
-	mu sync.Mutex
+// Option configures a Weaviate store
+type Option func(*Weaviate)
+
+// WithBatchSize sets the number of documents embedded and sent in a batch request at once
+// (default: ragkit.DefaultBatchSize)
+func WithBatchSize(n int) Option {
+	return func(w *Weaviate) {
+		w.batchSize = n
+	}
 }
 
-func New(client *weaviate.Client, className string, embedder ragkit.Embedder) *Weaviate {
-	return &Weaviate{
+func New(client *weaviate.Client, className string, embedder ragkit.Embedder, opts ...Option) *Weaviate {
+	w := &Weaviate{
 		className: ragkit.ToCamelCase(className),
 		client:    client,
 		embedder:  embedder,
+		batchSize: ragkit.DefaultBatchSize,
 	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
 }
 
+// Index embeds docs in batches and writes them with the batch objects API.
+// Documents failing to embed or to be written, or with an existing ID, are skipped and
+// reported by a *ragkit.IndexError.
+// The batch API overwrites objects, so IDs are checked beforehand; an object created
+// concurrently between the check and the write is replaced.
 func (w *Weaviate) Index(ctx context.Context, docs ...ragkit.Document) ([]string, error) {
-	w.mu.Lock()
-	defer w.mu.Unlock()
+	docs, failed := ragkit.EmbedDocs(ctx, w.embedder, w.batchLimits(), docs...)
+
+	var pos []int // positions of the embedded documents
+	seen := make(map[string]bool)
+	for i, doc := range docs {
+		if doc.Vector == nil {
+			continue
+		}
+		if seen[doc.ID] {
+			failed.Add(i, doc.ID, fmt.Errorf("%w: %s", ragkit.ErrDocumentExists, doc.ID))
+			continue
+		}
+		seen[doc.ID] = true
+		pos = append(pos, i)
+	}
 
 	var ids []string
-	for _, doc := range docs {
-		var embedding []float32
-		var err error
+	for len(pos) > 0 {
+		batch := pos[:min(len(pos), max(w.batchSize, 1))]
+		pos = pos[len(batch):]
 
-		if doc.ID == "" {
-			doc.ID = ragkit.GenerateID(doc.Text, doc.Metadata)
+		exists, err := w.existing(ctx, docs, batch)
+		if err != nil {
+			for _, i := range batch {
+				failed.Add(i, docs[i].ID, err)
+			}
+			continue
 		}
 
-		// Use provided vector if available, otherwise generate one
-		if doc.Vector != nil {
-			embedding = doc.Vector
-		} else {
-			vectors, err := w.embedder.EmbedTexts(ctx, doc.Text)
-			if err != nil {
-				return nil, err
+		var write []int
+		for _, i := range batch {
+			if exists[docs[i].ID] {
+				failed.Add(i, docs[i].ID, fmt.Errorf("%w: %s", ragkit.ErrDocumentExists, docs[i].ID))
+				continue
 			}
-			embedding = vectors[0]
+			write = append(write, i)
 		}
 
-		// Create data object
-		data := map[string]any{
-			"text":     doc.Text,
-			"metadata": doc.Metadata,
+		for _, i := range w.writeBatch(ctx, docs, write, failed) {
+			ids = append(ids, docs[i].ID)
 		}
+	}
+	return ids, failed.Err()
+}
+
+// Upsert indexes docs in batches, replacing the objects of existing IDs.
+// Each object is replaced atomically, but a failure leaves the other documents upserted;
+// the documents that failed are reported by a *ragkit.IndexError.
+func (w *Weaviate) Upsert(ctx context.Context, docs ...ragkit.Document) (ragkit.UpsertResult, error) {
+	var result ragkit.UpsertResult
+
+	docs, err := ragkit.PrepareDocs(ctx, w.embedder, w.batchLimits(), docs...)
+	if err != nil {
+		return result, err
+	}
+
+	failed := &ragkit.IndexError{}
+	seen := make(map[string]bool)
+	pos := make([]int, len(docs))
+	for i := range pos {
+		pos[i] = i
+	}
+	for len(pos) > 0 {
+		batch := pos[:min(len(pos), max(w.batchSize, 1))]
+		pos = pos[len(batch):]
 
-		// Create object with embedding
-		_, err = w.client.Data().Creator().
-			WithClassName(w.className).
-			WithID(doc.ID).
-			WithProperties(data).
-			WithVector(embedding).
-			Do(ctx)
+		exists, err := w.existing(ctx, docs, batch)
 		if err != nil {
-			return nil, err
+			for _, i := range batch {
+				failed.Add(i, docs[i].ID, err)
+			}
+			continue
+		}
+
+		for _, i := range w.writeBatch(ctx, docs, batch, failed) {
+			id := docs[i].ID
+			if exists[id] || seen[id] {
+				result.Updated = append(result.Updated, id)
+			} else {
+				result.Inserted = append(result.Inserted, id)
+			}
+			seen[id] = true
 		}
-		ids = append(ids, doc.ID)
 	}
-	return ids, nil
+	return result, failed.Err()
 }
 
-func (w *Weaviate) Delete(ctx context.Context, id string) error {
-	w.mu.Lock()
-	defer w.mu.Unlock()
+// existing returns the set of IDs among docs[batch] that are already stored
+func (w *Weaviate) existing(ctx context.Context, docs []ragkit.Document, batch []int) (map[string]bool, error) {
+	exists := make(map[string]bool)
+	if len(batch) == 0 {
+		return exists, nil
+	}
 
-	return w.client.Data().Deleter().
+	ids := make([]string, len(batch))
+	for j, i := range batch {
+		ids[j] = docs[i].ID
+	}
+	response, err := w.client.GraphQL().Get().
 		WithClassName(w.className).
-		WithID(id).
+		WithFields(graphql.Field{Name: "_additional", Fields: []graphql.Field{{Name: "id"}}}).
+		WithWhere(filters.Where().
+			WithPath([]string{"id"}).
+			WithOperator(filters.ContainsAny).
+			WithValueText(ids...)).
+		WithLimit(len(ids)).
 		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	found, err := w.parseResults(response)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := make(map[string]bool, len(found))
+	for _, doc := range found {
+		stored[strings.ToLower(doc.ID)] = true
+	}
+	for _, id := range ids {
+		if stored[strings.ToLower(id)] {
+			exists[id] = true
+		}
+	}
+	return exists, nil
 }
 
-func (w *Weaviate) Exists(ctx context.Context, id string) (bool, error) {
-	w.mu.Lock()
-	defer w.mu.Unlock()
+// writeBatch writes docs[batch] in a single batch request and returns the positions written.
+// The failures are added to failed.
+func (w *Weaviate) writeBatch(ctx context.Context, docs []ragkit.Document, batch []int, failed *ragkit.IndexError) []int {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	objs := make([]*models.Object, len(batch))
+	byID := make(map[string]int, len(batch)) // lowercased ID to position
+	for j, i := range batch {
+		objs[j] = &models.Object{
+			Class:      w.className,
+			ID:         strfmt.UUID(docs[i].ID),
+			Properties: objectProps(docs[i]),
+			Vector:     docs[i].Vector,
+		}
+		byID[strings.ToLower(docs[i].ID)] = i
+	}
+
+	resp, err := w.client.Batch().ObjectsBatcher().WithObjects(objs...).Do(ctx)
+	if err != nil {
+		for _, i := range batch {
+			failed.Add(i, docs[i].ID, err)
+		}
+		return nil
+	}
+
+	rejected := make(map[int]bool)
+	for _, r := range resp {
+		if r.Result == nil || r.Result.Errors == nil || len(r.Result.Errors.Error) == 0 {
+			continue
+		}
+		i, ok := byID[strings.ToLower(r.ID.String())]
+		if !ok {
+			continue
+		}
+		var msgs []string
+		for _, e := range r.Result.Errors.Error {
+			msgs = append(msgs, e.Message)
+		}
+		failed.Add(i, docs[i].ID, errors.New(strings.Join(msgs, "; ")))
+		rejected[i] = true
+	}
+
+	var written []int
+	for _, i := range batch {
+		if !rejected[i] {
+			written = append(written, i)
+		}
+	}
+	return written
+}
 
-	_, err := w.client.Data().ObjectsGetter().
+func (w *Weaviate) Delete(ctx context.Context, id string) error {
+	err := w.client.Data().Deleter().
 		WithClassName(w.className).
 		WithID(id).
 		Do(ctx)
-	if err != nil {
-		if err.Error() == "object not found" || strings.Contains(err.Error(), "404") {
-			return false, nil
-		}
-		return false, err
+	if isNotFound(err) {
+		// deleting a missing document is not an error, as with the other stores
+		return nil
 	}
-	return true, nil
+	return err
+}
+
+func (w *Weaviate) Exists(ctx context.Context, id string) (bool, error) {
+	return w.client.Data().Checker().
+		WithClassName(w.className).
+		WithID(id).
+		Do(ctx)
 }
 
 func (w *Weaviate) Retrieve(ctx context.Context, query []float32, topK int, metadataFieldNames ...string) ([]ragkit.RetrievedDoc, error) {
-	w.mu.Lock()
-	defer w.mu.Unlock()
+	return w.RetrieveFiltered(ctx, query, topK, nil, metadataFieldNames...)
+}
 
-	var metadataFields []graphql.Field
-	for _, name := range metadataFieldNames {
-		metadataFields = append(metadataFields, graphql.Field{Name: name})
+func (w *Weaviate) RetrieveFiltered(ctx context.Context, query []float32, topK int, filter *ragkit.Filter, metadataFieldNames ...string) ([]ragkit.RetrievedDoc, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	if topK <= 0 {
+		return nil, ctx.Err()
 	}
 
 	// Get near vector results
-	response, err := w.client.GraphQL().Get().
+	getter := w.client.GraphQL().Get()
+	if filter != nil {
+		where, err := compileFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+		getter = getter.WithWhere(where)
+	}
+	response, err := getter.
 		WithClassName(w.className).
-		WithFields(
-			graphql.Field{Name: "text"},
-			graphql.Field{
-				Name:   "metadata",
-				Fields: metadataFields,
-			},
-		).
+		WithFields(resultFields("distance", metadataFieldNames)...).
 		WithNearVector(w.client.GraphQL().NearVectorArgBuilder().
 			WithVector(query)).
 		WithLimit(topK).
@@ -128,45 +297,219 @@ func (w *Weaviate) Retrieve(ctx context.Context, query []float32, topK int, meta
 		return nil, err
 	}
 
-	// Parse results
-	var results []ragkit.RetrievedDoc
-	if resultData, ok := response.Data["Get"].(map[string]any); ok {
-		if classResultData, ok := resultData[w.className].([]any); ok {
-			for _, obj := range classResultData {
-				objMap := obj.(map[string]any)
-
-				var metadata map[string]any
-				if m, ok := objMap["metadata"].(map[string]any); ok {
-					metadata = m
-				}
+	results, err := w.parseResults(response)
+	if err != nil || len(results) == 0 {
+		return results, err
+	}
+	metric, err := w.classMetric(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Metric = metric
+		if metric == ragkit.DistanceL2 {
+			results[i].Distance = float32(math.Sqrt(float64(results[i].Distance))) // Weaviate measures squared L2
+		}
+		results[i].Score = ragkit.ScoreFromDistance(metric, results[i].Distance)
+	}
+	return results, nil
+}
 
-				results = append(results, ragkit.RetrievedDoc{
-					// ID:       objMap["_id"].(string),
-					// Score:    objMap["_additional"].(map[string]any)["distance"].(float32),
-					Vector:   query, // Weaviate doesn't return the vector in the response
-					Text:     objMap["text"].(string),
-					Metadata: metadata,
-				})
-			}
-		} else {
-			return nil, fmt.Errorf("no results found in class: %s", w.className)
+// classMetric returns the distance metric of the class, from the distance of its vectorIndexConfig
+func (w *Weaviate) classMetric(ctx context.Context) (ragkit.DistanceMetric, error) {
+	w.metricMu.Lock()
+	defer w.metricMu.Unlock()
+
+	if w.metric != "" {
+		return w.metric, nil
+	}
+	class, err := w.client.Schema().ClassGetter().WithClassName(w.className).Do(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the distance of class %s: %w", w.className, err)
+	}
+	distance := "cosine" // Weaviate's default
+	if cfg, ok := class.VectorIndexConfig.(map[string]any); ok {
+		if d, ok := cfg["distance"].(string); ok && d != "" {
+			distance = d
 		}
-	} else {
-		return nil, fmt.Errorf("no results found")
 	}
+	switch distance {
+	case "cosine":
+		w.metric = ragkit.DistanceCosine
+	case "dot":
+		w.metric = ragkit.DistanceDot
+	case "l2-squared":
+		w.metric = ragkit.DistanceL2
+	default:
+		return "", fmt.Errorf("unsupported distance %q of class %s", distance, w.className)
+	}
+	return w.metric, nil
+}
 
-	return results, nil
+// RetrieveHybrid runs a native hybrid query, fusing BM25 over the text property with vector search
+// by relative score fusion, the strategy of ragkit.FuseRelativeScores.
+// Results carry the fused Score but no Distance.
+func (w *Weaviate) RetrieveHybrid(ctx context.Context, text string, topK int, alpha float32, filter *ragkit.Filter, metadataFieldNames ...string) ([]ragkit.RetrievedDoc, error) {
+	if err := ragkit.ValidateAlpha(alpha); err != nil {
+		return nil, err
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	if topK <= 0 {
+		return nil, ctx.Err()
+	}
+
+	hybrid := w.client.GraphQL().HybridArgumentBuilder().
+		WithQuery(text).
+		WithAlpha(alpha).
+		WithProperties([]string{"text"}).
+		WithFusionType(graphql.RelativeScore)
+	if alpha > 0 {
+		// embed here, as the class has no vectorizer
+		vectors, err := w.embedder.EmbedTexts(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		hybrid = hybrid.WithVector(vectors[0])
+	}
+
+	getter := w.client.GraphQL().Get()
+	if filter != nil {
+		where, err := compileFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+		getter = getter.WithWhere(where)
+	}
+	response, err := getter.
+		WithClassName(w.className).
+		WithFields(resultFields("score", metadataFieldNames)...).
+		WithHybrid(hybrid).
+		WithLimit(topK).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return w.parseResults(response)
 }
 
 func (w *Weaviate) RetrieveText(ctx context.Context, text string, topK int, metadataFieldNames ...string) ([]ragkit.RetrievedDoc, error) {
+	return w.RetrieveTextFiltered(ctx, text, topK, nil, metadataFieldNames...)
+}
+
+func (w *Weaviate) RetrieveTextFiltered(ctx context.Context, text string, topK int, filter *ragkit.Filter, metadataFieldNames ...string) ([]ragkit.RetrievedDoc, error) {
 	vectors, err := w.embedder.EmbedTexts(ctx, text)
 	if err != nil {
 		return nil, err
 	}
 
-	return w.Retrieve(ctx, vectors[0], topK, metadataFieldNames...)
-} // Get near text results
+	return w.RetrieveFiltered(ctx, vectors[0], topK, filter, metadataFieldNames...)
+}
+
+// objectProps returns the properties of the object storing doc
+func objectProps(doc ragkit.Document) map[string]any {
+	props := filterProps(doc.Metadata)
+	props["text"] = doc.Text
+	props["metadata"] = doc.Metadata
+	return props
+}
+
+// resultFields returns the fields of a search query: the text, the ID, the stored vector,
+// the additional field rank ("distance" or "score") and the listed metadata fields
+func resultFields(rank string, metadataFieldNames []string) []graphql.Field {
+	fields := []graphql.Field{
+		{Name: "text"},
+		{
+			Name: "_additional",
+			Fields: []graphql.Field{
+				{Name: "id"},
+				{Name: rank},
+				{Name: "vector"},
+			},
+		},
+	}
+	// Weaviate can only return the listed fields of the metadata object
+	if len(metadataFieldNames) > 0 {
+		var metadataFields []graphql.Field
+		for _, name := range metadataFieldNames {
+			metadataFields = append(metadataFields, graphql.Field{Name: name})
+		}
+		fields = append(fields, graphql.Field{Name: "metadata", Fields: metadataFields})
+	}
+	return fields
+}
+
+// parseResults converts the objects of a Get query into RetrievedDocs
+func (w *Weaviate) parseResults(response *models.GraphQLResponse) ([]ragkit.RetrievedDoc, error) {
+	if len(response.Errors) > 0 {
+		var msgs []string
+		for _, e := range response.Errors {
+			// the class is only created with the first object, so treat it as empty
+			if strings.Contains(e.Message, fmt.Sprintf("Cannot query field %q", w.className)) {
+				return nil, nil
+			}
+			msgs = append(msgs, e.Message)
+		}
+		return nil, fmt.Errorf("graphql: %s", strings.Join(msgs, "; "))
+	}
+
+	resultData, ok := response.Data["Get"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("no results found")
+	}
+	classResultData, ok := resultData[w.className].([]any)
+	if !ok {
+		return nil, fmt.Errorf("no results found in class: %s", w.className)
+	}
+
+	var results []ragkit.RetrievedDoc
+	for _, obj := range classResultData {
+		objMap, ok := obj.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		var doc ragkit.RetrievedDoc
+		doc.Text, _ = objMap["text"].(string)
+		doc.Metadata, _ = objMap["metadata"].(map[string]any)
+
+		if additional, ok := objMap["_additional"].(map[string]any); ok {
+			doc.ID, _ = additional["id"].(string)
+			if d, ok := additional["distance"].(float64); ok {
+				doc.Distance = float32(d) // the metric is set by RetrieveFiltered
+			}
+			// hybrid and BM25 scores are strings
+			switch score := additional["score"].(type) {
+			case string:
+				f, _ := strconv.ParseFloat(score, 32)
+				doc.Score = float32(f)
+			case float64:
+				doc.Score = float32(score)
+			}
+			if vector, ok := additional["vector"].([]any); ok {
+				doc.Vector = make([]float32, len(vector))
+				for i, v := range vector {
+					f, _ := v.(float64)
+					doc.Vector[i] = float32(f)
+				}
+			}
+		}
+		results = append(results, doc)
+	}
+	return results, nil
+}
+
+func (w *Weaviate) batchLimits() ragkit.BatchLimits {
+	return ragkit.BatchLimits{MaxTexts: w.batchSize}
+}
 
 func (w *Weaviate) String() string {
 	return fmt.Sprintf("Weaviate(class: %s, embedder: %s)", w.className, w.embedder)
 }
+
+func isNotFound(err error) bool {
+	var clientErr *fault.WeaviateClientError
+	return errors.As(err, &clientErr) && clientErr.StatusCode == http.StatusNotFound
+}
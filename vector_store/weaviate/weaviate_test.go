@@ -0,0 +1,120 @@
+package weviate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ragkit "github.com/suapapa/go_ragkit"
+	"github.com/suapapa/go_ragkit/embedder/fake"
+	"github.com/suapapa/go_ragkit/ragkittest"
+	"github.com/weaviate/weaviate-go-client/v5/weaviate"
+)
+
+var classes atomic.Int64
+
+// TestConformance runs against the Weaviate at WEAVIATE_TEST_ADDR, like http://localhost:8080,
+// creating and deleting a class per test
+func TestConformance(t *testing.T) {
+	addr := os.Getenv("WEAVIATE_TEST_ADDR")
+	if addr == "" {
+		t.Skip("WEAVIATE_TEST_ADDR not set")
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := weaviate.NewClient(weaviate.Config{Host: u.Host, Scheme: u.Scheme, StartupTimeout: 3 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ragkittest.RunVectorStoreSuite(t, func(t *testing.T) ragkit.VectorStore {
+		w := New(client, fmt.Sprintf("ragkittest_%d_%d", time.Now().Unix(), classes.Add(1)), fake.New(64))
+		t.Cleanup(func() {
+			// the class is created by the first write, if any
+			client.Schema().ClassDeleter().WithClassName(w.className).Do(context.Background())
+		})
+		return w
+	})
+}
+
+// fakeWeaviate serves a class with the given distance whose nearVector search finds one object at distance d
+func fakeWeaviate(t *testing.T, distance string, d float64) *weaviate.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/schema/Docs":
+			json.NewEncoder(w).Encode(map[string]any{
+				"class":             "Docs",
+				"vectorIndexConfig": map[string]any{"distance": distance},
+			})
+		case "/v1/graphql":
+			json.NewEncoder(w).Encode(map[string]any{
+				"data": map[string]any{"Get": map[string]any{"Docs": []any{
+					map[string]any{
+						"text":        "hello",
+						"_additional": map[string]any{"id": "1", "distance": d, "vector": []float64{1, 0}},
+					},
+				}}},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	u, _ := url.Parse(srv.URL)
+	client, err := weaviate.NewClient(weaviate.Config{Host: u.Host, Scheme: u.Scheme})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestRetrieveMetric(t *testing.T) {
+	tests := []struct {
+		distance     string
+		raw          float64
+		wantMetric   ragkit.DistanceMetric
+		wantDistance float32
+	}{
+		{"cosine", 0.5, ragkit.DistanceCosine, 0.5},
+		{"", 0.5, ragkit.DistanceCosine, 0.5},
+		{"dot", -3, ragkit.DistanceDot, -3},
+		{"l2-squared", 4, ragkit.DistanceL2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.distance, func(t *testing.T) {
+			w := New(fakeWeaviate(t, tt.distance, tt.raw), "docs", fake.New(2))
+			results, err := w.Retrieve(context.Background(), []float32{1, 0}, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("got %d results, want 1", len(results))
+			}
+			r := results[0]
+			if r.Metric != tt.wantMetric || math.Abs(float64(r.Distance-tt.wantDistance)) > 1e-6 {
+				t.Errorf("Metric, Distance = %s, %v, want %s, %v", r.Metric, r.Distance, tt.wantMetric, tt.wantDistance)
+			}
+			if want := ragkit.ScoreFromDistance(tt.wantMetric, tt.wantDistance); r.Score != want {
+				t.Errorf("Score = %v, want %v", r.Score, want)
+			}
+		})
+	}
+
+	w := New(fakeWeaviate(t, "hamming", 1), "docs", fake.New(2))
+	if _, err := w.Retrieve(context.Background(), []float32{1, 0}, 1); err == nil {
+		t.Error("hamming distance: no error")
+	}
+}